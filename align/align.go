@@ -9,10 +9,16 @@
 //   gap        int
 //   wild       int
 //   errorRate  float64
+// Aligner created by New use Config, support affine gap (gotoh) and substitution matrix
+// myers aligner search edit distance hits by bit-parallel algorithm, see myers.go
+// banded aligner align long sequence in band with optional x-drop, see band.go
 
 package align
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // ****************************** Default Const Setting ***********************
 
@@ -27,9 +33,9 @@ const WILD_QUERY int = 1
 const WILD_TARGET int = 2
 const WILD_ALL int = 3
 
-// const LOCAL int = 0
-// const GLOCAL int = 1
-// const GLOBAL int = 2
+const LOCAL int = 0
+const GLOCAL int = 1
+const GLOBAL int = 2
 
 // ****************************** Data Structure Setting **********************
 
@@ -49,6 +55,7 @@ type AlignResult struct { // record alignment result
 	Score  int // the score value of the alignment
 	Matchs int // match numbers of the alignment
 	Errors int // error numbers of the alignment
+	Ops    Ops // edit operations from start to end, only given by traceback
 }
 
 func (ar AlignResult) String() string {
@@ -74,58 +81,120 @@ func Align(method, query, target string, match, mismatch, gap int, wild int, err
 	return nil
 }
 
-// init Aligner(name, match, mismatch, gap, wild, errRate)
-func New(name, seq string, args ...int) (Aligner, error) {
+// ****************************** Config Setting ******************************
+
+// Config aligner score setting
+// gap with length L score: GapOpen + (L-1)*GapExtend, linear gap when GapOpen == GapExtend
+type Config struct {
+	Match     int     // match score [1]
+	Mismatch  int     // mismatch score [-1]
+	GapOpen   int     // score of the first base in a gap [-2]
+	GapExtend int     // score of each following base in a gap [-2]
+	Wild      int     // which sequence use N as wild char [WILD_QUERY]
+	ErrorRate float64 // errors / align_length [0.01]
+	Matrix    *Matrix // substitution matrix, replace Match and Mismatch if given
+	Alphabet  string  // valid letters of aligner sequence, eg. DNA_ALPHABET, PROTEIN_ALPHABET
+	Traceback bool    // record full traceback for edit operations (memory qlen*tlen)
+	MaxErrors int     // max edit distance of myers aligner, use ErrorRate*len(seq) if 0
+	Band      int     // band width of banded aligner, no band if 0
+	Diagonal  int     // band centre (j - i) of banded glocal aligner
+	XDrop     int     // stop banded alignment when score drop more than XDrop, no xdrop if 0
+}
+
+// DefaultConfig return config (match:1, mismatch:-1, gap:-2, wild:WILD_QUERY, errRate:0.01)
+func DefaultConfig() *Config {
+	return &Config{
+		Match:     1,
+		Mismatch:  -1,
+		GapOpen:   -2,
+		GapExtend: -2,
+		Wild:      WILD_QUERY,
+		ErrorRate: 0.01,
+	}
+}
+
+// ProteinConfig return config using BLOSUM62 matrix and affine gap (open:-11, extend:-1)
+func ProteinConfig() *Config {
+	matrix, _ := GetMatrix("BLOSUM62")
+	return &Config{
+		GapOpen:   -11,
+		GapExtend: -1,
+		Wild:      WILD_NONE,
+		ErrorRate: 1,
+		Matrix:    matrix,
+		Alphabet:  PROTEIN_ALPHABET,
+	}
+}
+
+func (c *Config) String() string {
+	if c.Matrix != nil {
+		return fmt.Sprintf("matrix:%s, gapOpen:%d, gapExtend:%d, wild:%d, errRate:%0.3f",
+			c.Matrix.Name, c.GapOpen, c.GapExtend, c.Wild, c.ErrorRate)
+	}
+	return fmt.Sprintf("match:%d, mismatch:%d, gapOpen:%d, gapExtend:%d, wild:%d, errRate:%0.3f",
+		c.Match, c.Mismatch, c.GapOpen, c.GapExtend, c.Wild, c.ErrorRate)
+}
+
+// score return score and is_match of query base q and target base t
+func (c *Config) score(q, t byte) (int, int) {
+	is_match := 0
+	if q == t || (c.Wild&WILD_QUERY != 0 && q == WILD_CHAR) || (c.Wild&WILD_TARGET != 0 && t == WILD_CHAR) {
+		is_match = 1
+	}
+	if c.Matrix != nil {
+		return c.Matrix.Score(q, t), is_match
+	}
+	if is_match == 1 {
+		return c.Match, is_match
+	}
+	return c.Mismatch, is_match
+}
+
+// check check seq letters all in Alphabet
+func (c *Config) check(seq string) error {
+	if c.Alphabet == "" {
+		return nil
+	}
+	for i := 0; i < len(seq); i++ {
+		if strings.IndexByte(c.Alphabet, seq[i]) < 0 && strings.IndexByte(c.Alphabet, upper(seq[i])) < 0 {
+			return fmt.Errorf("Aligner Sequence %s contain letter %c not in alphabet %s", seq, seq[i], c.Alphabet)
+		}
+	}
+	return nil
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
+
+// New init Aligner(name, seq, config), use DefaultConfig if config is nil
+func New(name, seq string, conf *Config) (Aligner, error) {
 	if seq == "" {
 		return nil, fmt.Errorf("Alinger Sequence is empyty%s", "!")
 	}
-
-	match := 1
-	mismatch := -1
-	gap := -2
-	wild := WILD_QUERY
-	errRate := 1
-	switch l := len(args); {
-	case l > 4:
-		errRate = args[4]
-		fallthrough
-	case l > 3:
-		wild = args[3]
-		fallthrough
-	case l > 2:
-		gap = args[2]
-		fallthrough
-	case l > 1:
-		mismatch = args[1]
-		fallthrough
-	case l > 0:
-		match = args[0]
+	if conf == nil {
+		conf = DefaultConfig()
+	}
+	if err := conf.check(seq); err != nil {
+		return nil, err
 	}
 
 	switch name {
 	case "local":
-		return &LocalAligner{
-			seq:      seq,
-			match:    match,
-			mismatch: mismatch,
-			gap:      gap, wild: wild,
-			errorRate: 0.01 * float64(errRate)}, nil
+		return &LocalAligner{seq: seq, conf: conf}, nil
 	case "global":
-		return &GlobalAligner{
-			seq:       seq,
-			match:     match,
-			mismatch:  mismatch,
-			gap:       gap,
-			wild:      wild,
-			errorRate: 0.01 * float64(errRate)}, nil
+		return &GlobalAligner{seq: seq, conf: conf}, nil
 	case "glocal":
-		return &GlocalAligner{
-			seq:       seq,
-			match:     match,
-			mismatch:  mismatch,
-			gap:       gap,
-			wild:      wild,
-			errorRate: 0.01 * float64(errRate)}, nil
+		return &GlocalAligner{seq: seq, conf: conf}, nil
+	case "myers":
+		return newMyersAligner(seq, conf), nil
+	case "banded-global":
+		return &BandedAligner{method: GLOBAL, seq: seq, conf: conf}, nil
+	case "banded-glocal":
+		return &BandedAligner{method: GLOCAL, seq: seq, conf: conf}, nil
 	}
 	return nil, fmt.Errorf("Unkown Aligner Name: %s", name)
 }
@@ -5,5 +5,61 @@ import (
 )
 
 func Test_New(t *testing.T) {
+	if _, err := New("local", "", nil); err == nil {
+		t.Error("New empty seq expect error")
+	}
+	if _, err := New("unkown", "ACGT", nil); err == nil {
+		t.Error("New unkown aligner expect error")
+	}
+	for _, name := range []string{"local", "glocal", "global"} {
+		aligner, err := New(name, "ACGT", nil)
+		if err != nil {
+			t.Errorf("New %s error: %v", name, err)
+			continue
+		}
+		if ar := aligner.Align("ACGT"); ar.Score != 4 || ar.Matchs != 4 || ar.Errors != 0 {
+			t.Errorf("%s Align expect score: 4 get: %v", aligner, ar)
+		}
+	}
+}
+
+func Test_New_alphabet(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Alphabet = DNA_ALPHABET
+	if _, err := New("local", "ACGTacgtN", conf); err != nil {
+		t.Error("New DNA alphabet error:", err)
+	}
+	if _, err := New("local", "ACGU", conf); err == nil {
+		t.Error("New DNA alphabet expect error for letter U")
+	}
+	if _, err := New("local", "MKTJ", ProteinConfig()); err == nil {
+		t.Error("New Protein alphabet expect error for letter J")
+	}
+}
+
+func Test_Affine(t *testing.T) {
+	conf := &Config{Match: 2, Mismatch: -3, GapOpen: -5, GapExtend: -1, ErrorRate: 1}
+	aligner, _ := New("global", "ACGTACGT", conf)
+	ar := aligner.Align("ACGTGGGACGT")
+	if ar.Score != 9 || ar.Matchs != 8 || ar.Errors != 3 {
+		t.Errorf("Affine Global expect score: 9, matchs: 8, errors: 3 get: %v", ar)
+	}
+
+	// linear gap pay the open score for each gap base
+	ar = Global("ACGTACGT", "ACGTGGGACGT", 2, -3, -5, WILD_NONE, 1)
+	if ar.Score != 1 {
+		t.Errorf("Linear Global expect score: 1 get: %v", ar)
+	}
+}
 
+func Test_Protein(t *testing.T) {
+	aligner, err := New("local", "MKTAYIAK", ProteinConfig())
+	if err != nil {
+		t.Fatal("New protein aligner error:", err)
+	}
+	ar := aligner.Align("GGMKTAYIAKGG")
+	expect := AlignResult{Qstart: 0, Qend: 8, Tstart: 2, Tend: 10, Score: 39, Matchs: 8, Errors: 0}
+	if ar.String() != expect.String() {
+		t.Errorf("Protein Local expect: %v get: %v", expect, ar)
+	}
 }
@@ -0,0 +1,245 @@
+package align
+
+import "fmt"
+
+/**************************************** BANDED ******************************
+  banded affine gap alignment for long sequence, only cells near the band
+  centre are computed, fill the matrix row by row (query base by base):
+
+    GLOBAL  centre of row i is j = i * tlen / qlen
+    GLOCAL  centre of row i is j = i + Diagonal
+
+  cells with |j - centre(i)| > Band are skipped, memory is linear in Band.
+
+  X-drop: when XDrop > 0, cells score lower than (best score - XDrop) are
+  dropped, alignment stop early when all cells of a row are dropped, the
+  result is the best scoring cell found (extension alignment).
+******************************************************************************/
+
+type BandedAligner struct {
+	method int
+	seq    string
+	conf   *Config
+}
+
+func (ba BandedAligner) Align(target string) *AlignResult {
+	return banded(ba.method, ba.seq, target, ba.conf)
+}
+
+func (ba BandedAligner) alignWith(ws *workspace, target string) *AlignResult {
+	return ws.banded(ba.method, ba.seq, target, ba.conf)
+}
+
+func (ba BandedAligner) AlignTo(query string) *AlignResult {
+	return banded(ba.method, query, ba.seq, ba.conf)
+}
+
+func (ba BandedAligner) String() string {
+	name := "Global"
+	if ba.method == GLOCAL {
+		name = "Glocal"
+	}
+	return fmt.Sprintf("Banded%sAligner(Seq:%s, band:%d, diagonal:%d, xdrop:%d, %s)",
+		name, ba.seq, ba.conf.Band, ba.conf.Diagonal, ba.conf.XDrop, ba.conf)
+}
+
+// band record [lo, hi] target range of each query row
+type band struct {
+	method int
+	qlen   int
+	tlen   int
+	width  int
+	diag   int
+}
+
+func (b *band) centre(i int) int {
+	if b.method == GLOBAL {
+		if b.qlen == 0 {
+			return 0
+		}
+		return i * b.tlen / b.qlen
+	}
+	return i + b.diag
+}
+
+func (b *band) lo(i int) int {
+	if lo := b.centre(i) - b.width; lo > 0 {
+		return lo
+	}
+	return 0
+}
+
+func (b *band) hi(i int) int {
+	if hi := b.centre(i) + b.width; hi < b.tlen {
+		return hi
+	}
+	return b.tlen
+}
+
+// banded align query to target in band using method GLOBAL or GLOCAL
+func banded(method int, query, target string, conf *Config) *AlignResult {
+	return new(workspace).banded(method, query, target, conf)
+}
+
+func (ws *workspace) banded(method int, query, target string, conf *Config) *AlignResult {
+	var diag, up, left cell
+	var is_match, score int
+	var upExt, leftExt bool
+	var trace []byte
+
+	best_align := AlignResult{}
+	qlen := len(query)
+	tlen := len(target)
+	b := &band{method: method, qlen: qlen, tlen: tlen, width: conf.Band, diag: conf.Diagonal}
+	size := 2*b.width + 1
+	if b.width <= 0 || size > tlen+1 { // no band, or band wider than target
+		b.width = tlen + qlen
+		size = tlen + 1
+	}
+	impossible := cell{score: minScore}
+	prevH, prevF := ws.cells(0, size), ws.cells(1, size) // H and F of previous row
+	curH, curF := ws.cells(2, size), ws.cells(3, size)   // H and F of current row
+	if conf.Traceback {
+		trace = ws.traceback((qlen + 1) * size)
+	}
+
+	// init [0] row tstart
+	bestScore := 0 // best score for xdrop
+	best := cell{score: minScore}
+	bestI, bestJ := 0, 0
+	lo, hi := b.lo(0), b.hi(0)
+	for j := lo; j <= hi; j++ {
+		curH[j-lo] = cell{tstart: j}
+		curF[j-lo] = impossible
+		if method == GLOBAL {
+			curH[j-lo].score = conf.gapScore(j)
+			curH[j-lo].errors = j
+		}
+	}
+
+	for i := 1; i < qlen+1; i++ { // align each row one by one
+		prevH, curH = curH, prevH
+		prevF, curF = curF, prevF
+		plo, phi := lo, hi
+		lo, hi = b.lo(i), b.hi(i)
+		prev := func(row []cell, j int) cell {
+			if j < plo || j > phi {
+				return impossible
+			}
+			return row[j-plo]
+		}
+
+		alive := false
+		left = impossible
+		for j := lo; j <= hi; j++ {
+			k := j - lo
+			if j == 0 { // init [0] col qstart
+				curH[k] = cell{qstart: i, score: conf.gapScore(i)}
+				if method == GLOBAL {
+					curH[k].qstart = 0
+					curH[k].errors = i
+				}
+				curF[k] = impossible
+				if curH[k].score >= bestScore-conf.XDrop || conf.XDrop <= 0 {
+					alive = true
+				}
+				continue
+			}
+
+			diag = prev(prevH, j-1)
+			if diag.score > minScore {
+				score, is_match = conf.score(query[i-1], target[j-1])
+				diag.score += score
+				diag.matchs += is_match
+				diag.errors += 1 - is_match
+			}
+			up, upExt = gapCell(prev(prevH, j), prev(prevF, j), conf)
+			if j > lo {
+				left, leftExt = gapCell(curH[k-1], left, conf)
+			} else {
+				left, leftExt = impossible, false
+			}
+			curF[k] = up
+
+			dir := DIR_DIAG
+			if diag.score >= up.score && diag.score >= left.score { // match or mismatch
+				curH[k] = diag
+			} else if up.score >= left.score { // insert
+				curH[k] = up
+				dir = DIR_UP
+			} else { // delete
+				curH[k] = left
+				dir = DIR_LEFT
+			}
+
+			if curH[k].score <= minScore/2 { // cell out of band
+				curH[k] = impossible
+				dir = DIR_NONE
+			} else if conf.XDrop > 0 {
+				if curH[k].score < bestScore-conf.XDrop { // drop cell
+					curH[k] = impossible
+					curF[k] = impossible
+					left = impossible
+					dir = DIR_NONE
+				} else {
+					alive = true
+					if c := curH[k]; c.score > best.score && float64(c.errors) <= conf.ErrorRate*float64(j-c.tstart) {
+						best, bestI, bestJ = c, i, j
+					}
+					if curH[k].score > bestScore {
+						bestScore = curH[k].score
+					}
+				}
+			} else {
+				alive = true
+			}
+			if trace != nil {
+				trace[i*size+k] = traceByte(dir, leftExt, upExt)
+			}
+
+			// check the last col for the max row alignment
+			if c := curH[k]; method == GLOCAL && conf.XDrop <= 0 && j == tlen && i < qlen &&
+				float64(c.errors) <= conf.ErrorRate*float64(tlen-c.tstart) && better(c.score, c.matchs, &best_align) {
+				best_align = AlignResult{Qstart: c.qstart, Qend: i, Tstart: c.tstart, Tend: tlen,
+					Score: c.score, Matchs: c.matchs, Errors: c.errors}
+			}
+		}
+		if !alive { // all cells dropped
+			break
+		}
+
+		if i != qlen || conf.XDrop > 0 {
+			continue
+		}
+		switch method {
+		case GLOCAL: // check the last row for the max row alignment
+			for j := lo; j <= hi; j++ {
+				if c := curH[j-lo]; c.score > minScore && float64(c.errors) <= conf.ErrorRate*float64(j-c.tstart) &&
+					better(c.score, c.matchs, &best_align) {
+					best_align = AlignResult{Qstart: c.qstart, Qend: qlen, Tstart: c.tstart, Tend: j,
+						Score: c.score, Matchs: c.matchs, Errors: c.errors}
+				}
+			}
+		case GLOBAL: // check the last cell
+			if hi != tlen {
+				break
+			}
+			if c := curH[tlen-lo]; c.score > minScore && float64(c.errors) <= float64(tlen)*conf.ErrorRate {
+				best_align = AlignResult{Qstart: 0, Qend: qlen, Tstart: 0, Tend: tlen,
+					Score: c.score, Matchs: c.matchs, Errors: c.errors}
+			}
+		}
+	}
+
+	if conf.XDrop > 0 && best.score > minScore {
+		best_align = AlignResult{Qstart: best.qstart, Qend: bestI, Tstart: best.tstart, Tend: bestJ,
+			Score: best.score, Matchs: best.matchs, Errors: best.errors}
+	}
+	if trace != nil && (best_align.Qend > 0 || best_align.Tend > 0) {
+		at := func(i, j int) byte {
+			return trace[i*size+j-b.lo(i)]
+		}
+		best_align.Ops = traceback(method, query, target, at, &best_align, conf)
+	}
+	return &best_align
+}
@@ -0,0 +1,93 @@
+package align
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// mutate return seq with random substitution, insert and delete at rate
+func mutate(r *rand.Rand, seq string, rate float64) string {
+	buf := []byte{}
+	for i := 0; i < len(seq); i++ {
+		switch x := r.Float64(); {
+		case x < rate/3: // substitution
+			buf = append(buf, "ACGT"[r.Intn(4)])
+		case x < rate*2/3: // insert
+			buf = append(buf, seq[i], "ACGT"[r.Intn(4)])
+		case x < rate: // delete
+		default:
+			buf = append(buf, seq[i])
+		}
+	}
+	return string(buf)
+}
+
+func Test_Banded_noband(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		query, target := randSeq(r, 1+r.Intn(30)), randSeq(r, 1+r.Intn(40))
+		conf := &Config{Match: 2, Mismatch: -3, GapOpen: -5, GapExtend: -2, ErrorRate: 1}
+		for _, method := range []int{GLOBAL, GLOCAL} {
+			expect := gotoh(method, query, target, conf)
+			if ar := banded(method, query, target, conf); ar.Score != expect.Score {
+				t.Fatalf("%d %s %s banded expect: %v get: %v", method, query, target, expect, ar)
+			}
+		}
+	}
+}
+
+func Test_Banded_long(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	target := randSeq(r, 3000)
+	query := mutate(r, target, 0.05)
+	conf := &Config{Match: 2, Mismatch: -3, GapOpen: -5, GapExtend: -2, ErrorRate: 1, Traceback: true}
+	expect := gotoh(GLOBAL, query, target, conf)
+
+	conf.Band = 100
+	aligner, err := New("banded-global", query, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ar := aligner.Align(target)
+	if ar.Score != expect.Score || ar.Tend != len(target) || ar.Qend != len(query) {
+		t.Errorf("banded global expect: %v get: %v", expect, ar)
+	}
+	if nm := ar.NM(); nm != ar.Errors {
+		t.Errorf("banded global NM: %d != Errors: %d", nm, ar.Errors)
+	}
+
+	// glocal: query inside a longer target, band centre at the query offset
+	region := randSeq(r, 500) + target + randSeq(r, 500)
+	conf.Diagonal = 500
+	aligner, _ = New("banded-glocal", query, conf)
+	ar = aligner.Align(region)
+	if ar.Tstart != 500 || ar.Tend != 3500 || ar.Qend != len(query) {
+		t.Errorf("banded glocal expect tstart: 500, tend: 3500 get: %v", ar)
+	}
+}
+
+func Test_Banded_xdrop(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	target := randSeq(r, 400)
+	query := target[:200] + randSeq(r, 200)
+	conf := &Config{Match: 1, Mismatch: -2, GapOpen: -3, GapExtend: -1, ErrorRate: 1, Band: 50, XDrop: 20}
+	aligner, _ := New("banded-global", query, conf)
+	ar := aligner.Align(target)
+	if ar.Qend < 195 || ar.Qend > 210 || ar.Score < 195 {
+		t.Errorf("xdrop expect stop near 200 get: %v", ar)
+	}
+}
+
+func BenchmarkBanded(b *testing.B) {
+	r := rand.New(rand.NewSource(2))
+	target := randSeq(r, 10000)
+	query := mutate(r, target, 0.05)
+	conf := DefaultConfig()
+	conf.ErrorRate = 1
+	conf.Band = 100
+	aligner, _ := New("banded-global", query, conf)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		aligner.Align(target)
+	}
+}
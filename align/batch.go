@@ -0,0 +1,135 @@
+package align
+
+import (
+	"fmt"
+	"gongs/biofile"
+	"runtime"
+	"sync"
+)
+
+// BatchResult alignment result of a target sequence in batch
+type BatchResult struct {
+	Index  int          // input order of the target, start from 0
+	Name   string       // target name, eg. read name
+	Result *AlignResult // alignment of aligner seq to target
+}
+
+func (br BatchResult) String() string {
+	return fmt.Sprintf("BatchResult(index:%d, name:%s, %v)", br.Index, br.Name, br.Result)
+}
+
+// BatchAligner align aligner seq to many targets using a worker pool,
+// each worker keep its own reusable dp buffers
+type BatchAligner struct {
+	aligner Aligner
+	workers int
+}
+
+type batchJob struct {
+	index  int
+	name   string
+	target string
+}
+
+// NewBatch init BatchAligner(name, seq, config, workers), use all cpus if workers < 1
+func NewBatch(name, seq string, conf *Config, workers int) (*BatchAligner, error) {
+	aligner, err := New(name, seq, conf)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	return &BatchAligner{aligner: aligner, workers: workers}, nil
+}
+
+func (ba *BatchAligner) String() string {
+	return fmt.Sprintf("BatchAligner(%s, workers:%d)", ba.aligner, ba.workers)
+}
+
+// align align one job using workspace
+func (ba *BatchAligner) align(ws *workspace, job *batchJob) *BatchResult {
+	var ar *AlignResult
+	if aligner, ok := ba.aligner.(bufferedAligner); ok {
+		ar = aligner.alignWith(ws, job.target)
+	} else {
+		ar = ba.aligner.Align(job.target)
+	}
+	return &BatchResult{Index: job.index, Name: job.name, Result: ar}
+}
+
+// run start workers align jobs from jobChan, send results to resultChan in any order
+func (ba *BatchAligner) run(jobChan <-chan *batchJob, resultChan chan<- *BatchResult) {
+	wg := &sync.WaitGroup{}
+	for i := 0; i < ba.workers; i++ {
+		wg.Add(1)
+		go func(wg *sync.WaitGroup) {
+			ws := new(workspace)
+			for job := range jobChan {
+				resultChan <- ba.align(ws, job)
+			}
+			wg.Done()
+		}(wg)
+	}
+	wg.Wait()
+	close(resultChan)
+}
+
+// AlignAll align all seqs, results in input order
+func (ba *BatchAligner) AlignAll(seqs []biofile.Seqer) []*BatchResult {
+	jobChan := make(chan *batchJob, 2*ba.workers)
+	resultChan := make(chan *BatchResult, 2*ba.workers)
+	go func(jobChan chan *batchJob) {
+		for i, seq := range seqs {
+			jobChan <- &batchJob{index: i, name: seq.GetName(), target: string(seq.GetSeq())}
+		}
+		close(jobChan)
+	}(jobChan)
+	go ba.run(jobChan, resultChan)
+
+	results := make([]*BatchResult, len(seqs))
+	for result := range resultChan {
+		results[result.Index] = result
+	}
+	return results
+}
+
+// AlignSeqs align seqs from channel, eg. FastqFile.Seqs(), results sent in input order
+func (ba *BatchAligner) AlignSeqs(seqChan <-chan biofile.Seqer) <-chan *BatchResult {
+	inflight := 64 * ba.workers // max jobs waiting for output, limit memory of reorder
+	tokens := make(chan struct{}, inflight)
+	jobChan := make(chan *batchJob, 2*ba.workers)
+	resultChan := make(chan *BatchResult, 2*ba.workers)
+	out := make(chan *BatchResult, 2*ba.workers)
+
+	go func(seqChan <-chan biofile.Seqer, jobChan chan *batchJob) {
+		index := 0
+		for seq := range seqChan {
+			tokens <- struct{}{}
+			jobChan <- &batchJob{index: index, name: seq.GetName(), target: string(seq.GetSeq())}
+			index++
+		}
+		close(jobChan)
+	}(seqChan, jobChan)
+	go ba.run(jobChan, resultChan)
+
+	go func(resultChan <-chan *BatchResult, out chan *BatchResult) { // reorder results
+		next := 0
+		pending := make(map[int]*BatchResult)
+		for result := range resultChan {
+			pending[result.Index] = result
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				out <- r
+				<-tokens
+				next++
+			}
+		}
+		close(out)
+	}(resultChan, out)
+	return out
+}
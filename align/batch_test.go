@@ -0,0 +1,80 @@
+package align
+
+import (
+	"gongs/biofile"
+	"gongs/biofile/fastq"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func batchSeqs(n int) []biofile.Seqer {
+	r := rand.New(rand.NewSource(1))
+	seqs := make([]biofile.Seqer, n)
+	for i, read := range benchReads()[:n] {
+		if r.Intn(3) == 0 {
+			read = mutate(r, read, 0.05)
+		}
+		seqs[i] = &fastq.Fastq{Name: "read" + strconv.Itoa(i), Seq: []byte(read)}
+	}
+	return seqs
+}
+
+func Test_BatchAligner(t *testing.T) {
+	seqs := batchSeqs(500)
+	for _, name := range []string{"local", "glocal", "myers"} {
+		conf := DefaultConfig()
+		conf.MaxErrors = 2
+		ba, err := NewBatch(name, benchAdapter, conf, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		aligner, _ := New(name, benchAdapter, conf)
+
+		results := ba.AlignAll(seqs)
+		for i, result := range results {
+			expect := aligner.Align(string(seqs[i].GetSeq()))
+			if result.Index != i || result.Name != seqs[i].GetName() || result.Result.String() != expect.String() {
+				t.Fatalf("%s AlignAll %d expect: %v get: %v", name, i, expect, result)
+			}
+		}
+
+		seqChan := make(chan biofile.Seqer)
+		go func() {
+			for _, seq := range seqs {
+				seqChan <- seq
+			}
+			close(seqChan)
+		}()
+		i := 0
+		for result := range ba.AlignSeqs(seqChan) {
+			if result.Index != i || result.Result.String() != results[i].Result.String() {
+				t.Fatalf("%s AlignSeqs %d expect: %v get: %v", name, i, results[i], result)
+			}
+			i++
+		}
+		if i != len(seqs) {
+			t.Errorf("%s AlignSeqs expect results: %d get: %d", name, len(seqs), i)
+		}
+	}
+}
+
+func BenchmarkBatchAligner(b *testing.B) {
+	seqs := batchSeqs(1000)
+	ba, _ := NewBatch("glocal", benchAdapter, nil, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ba.AlignAll(seqs)
+	}
+}
+
+func BenchmarkBatchSequential(b *testing.B) {
+	seqs := batchSeqs(1000)
+	aligner, _ := New("glocal", benchAdapter, nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, seq := range seqs {
+			aligner.Align(string(seq.GetSeq()))
+		}
+	}
+}
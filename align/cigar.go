@@ -0,0 +1,157 @@
+// edit operations of alignment, output as CIGAR, MD tag or pretty three-line view
+// query is treated as read and target as reference, as in SAM format
+
+package align
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type Op byte
+
+const (
+	OP_MATCH    Op = '=' // query base equal target base
+	OP_MISMATCH Op = 'X' // query base not equal target base
+	OP_INSERT   Op = 'I' // query base not in target
+	OP_DELETE   Op = 'D' // target base not in query
+)
+
+// Ops edit operations, one for each alignment column
+type Ops []Op
+
+func (ops Ops) String() string {
+	buf := make([]byte, len(ops))
+	for i, op := range ops {
+		buf[i] = byte(op)
+	}
+	return string(buf)
+}
+
+// Cigar return CIGAR string using =/X/I/D
+func (ops Ops) Cigar() string {
+	var buf bytes.Buffer
+	for i, n := 0, len(ops); i < n; {
+		j := i + 1
+		for j < n && ops[j] == ops[i] {
+			j++
+		}
+		buf.WriteString(strconv.Itoa(j - i))
+		buf.WriteByte(byte(ops[i]))
+		i = j
+	}
+	return buf.String()
+}
+
+// NM return edit distance: mismatch, insert and delete number
+func (ops Ops) NM() int {
+	nm := 0
+	for _, op := range ops {
+		if op != OP_MATCH {
+			nm++
+		}
+	}
+	return nm
+}
+
+// Cigar return CIGAR string of alignment, empty if no traceback
+func (ar AlignResult) Cigar() string {
+	return ar.Ops.Cigar()
+}
+
+// SamCigar return CIGAR string with soft clip of unaligned query bases
+func (ar AlignResult) SamCigar(qlen int) string {
+	cigar := ar.Ops.Cigar()
+	if cigar == "" {
+		return "*"
+	}
+	if ar.Qstart > 0 {
+		cigar = strconv.Itoa(ar.Qstart) + "S" + cigar
+	}
+	if qlen > ar.Qend {
+		cigar += strconv.Itoa(qlen-ar.Qend) + "S"
+	}
+	return cigar
+}
+
+// NM return edit distance of alignment
+func (ar AlignResult) NM() int {
+	return ar.Ops.NM()
+}
+
+// MD return SAM MD tag of alignment, target is the reference sequence
+func (ar AlignResult) MD(target string) string {
+	var buf bytes.Buffer
+	count := 0
+	j := ar.Tstart
+	for i, op := range ar.Ops {
+		switch op {
+		case OP_MATCH:
+			count++
+			j++
+		case OP_MISMATCH:
+			buf.WriteString(strconv.Itoa(count))
+			buf.WriteByte(target[j])
+			count = 0
+			j++
+		case OP_DELETE:
+			if i == 0 || ar.Ops[i-1] != OP_DELETE { // start of a deletion
+				buf.WriteString(strconv.Itoa(count))
+				buf.WriteByte('^')
+				count = 0
+			}
+			buf.WriteByte(target[j])
+			j++
+		}
+	}
+	buf.WriteString(strconv.Itoa(count))
+	return buf.String()
+}
+
+// Pretty return three-line view of the alignment
+//
+//	query   1 ACG-TACGT 8
+//	          ||| |.|||
+//	target  3 ACGATCCGT 11
+func (ar AlignResult) Pretty(query, target string) string {
+	var qline, mline, tline bytes.Buffer
+	i, j := ar.Qstart, ar.Tstart
+	for _, op := range ar.Ops {
+		switch op {
+		case OP_MATCH, OP_MISMATCH:
+			qline.WriteByte(query[i])
+			tline.WriteByte(target[j])
+			if op == OP_MATCH {
+				mline.WriteByte('|')
+			} else {
+				mline.WriteByte('.')
+			}
+			i++
+			j++
+		case OP_INSERT:
+			qline.WriteByte(query[i])
+			tline.WriteByte('-')
+			mline.WriteByte(' ')
+			i++
+		case OP_DELETE:
+			qline.WriteByte('-')
+			tline.WriteByte(target[j])
+			mline.WriteByte(' ')
+			j++
+		}
+	}
+
+	qstart := strconv.Itoa(ar.Qstart + 1)
+	tstart := strconv.Itoa(ar.Tstart + 1)
+	width := len(qstart)
+	if len(tstart) > width {
+		width = len(tstart)
+	}
+	return strings.Join([]string{
+		fmt.Sprintf("query  %*s %s %d", width, qstart, qline.String(), ar.Qend),
+		fmt.Sprintf("       %*s %s", width, "", mline.String()),
+		fmt.Sprintf("target %*s %s %d", width, tstart, tline.String(), ar.Tend),
+	}, "\n")
+}
@@ -0,0 +1,65 @@
+package align
+
+import (
+	"testing"
+)
+
+func traceConfig() *Config {
+	conf := &Config{Match: 2, Mismatch: -3, GapOpen: -5, GapExtend: -1, ErrorRate: 1, Traceback: true}
+	return conf
+}
+
+func Test_Traceback(t *testing.T) {
+	cases := []struct {
+		method string
+		query  string
+		target string
+		cigar  string
+		md     string
+		nm     int
+	}{
+		{"global", "ACGTACGT", "ACGTGGGACGT", "4=3D4=", "4^GGG4", 3},
+		{"global", "ACGTGGGACGT", "ACGTACGT", "4=3I4=", "8", 3},
+		{"local", "ACGTTCGT", "GGACGTACGTGG", "4=1X3=", "4A3", 1},
+		{"glocal", "ACGTACGT", "TTTTACGTACGTTTTT", "8=", "8", 0},
+	}
+	for _, c := range cases {
+		aligner, err := New(c.method, c.query, traceConfig())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ar := aligner.Align(c.target)
+		if cigar := ar.Cigar(); cigar != c.cigar {
+			t.Errorf("%s %s %s Cigar expect: %s get: %s", c.method, c.query, c.target, c.cigar, cigar)
+		}
+		if md := ar.MD(c.target); md != c.md {
+			t.Errorf("%s %s %s MD expect: %s get: %s", c.method, c.query, c.target, c.md, md)
+		}
+		if nm := ar.NM(); nm != c.nm || nm != ar.Errors {
+			t.Errorf("%s %s %s NM expect: %d get: %d errors: %d", c.method, c.query, c.target, c.nm, nm, ar.Errors)
+		}
+	}
+}
+
+func Test_Traceback_none(t *testing.T) {
+	aligner, _ := New("local", "ACGT", nil)
+	if ar := aligner.Align("ACGT"); ar.Ops != nil || ar.Cigar() != "" || ar.SamCigar(4) != "*" {
+		t.Errorf("Align without traceback expect no ops get: %v", ar.Ops)
+	}
+}
+
+func Test_SamCigar(t *testing.T) {
+	ar := &AlignResult{Qstart: 2, Qend: 6, Ops: Ops{OP_MATCH, OP_MATCH, OP_MISMATCH, OP_MATCH}}
+	if cigar := ar.SamCigar(8); cigar != "2S2=1X1=2S" {
+		t.Errorf("SamCigar expect: 2S2=1X1=2S get: %s", cigar)
+	}
+}
+
+func Test_Pretty(t *testing.T) {
+	aligner, _ := New("global", "ACGTACGT", traceConfig())
+	ar := aligner.Align("ACGTGGGACGT")
+	expect := "query  1 ACGT---ACGT 8\n         ||||   ||||\ntarget 1 ACGTGGGACGT 11"
+	if s := ar.Pretty("ACGTACGT", "ACGTGGGACGT"); s != expect {
+		t.Errorf("Pretty expect:\n%s\nget:\n%s", expect, s)
+	}
+}
@@ -0,0 +1,249 @@
+package align
+
+/**************************************** GOTOH *******************************
+  affine gap alignment, keep three state for each cell:
+    H   best score end at (i, j)
+    E   best score end with a gap in query  (left, consume target base)
+    F   best score end with a gap in target (up, consume query base)
+
+    E(i,j) = max(H(i,j-1) + gapOpen, E(i,j-1) + gapExtend)
+    F(i,j) = max(H(i-1,j) + gapOpen, F(i-1,j) + gapExtend)
+    H(i,j) = max(H(i-1,j-1) + score(i,j), E(i,j), F(i,j))
+
+  the same as linear gap alignment when gapOpen == gapExtend
+
+  when traceback is needed, each cell record a trace byte:
+    bit 0-1   H direction: DIR_NONE, DIR_LEFT, DIR_UP, DIR_DIAG
+    bit 2     E extended from E(i,j-1), else opened from H(i,j-1)
+    bit 3     F extended from F(i-1,j), else opened from H(i-1,j)
+******************************************************************************/
+
+const minScore int = -1 << 30 // score of impossible cell
+
+const (
+	traceDir  byte = 3 // mask of H direction
+	traceEExt byte = 4 // E extended
+	traceFExt byte = 8 // F extended
+)
+
+const (
+	stateH int = iota
+	stateE
+	stateF
+)
+
+// gapScore return score of a gap with length n
+func (c *Config) gapScore(n int) int {
+	if n <= 0 {
+		return 0
+	}
+	return c.GapOpen + (n-1)*c.GapExtend
+}
+
+// gapCell return cell of gap state from open cell or extend cell, and extended or not
+func gapCell(open, extend cell, conf *Config) (cell, bool) {
+	if open.score+conf.GapOpen >= extend.score+conf.GapExtend {
+		open.score += conf.GapOpen
+		open.errors++
+		return open, false
+	}
+	extend.score += conf.GapExtend
+	extend.errors++
+	return extend, true
+}
+
+func better(score, matchs int, best *AlignResult) bool {
+	return score > best.Score || (score == best.Score && matchs >= best.Matchs)
+}
+
+// gotoh align query to target using method LOCAL, GLOCAL or GLOBAL
+func gotoh(method int, query, target string, conf *Config) *AlignResult {
+	return new(workspace).gotoh(method, query, target, conf)
+}
+
+func (ws *workspace) gotoh(method int, query, target string, conf *Config) *AlignResult {
+	var temp, diag, up, left cell
+	var is_match, score int
+	var upExt, leftExt bool
+	var trace []byte
+
+	best_align := AlignResult{}
+	qlen := len(query)
+	tlen := len(target)
+	rows := ws.cells(0, qlen+1) // H of current col
+	gaps := ws.cells(1, qlen+1) // E of current col
+	if conf.Traceback {
+		trace = ws.traceback((qlen + 1) * (tlen + 1))
+	}
+
+	// init [0] col qstart
+	for i := 0; i < qlen+1; i++ {
+		rows[i] = cell{qstart: i}
+		if method != LOCAL {
+			rows[i].score = conf.gapScore(i)
+		}
+		if method == GLOBAL { // leading gap is error in global alignment
+			rows[i].errors = i
+		}
+		gaps[i] = cell{score: minScore}
+	}
+
+	for j := 1; j < tlen+1; j++ { // align each col one by one
+		temp = rows[0]
+		rows[0] = cell{tstart: j}
+		if method == GLOBAL {
+			rows[0].score = conf.gapScore(j)
+			rows[0].errors = j
+		}
+		up = cell{score: minScore} // F of row 0
+
+		for i := 1; i < qlen+1; i++ {
+			score, is_match = conf.score(query[i-1], target[j-1])
+			diag = temp
+			diag.score += score
+			diag.matchs += is_match
+			diag.errors += 1 - is_match
+
+			up, upExt = gapCell(rows[i-1], up, conf)
+			left, leftExt = gapCell(rows[i], gaps[i], conf)
+			gaps[i] = left
+
+			temp = rows[i] // record current row for next diag compare
+
+			// update current row
+			dir := DIR_DIAG
+			if diag.score >= up.score && diag.score >= left.score { // match or mismatch
+				rows[i] = diag
+			} else if up.score >= left.score { // insert
+				rows[i] = up
+				dir = DIR_UP
+			} else { // delete
+				rows[i] = left
+				dir = DIR_LEFT
+			}
+			if trace != nil {
+				if method == LOCAL && rows[i].score < 0 {
+					dir = DIR_NONE
+				}
+				trace[j*(qlen+1)+i] = traceByte(dir, leftExt, upExt)
+			}
+
+			if method != LOCAL {
+				continue
+			}
+			if rows[i].score < 0 { // reset alignment start if align score too low
+				rows[i] = cell{qstart: i, tstart: j}
+				gaps[i] = cell{score: minScore}
+				up = cell{score: minScore}
+			} else if c := rows[i]; float64(c.errors) <= float64(j-c.tstart)*conf.ErrorRate && better(c.score, c.matchs, &best_align) {
+				best_align = AlignResult{Qstart: c.qstart, Qend: i, Tstart: c.tstart, Tend: j,
+					Score: c.score, Matchs: c.matchs, Errors: c.errors}
+			}
+		}
+
+		// check the last row for the max row alignment
+		if c := rows[qlen]; method == GLOCAL && qlen > 0 &&
+			float64(c.errors) <= conf.ErrorRate*float64(j-c.tstart) && better(c.score, c.matchs, &best_align) {
+			best_align = AlignResult{Qstart: c.qstart, Qend: qlen, Tstart: c.tstart, Tend: j,
+				Score: c.score, Matchs: c.matchs, Errors: c.errors}
+		}
+	}
+
+	switch method {
+	case GLOCAL: // check the last col for the max row alignment
+		for i := 1; i < qlen; i++ {
+			if c := rows[i]; float64(c.errors) <= conf.ErrorRate*float64(tlen-c.tstart) && better(c.score, c.matchs, &best_align) {
+				best_align = AlignResult{Qstart: c.qstart, Qend: i, Tstart: c.tstart, Tend: tlen,
+					Score: c.score, Matchs: c.matchs, Errors: c.errors}
+			}
+		}
+	case GLOBAL: // check the last cell
+		if c := rows[qlen]; float64(c.errors) <= float64(tlen)*conf.ErrorRate {
+			best_align = AlignResult{Qstart: 0, Qend: qlen, Tstart: 0, Tend: tlen,
+				Score: c.score, Matchs: c.matchs, Errors: c.errors}
+		}
+	}
+	if trace != nil && (best_align.Qend > 0 || best_align.Tend > 0) {
+		at := func(i, j int) byte {
+			return trace[j*(qlen+1)+i]
+		}
+		best_align.Ops = traceback(method, query, target, at, &best_align, conf)
+	}
+	return &best_align
+}
+
+func traceByte(dir int, leftExt, upExt bool) byte {
+	b := byte(dir)
+	if leftExt {
+		b |= traceEExt
+	}
+	if upExt {
+		b |= traceFExt
+	}
+	return b
+}
+
+// traceback trace from alignment end cell back to start cell, return edit operations
+// at return trace byte of cell (i, j)
+func traceback(method int, query, target string, at func(i, j int) byte, ar *AlignResult, conf *Config) Ops {
+	ops := Ops{}
+	i, j := ar.Qend, ar.Tend
+	state := stateH
+	for i > 0 || j > 0 {
+		if state == stateH && (i == 0 || j == 0) { // reach the first row or col
+			if method != GLOBAL {
+				break
+			}
+			for ; i > 0; i-- {
+				ops = append(ops, OP_INSERT)
+			}
+			for ; j > 0; j-- {
+				ops = append(ops, OP_DELETE)
+			}
+			break
+		}
+
+		t := at(i, j)
+		switch state {
+		case stateE: // gap in query
+			ops = append(ops, OP_DELETE)
+			j--
+			if t&traceEExt == 0 {
+				state = stateH
+			}
+			continue
+		case stateF: // gap in target
+			ops = append(ops, OP_INSERT)
+			i--
+			if t&traceFExt == 0 {
+				state = stateH
+			}
+			continue
+		}
+
+		dir := int(t & traceDir)
+		if dir == DIR_NONE { // reach local alignment start
+			break
+		}
+		switch dir {
+		case DIR_DIAG:
+			if _, is_match := conf.score(query[i-1], target[j-1]); is_match == 1 {
+				ops = append(ops, OP_MATCH)
+			} else {
+				ops = append(ops, OP_MISMATCH)
+			}
+			i--
+			j--
+		case DIR_UP:
+			state = stateF
+		case DIR_LEFT:
+			state = stateE
+		}
+	}
+
+	// reverse ops in alignment order
+	for l, r := 0, len(ops)-1; l < r; l, r = l+1, r-1 {
+		ops[l], ops[r] = ops[r], ops[l]
+	}
+	return ops
+}
@@ -0,0 +1,203 @@
+// substitution score matrix for nucleotide and protein alignment
+// matrix file use NCBI format, eg. BLOSUM62, EDNAFULL:
+//   # comment line
+//      A  R  N ...
+//   A  4 -1 -2 ...
+//   R -1  5  0 ...
+
+package align
+
+import (
+	"fmt"
+	"gongs/scan"
+	"gongs/xopen"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ****************************** Alphabet Setting ****************************
+
+const DNA_ALPHABET string = "ACGTN"
+const PROTEIN_ALPHABET string = "ARNDCQEGHILKMFPSTWYVBZX*"
+
+// ****************************** Matrix **************************************
+
+type Matrix struct {
+	Name     string
+	alphabet string        // matrix letters in file order
+	scores   [256][256]int // score of each letter pair, case insensitive
+	known    [256]bool     // letter in matrix or not
+	min      int           // lowest score, used for unknown letters, math.MaxInt before any set
+}
+
+// Score return substitution score of letter a and b
+func (m *Matrix) Score(a, b byte) int {
+	if !m.known[a] || !m.known[b] {
+		return m.min
+	}
+	return m.scores[a][b]
+}
+
+// Alphabet return letters defined in matrix
+func (m *Matrix) Alphabet() string {
+	return m.alphabet
+}
+
+func (m *Matrix) String() string {
+	return fmt.Sprintf("Matrix(%s, alphabet:%s)", m.Name, m.alphabet)
+}
+
+func (m *Matrix) set(a, b byte, score int) {
+	for _, x := range caseOf(a) {
+		for _, y := range caseOf(b) {
+			m.scores[x][y] = score
+			m.known[x] = true
+			m.known[y] = true
+		}
+	}
+	if score < m.min {
+		m.min = score
+	}
+}
+
+func caseOf(c byte) []byte {
+	if c >= 'a' && c <= 'z' {
+		return []byte{c, c - 'a' + 'A'}
+	} else if c >= 'A' && c <= 'Z' {
+		return []byte{c, c - 'A' + 'a'}
+	}
+	return []byte{c}
+}
+
+// NewMatrix build a simple matrix using match and mismatch score over alphabet
+func NewMatrix(name, alphabet string, match, mismatch int) *Matrix {
+	m := &Matrix{Name: name, alphabet: alphabet, min: math.MaxInt}
+	for i := 0; i < len(alphabet); i++ {
+		for j := 0; j < len(alphabet); j++ {
+			if i == j {
+				m.set(alphabet[i], alphabet[j], match)
+			} else {
+				m.set(alphabet[i], alphabet[j], mismatch)
+			}
+		}
+	}
+	return m
+}
+
+// ParseMatrix read NCBI format matrix
+func ParseMatrix(name string, r io.Reader) (*Matrix, error) {
+	m := &Matrix{Name: name, min: math.MaxInt}
+	s := scan.New(r)
+	header := []byte{}
+	for s.Scan() {
+		line := strings.TrimSpace(s.Line())
+		if len(line) == 0 || line[0] == '#' { // skip empty and comment line
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(header) == 0 { // first line is column letters
+			for _, field := range fields {
+				if len(field) != 1 {
+					return nil, fmt.Errorf("Matrix %s Wrong Header Letter: %s at line: %d", name, field, s.Lid())
+				}
+				header = append(header, field[0])
+			}
+			m.alphabet = string(header)
+			continue
+		}
+		if len(fields[0]) != 1 || len(fields) != len(header)+1 {
+			return nil, fmt.Errorf("Matrix %s Wrong Row: %s at line: %d", name, line, s.Lid())
+		}
+		for i, field := range fields[1:] {
+			score, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("Matrix %s Wrong Score: %s at line: %d", name, field, s.Lid())
+			}
+			m.set(fields[0][0], header[i], score)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(header) == 0 || m.min == math.MaxInt { // no header or no score row
+		return nil, fmt.Errorf("Matrix %s is empty%s", name, "!")
+	}
+	return m, nil
+}
+
+// LoadMatrix load NCBI format matrix from file
+func LoadMatrix(filename string) (*Matrix, error) {
+	file, err := xopen.Xopen(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseMatrix(filename, file)
+}
+
+// GetMatrix return builtin matrix by name: BLOSUM62, EDNAFULL
+func GetMatrix(name string) (*Matrix, error) {
+	switch strings.ToUpper(name) {
+	case "BLOSUM62":
+		return ParseMatrix("BLOSUM62", strings.NewReader(blosum62))
+	case "EDNAFULL", "NUC.4.4":
+		return ParseMatrix("EDNAFULL", strings.NewReader(ednafull))
+	}
+	return nil, fmt.Errorf("Unkown Matrix Name: %s", name)
+}
+
+// ****************************** Builtin Matrix ******************************
+
+const blosum62 = `
+#  Matrix made by matblas from blosum62.iij
+#  BLOSUM Clustered Scoring Matrix in 1/2 Bit Units
+   A  R  N  D  C  Q  E  G  H  I  L  K  M  F  P  S  T  W  Y  V  B  Z  X  *
+A  4 -1 -2 -2  0 -1 -1  0 -2 -1 -1 -1 -1 -2 -1  1  0 -3 -2  0 -2 -1  0 -4
+R -1  5  0 -2 -3  1  0 -2  0 -3 -2  2 -1 -3 -2 -1 -1 -3 -2 -3 -1  0 -1 -4
+N -2  0  6  1 -3  0  0  0  1 -3 -3  0 -2 -3 -2  1  0 -4 -2 -3  3  0 -1 -4
+D -2 -2  1  6 -3  0  2 -1 -1 -3 -4 -1 -3 -3 -1  0 -1 -4 -3 -3  4  1 -1 -4
+C  0 -3 -3 -3  9 -3 -4 -3 -3 -1 -1 -3 -1 -2 -3 -1 -1 -2 -2 -1 -3 -3 -2 -4
+Q -1  1  0  0 -3  5  2 -2  0 -3 -2  1  0 -3 -1  0 -1 -2 -1 -2  0  3 -1 -4
+E -1  0  0  2 -4  2  5 -2  0 -3 -3  1 -2 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+G  0 -2  0 -1 -3 -2 -2  6 -2 -4 -4 -2 -3 -3 -2  0 -2 -2 -3 -3 -1 -2 -1 -4
+H -2  0  1 -1 -3  0  0 -2  8 -3 -3 -1 -2 -1 -2 -1 -2 -2  2 -3  0  0 -1 -4
+I -1 -3 -3 -3 -1 -3 -3 -4 -3  4  2 -3  1  0 -3 -2 -1 -3 -1  3 -3 -3 -1 -4
+L -1 -2 -3 -4 -1 -2 -3 -4 -3  2  4 -2  2  0 -3 -2 -1 -2 -1  1 -4 -3 -1 -4
+K -1  2  0 -1 -3  1  1 -2 -1 -3 -2  5 -1 -3 -1  0 -1 -3 -2 -2  0  1 -1 -4
+M -1 -1 -2 -3 -1  0 -2 -3 -2  1  2 -1  5  0 -2 -1 -1 -1 -1  1 -3 -1 -1 -4
+F -2 -3 -3 -3 -2 -3 -3 -3 -1  0  0 -3  0  6 -4 -2 -2  1  3 -1 -3 -3 -1 -4
+P -1 -2 -2 -1 -3 -1 -1 -2 -2 -3 -3 -1 -2 -4  7 -1 -1 -4 -3 -2 -2 -1 -2 -4
+S  1 -1  1  0 -1  0  0  0 -1 -2 -2  0 -1 -2 -1  4  1 -3 -2 -2  0  0  0 -4
+T  0 -1  0 -1 -1 -1 -1 -2 -2 -1 -1 -1 -1 -2 -1  1  5 -2 -2  0 -1 -1  0 -4
+W -3 -3 -4 -4 -2 -2 -3 -2 -2 -3 -2 -3 -1  1 -4 -3 -2 11  2 -3 -4 -3 -2 -4
+Y -2 -2 -2 -3 -2 -1 -2 -3  2 -1 -1 -2 -1  3 -3 -2 -2  2  7 -1 -3 -2 -1 -4
+V  0 -3 -3 -3 -1 -2 -2 -3 -3  3  1 -2  1 -1 -2 -2  0 -3 -1  4 -3 -2 -1 -4
+B -2 -1  3  4 -3  0  1 -1  0 -3 -4  0 -3 -3 -2  0 -1 -4 -3 -3  4  1 -1 -4
+Z -1  0  0  1 -3  3  4 -2  0 -3 -3  1 -1 -3 -1  0 -1 -3 -2 -2  1  4 -1 -4
+X  0 -1 -1 -1 -2 -1 -1 -1 -1 -1 -1 -1 -1 -1 -2  0  0 -2 -1 -1 -1 -1 -1 -4
+* -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4 -4  1
+`
+
+const ednafull = `
+# This matrix was created by Todd Lowe   12/10/92
+# Uses ambiguous nucleotide codes, probabilities rounded to
+#  nearest integer
+    A   T   G   C   S   W   R   Y   K   M   B   V   H   D   N
+A   5  -4  -4  -4  -4   1   1  -4  -4   1  -4  -1  -1  -1  -2
+T  -4   5  -4  -4  -4   1  -4   1   1  -4  -1  -4  -1  -1  -2
+G  -4  -4   5  -4   1  -4   1  -4   1  -4  -1  -1  -4  -1  -2
+C  -4  -4  -4   5   1  -4  -4   1  -4   1  -1  -1  -1  -4  -2
+S  -4  -4   1   1  -1  -4  -2  -2  -2  -2  -1  -1  -3  -3  -1
+W   1   1  -4  -4  -4  -1  -2  -2  -2  -2  -3  -3  -1  -1  -1
+R   1  -4   1  -4  -2  -2  -1  -4  -2  -2  -3  -1  -3  -1  -1
+Y  -4   1  -4   1  -2  -2  -4  -1  -2  -2  -1  -3  -1  -3  -1
+K  -4   1   1  -4  -2  -2  -2  -2  -1  -4  -1  -3  -3  -1  -1
+M   1  -4  -4   1  -2  -2  -2  -2  -4  -1  -3  -1  -1  -3  -1
+B  -4  -1  -1  -1  -1  -3  -3  -1  -1  -3  -1  -2  -2  -2  -1
+V  -1  -4  -1  -1  -1  -3  -1  -3  -3  -1  -2  -1  -2  -2  -1
+H  -1  -1  -4  -1  -3  -1  -3  -1  -3  -1  -2  -2  -1  -2  -1
+D  -1  -1  -1  -4  -3  -1  -1  -3  -1  -3  -2  -2  -2  -1  -1
+N  -2  -2  -2  -2  -1  -1  -1  -1  -1  -1  -1  -1  -1  -1  -1
+`
@@ -0,0 +1,55 @@
+package align
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_GetMatrix(t *testing.T) {
+	for _, name := range []string{"BLOSUM62", "EDNAFULL"} {
+		m, err := GetMatrix(name)
+		if err != nil {
+			t.Fatal("GetMatrix error:", err)
+		}
+		alphabet := m.Alphabet()
+		for i := 0; i < len(alphabet); i++ {
+			for j := 0; j < len(alphabet); j++ {
+				if a, b := m.Score(alphabet[i], alphabet[j]), m.Score(alphabet[j], alphabet[i]); a != b {
+					t.Errorf("%s not symmetric at %c%c: %d != %d", name, alphabet[i], alphabet[j], a, b)
+				}
+			}
+		}
+	}
+	if _, err := GetMatrix("PAM1"); err == nil {
+		t.Error("GetMatrix unkown matrix expect error")
+	}
+}
+
+func Test_ParseMatrix(t *testing.T) {
+	m, err := ParseMatrix("test", strings.NewReader("# test\n   A  C\nA  2 -1\nC -1  3\n"))
+	if err != nil {
+		t.Fatal("ParseMatrix error:", err)
+	}
+	if s := m.Score('a', 'A'); s != 2 {
+		t.Errorf("Score aA expect: 2 get: %d", s)
+	}
+	if s := m.Score('C', 'c'); s != 3 {
+		t.Errorf("Score Cc expect: 3 get: %d", s)
+	}
+	if s := m.Score('A', 'G'); s != -1 {
+		t.Errorf("Score of unkown letter expect: -1 get: %d", s)
+	}
+	m, err = ParseMatrix("positive", strings.NewReader("   A  C\nA  5  1\nC  1  5\n"))
+	if err != nil {
+		t.Fatal("ParseMatrix error:", err)
+	}
+	if s := m.Score('A', 'N'); s != 1 {
+		t.Errorf("Score of unkown letter in positive matrix expect: 1 get: %d", s)
+	}
+	if s := NewMatrix("simple", "AC", 2, 1).Score('A', 'N'); s != 1 {
+		t.Errorf("Score of unkown letter in simple matrix expect: 1 get: %d", s)
+	}
+	if _, err := ParseMatrix("bad", strings.NewReader("   A  C\nA  2\n")); err == nil {
+		t.Error("ParseMatrix wrong row expect error")
+	}
+}
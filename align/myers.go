@@ -0,0 +1,238 @@
+package align
+
+import "fmt"
+
+/**************************************** MYERS *******************************
+  bit-parallel approximate string matching (Myers 1999, Hyyro 2003)
+  find all end positions of pattern in target with edit distance <= k
+
+  each column of the dp matrix is encoded by vertical delta bit vectors:
+    Pv   bit i set if D(i,j) - D(i-1,j) == +1
+    Mv   bit i set if D(i,j) - D(i-1,j) == -1
+  pattern longer than 64 is split into blocks of 64, the horizontal delta
+  of the block bottom row is carried into the next block.
+
+  search mode: D(0,j) = 0, pattern can start at any target position
+  prefix mode: D(0,j) = j, used to recover the start of a hit on the
+               reversed pattern and target
+******************************************************************************/
+
+const wordSize = 64
+
+// Hit record an approximate match of pattern in target
+type Hit struct {
+	Tstart int // start position at target
+	Tend   int // end position at target
+	Errors int // edit distance of pattern and target[Tstart:Tend]
+}
+
+func (h Hit) String() string {
+	return fmt.Sprintf("Hit(tstart:%d, tend:%d, errors:%d)", h.Tstart, h.Tend, h.Errors)
+}
+
+// peq pattern match bit vectors of each letter
+type peq struct {
+	m      int           // pattern length
+	blocks int           // block number
+	high   uint64        // bottom bit of the last block
+	eq     [256][]uint64 // eq[c][b] bit i set if pattern[b*64+i] match c
+}
+
+func newPeq(pattern string, wild int) *peq {
+	m := len(pattern)
+	p := &peq{m: m, blocks: (m + wordSize - 1) / wordSize}
+	p.high = uint64(1) << uint((m-1)%wordSize)
+	for c := 0; c < 256; c++ {
+		p.eq[c] = make([]uint64, p.blocks)
+		for i := 0; i < m; i++ {
+			if pattern[i] == byte(c) || (wild&WILD_QUERY != 0 && pattern[i] == WILD_CHAR) ||
+				(wild&WILD_TARGET != 0 && byte(c) == WILD_CHAR) {
+				p.eq[c][i/wordSize] |= uint64(1) << uint(i%wordSize)
+			}
+		}
+	}
+	return p
+}
+
+// advanceBlock advance one block by one target letter, return new Pv, Mv and bottom horizontal delta
+func advanceBlock(pv, mv, eq uint64, hin int, high uint64) (uint64, uint64, int) {
+	var hinNeg uint64
+	if hin < 0 {
+		hinNeg = 1
+	}
+	xv := eq | mv
+	eq |= hinNeg
+	xh := (((eq & pv) + pv) ^ pv) | eq
+	ph := mv | ^(xh | pv)
+	mh := pv & xh
+
+	hout := 0
+	if ph&high != 0 {
+		hout = 1
+	} else if mh&high != 0 {
+		hout = -1
+	}
+
+	ph <<= 1
+	mh <<= 1
+	mh |= hinNeg
+	if hin > 0 {
+		ph |= 1
+	}
+	pv = mh | ^(xv | ph)
+	mv = ph & xv
+	return pv, mv, hout
+}
+
+// scan run the pattern over target, call fn with end position and edit distance of each column
+// prefix mode set D(0,j) = j, else D(0,j) = 0
+func (p *peq) scan(target string, prefix bool, pv, mv []uint64, fn func(end, errors int) bool) {
+	for b := 0; b < p.blocks; b++ {
+		pv[b] = ^uint64(0)
+		mv[b] = 0
+	}
+	top := 0
+	if prefix {
+		top = 1
+	}
+	high := uint64(1) << (wordSize - 1)
+	last := p.blocks - 1
+	score := p.m
+	for j := 0; j < len(target); j++ {
+		eq := p.eq[target[j]]
+		hin := top
+		for b := 0; b < last; b++ {
+			pv[b], mv[b], hin = advanceBlock(pv[b], mv[b], eq[b], hin, high)
+		}
+		pv[last], mv[last], hin = advanceBlock(pv[last], mv[last], eq[last], hin, p.high)
+		score += hin
+		if !fn(j+1, score) {
+			return
+		}
+	}
+}
+
+type MyersAligner struct {
+	seq  string
+	conf *Config
+	peq  *peq // bit vectors of seq
+	rpeq *peq // bit vectors of reversed seq, for start recovery
+}
+
+func newMyersAligner(seq string, conf *Config) *MyersAligner {
+	return &MyersAligner{
+		seq:  seq,
+		conf: conf,
+		peq:  newPeq(seq, conf.Wild),
+		rpeq: newPeq(reverse(seq), conf.Wild),
+	}
+}
+
+func reverse(s string) string {
+	buf := make([]byte, len(s))
+	for i, l := 0, len(s); i < l; i++ {
+		buf[l-1-i] = s[i]
+	}
+	return string(buf)
+}
+
+// maxErrors return max edit distance allowed for pattern with length m
+func (c *Config) maxErrors(m int) int {
+	if c.MaxErrors > 0 {
+		return c.MaxErrors
+	}
+	return int(c.ErrorRate * float64(m))
+}
+
+// search find all end positions with edit distance <= k, recover the start of each hit
+func search(p, rp *peq, target string, k int) []Hit {
+	return new(workspace).search(p, rp, target, k)
+}
+
+func (ws *workspace) search(p, rp *peq, target string, k int) []Hit {
+	hits := []Hit{}
+	pv, mv := ws.words(p.blocks)
+	p.scan(target, false, pv, mv, func(end, errors int) bool {
+		if errors <= k {
+			hits = append(hits, Hit{Tend: end, Errors: errors})
+		}
+		return true
+	})
+	for i := range hits {
+		hits[i].Tstart = recoverStart(rp, target, hits[i], pv, mv)
+	}
+	return hits
+}
+
+// recoverStart scan reversed pattern over reversed target from hit end, the first
+// position reach hit errors is the hit start
+func recoverStart(rp *peq, target string, hit Hit, pv, mv []uint64) int {
+	start := hit.Tend - rp.m - hit.Errors
+	if start < 0 {
+		start = 0
+	}
+	tstart := start
+	rp.scan(reverse(target[start:hit.Tend]), true, pv, mv, func(end, errors int) bool {
+		if errors <= hit.Errors {
+			tstart = hit.Tend - end
+			return false
+		}
+		return true
+	})
+	return tstart
+}
+
+// best return hit with the lowest errors, the leftmost one if tie
+func best(hits []Hit) *Hit {
+	var h *Hit
+	for i := range hits {
+		if h == nil || hits[i].Errors < h.Errors {
+			h = &hits[i]
+		}
+	}
+	return h
+}
+
+// result build AlignResult of hit, matchs and edit operations come from global alignment
+// of query and the hit region using edit distance score
+func (ma *MyersAligner) result(ws *workspace, query, target string, hit *Hit) *AlignResult {
+	if hit == nil {
+		return &AlignResult{}
+	}
+	conf := &Config{Match: 0, Mismatch: -1, GapOpen: -1, GapExtend: -1,
+		Wild: ma.conf.Wild, ErrorRate: float64(len(query) + 1), Traceback: ma.conf.Traceback}
+	ar := ws.gotoh(GLOBAL, query, target[hit.Tstart:hit.Tend], conf)
+	ar.Tstart += hit.Tstart
+	ar.Tend += hit.Tstart
+	return ar
+}
+
+// Search return all hits of aligner seq in target with edit distance <= k
+func (ma *MyersAligner) Search(target string) []Hit {
+	return search(ma.peq, ma.rpeq, target, ma.conf.maxErrors(ma.peq.m))
+}
+
+// Align align aligner seq to target, Score is the negative edit distance
+func (ma *MyersAligner) Align(target string) *AlignResult {
+	return ma.alignWith(new(workspace), target)
+}
+
+func (ma *MyersAligner) alignWith(ws *workspace, target string) *AlignResult {
+	hits := ws.search(ma.peq, ma.rpeq, target, ma.conf.maxErrors(ma.peq.m))
+	return ma.result(ws, ma.seq, target, best(hits))
+}
+
+// AlignTo align query to aligner seq, Score is the negative edit distance
+func (ma *MyersAligner) AlignTo(query string) *AlignResult {
+	if query == "" {
+		return &AlignResult{}
+	}
+	p := newPeq(query, ma.conf.Wild)
+	rp := newPeq(reverse(query), ma.conf.Wild)
+	ws := new(workspace)
+	return ma.result(ws, query, ma.seq, best(ws.search(p, rp, ma.seq, ma.conf.maxErrors(len(query)))))
+}
+
+func (ma *MyersAligner) String() string {
+	return fmt.Sprintf("MyersAligner(Seq:%s, maxErrors:%d, wild:%d)", ma.seq, ma.conf.maxErrors(len(ma.seq)), ma.conf.Wild)
+}
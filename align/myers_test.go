@@ -0,0 +1,187 @@
+package align
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func randSeq(r *rand.Rand, n int) string {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = "ACGT"[r.Intn(4)]
+	}
+	return string(buf)
+}
+
+// editEnds return edit distance of pattern end at each target position using dp
+func editEnds(pattern, target string) []int {
+	m := len(pattern)
+	col := make([]int, m+1)
+	for i := range col {
+		col[i] = i
+	}
+	ends := make([]int, len(target))
+	for j := 0; j < len(target); j++ {
+		diag := col[0]
+		col[0] = 0
+		for i := 1; i <= m; i++ {
+			d := diag
+			if pattern[i-1] != target[j] {
+				d++
+			}
+			if col[i]+1 < d {
+				d = col[i] + 1
+			}
+			if col[i-1]+1 < d {
+				d = col[i-1] + 1
+			}
+			diag = col[i]
+			col[i] = d
+		}
+		ends[j] = col[m]
+	}
+	return ends
+}
+
+// editDistance return edit distance of a and b using dp
+func editDistance(a, b string) int {
+	col := make([]int, len(a)+1)
+	for i := range col {
+		col[i] = i
+	}
+	for j := 0; j < len(b); j++ {
+		diag := col[0]
+		col[0] = j + 1
+		for i := 1; i <= len(a); i++ {
+			d := diag
+			if a[i-1] != b[j] {
+				d++
+			}
+			if col[i]+1 < d {
+				d = col[i] + 1
+			}
+			if col[i-1]+1 < d {
+				d = col[i-1] + 1
+			}
+			diag = col[i]
+			col[i] = d
+		}
+	}
+	return col[len(a)]
+}
+
+func Test_Myers_search(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		pattern := randSeq(r, 1+r.Intn(150))
+		target := randSeq(r, 1+r.Intn(300))
+		k := r.Intn(len(pattern)/3 + 1)
+		ends := editEnds(pattern, target)
+
+		p, rp := newPeq(pattern, WILD_NONE), newPeq(reverse(pattern), WILD_NONE)
+		hits := search(p, rp, target, k)
+		expect := 0
+		for j, e := range ends {
+			if e <= k {
+				expect++
+				found := false
+				for _, h := range hits {
+					if h.Tend == j+1 && h.Errors == e {
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("pattern %s target %s end %d errors %d not found", pattern, target, j+1, e)
+				}
+			}
+		}
+		if expect != len(hits) {
+			t.Fatalf("pattern %s target %s expect hits: %d get: %d", pattern, target, expect, len(hits))
+		}
+		for _, h := range hits {
+			if e := editDistance(pattern, target[h.Tstart:h.Tend]); e != h.Errors {
+				t.Fatalf("pattern %s target %s hit: %v edit distance: %d", pattern, target, h, e)
+			}
+		}
+	}
+}
+
+func Test_Myers_Align(t *testing.T) {
+	conf := DefaultConfig()
+	conf.MaxErrors = 2
+	conf.Traceback = true
+	aligner, err := New("myers", "AGATCGGAAGAGC", conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := "TTTTTTTTTTAGATCGGTAGAGCTTTTT"
+	ar := aligner.Align(target)
+	if ar.Tstart != 10 || ar.Tend != 23 || ar.Errors != 1 || ar.Score != -1 || ar.Cigar() != "7=1X5=" {
+		t.Errorf("Myers Align expect (10, 23, errors 1, 7=1X5=) get: %v %s", ar, ar.Cigar())
+	}
+	if ar := aligner.Align("TTTTTTTTTTTTTTTTTTTTTT"); ar.Tend != 0 || ar.Errors != 0 {
+		t.Errorf("Myers Align expect no hit get: %v", ar)
+	}
+
+	myers := aligner.(*MyersAligner)
+	if hits := myers.Search(target); len(hits) == 0 || best(hits).Tend != 23 {
+		t.Errorf("Myers Search best expect end: 23 get: %v", hits)
+	}
+	if ar := aligner.AlignTo("AGATCGGAA"); ar.Tstart != 0 || ar.Tend != 9 || ar.Errors != 0 {
+		t.Errorf("Myers AlignTo expect (0, 9, errors 0) get: %v", ar)
+	}
+}
+
+func Test_Myers_wild(t *testing.T) {
+	conf := DefaultConfig()
+	conf.MaxErrors = 1
+	aligner, _ := New("myers", "ACNNGT", conf)
+	if ar := aligner.Align("TTACTAGTTT"); ar.Errors != 0 || ar.Tstart != 2 || ar.Tend != 8 {
+		t.Errorf("Myers wild expect (2, 8, errors 0) get: %v", ar)
+	}
+}
+
+var benchAdapter = "AGATCGGAAGAGCACACGTCT"
+
+func benchReads() []string {
+	r := rand.New(rand.NewSource(1))
+	reads := make([]string, 1000)
+	for i := range reads {
+		read := randSeq(r, 150)
+		if i%2 == 0 { // half reads with adapter
+			p := r.Intn(150 - len(benchAdapter))
+			read = read[:p] + benchAdapter + read[p+len(benchAdapter):]
+		}
+		reads[i] = read
+	}
+	return reads
+}
+
+func BenchmarkMyers(b *testing.B) {
+	conf := DefaultConfig()
+	conf.MaxErrors = 2
+	aligner, _ := New("myers", benchAdapter, conf)
+	reads := benchReads()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		aligner.Align(reads[i%len(reads)])
+	}
+}
+
+func BenchmarkLocal(b *testing.B) {
+	aligner, _ := New("local", benchAdapter, nil)
+	reads := benchReads()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		aligner.Align(reads[i%len(reads)])
+	}
+}
+
+func BenchmarkGlocal(b *testing.B) {
+	aligner, _ := New("glocal", benchAdapter, nil)
+	reads := benchReads()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		aligner.Align(reads[i%len(reads)])
+	}
+}
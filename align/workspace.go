@@ -0,0 +1,44 @@
+package align
+
+// workspace reusable dp buffers of aligner, not safe for concurrent use,
+// each goroutine should keep its own workspace
+type workspace struct {
+	rows  [4][]cell // cell rows, gotoh use 2, banded use 4
+	trace []byte    // traceback matrix
+	pv    []uint64  // myers vertical positive delta
+	mv    []uint64  // myers vertical negative delta
+}
+
+// bufferedAligner aligner can align using a workspace
+type bufferedAligner interface {
+	alignWith(ws *workspace, target string) *AlignResult
+}
+
+// cells return cell row k with length n
+func (ws *workspace) cells(k, n int) []cell {
+	if cap(ws.rows[k]) < n {
+		ws.rows[k] = make([]cell, n)
+	}
+	return ws.rows[k][:n]
+}
+
+// traceback return cleared traceback matrix with length n
+func (ws *workspace) traceback(n int) []byte {
+	if cap(ws.trace) < n {
+		ws.trace = make([]byte, n)
+	}
+	trace := ws.trace[:n]
+	for i := range trace {
+		trace[i] = 0
+	}
+	return trace
+}
+
+// words return myers bit vectors with n blocks
+func (ws *workspace) words(n int) ([]uint64, []uint64) {
+	if cap(ws.pv) < n {
+		ws.pv = make([]uint64, n)
+		ws.mv = make([]uint64, n)
+	}
+	return ws.pv[:n], ws.mv[:n]
+}
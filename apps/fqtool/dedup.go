@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"gongs/argparser"
+	"gongs/extsort"
+	"os"
+)
+
+const dedupName = "dedup"
+const dedupDesc = "remove reads of duplicated sequence, keep the copy of the highest quality"
+
+var dedupArger = argparser.New(mainName, dedupName)
+
+func init() {
+	dedupArger.Add("prefix", "-p", "--prefix", "output file prefix name, reads to prefix.fastq.gz or prefix.r1.fastq.gz prefix.r2.fastq.gz in sequence order", "dedup")
+	dedupArger.Add("pair", "-P", "--pair", "inputs are pairs of read1 read2, pairs are duplicated if both mates are the same", false)
+	dedupArger.Add("memory", "-m", "--memory", "memory of reads before saved to temp runs, eg. 512M, 2G", "512M")
+	dedupArger.Add("tempdir", "-T", "--temp-dir", "dir of temp runs, default system temp dir", "")
+	dedupArger.Add("uncompress", "-u", "--uncompress", "output plain fastq, not gzip", false)
+}
+
+func dedupRunner(args ...string) {
+	if len(args) == 0 {
+		dedupArger.Usage()
+		os.Exit(1)
+	}
+	if err := dedupRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func dedupRun(args ...string) error {
+	if err := dedupArger.Parse(args...); err != nil {
+		return err
+	}
+
+	s, err := newSorter(dedupArger, dedupName, extsort.BySeq)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	outs, err := newSortOutputs(dedupArger, s.Mates)
+	if err != nil {
+		return err
+	}
+	total, unique, err := extsort.Dedup(s, outs.write)
+	if e := outs.close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	rate := 0.0
+	if total > 0 {
+		rate = float64((total-unique)*100) / float64(total)
+	}
+	fmt.Printf("total\t%d\nunique\t%d\nduplicates\t%d\nduplication\t%.2f%%\n", total, unique, total-unique, rate)
+	return nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/demux"
+	"gongs/xopen"
+	"io"
+	"os"
+	"strings"
+)
+
+const demuxName = "demux"
+const demuxDesc = "demultiplex reads to samples by i7 and i5 index"
+
+var demuxArger = argparser.New(mainName, demuxName)
+
+func init() {
+	demuxArger.Add("sheet", "-s", "--sample-sheet", "csv sample sheet, Illumina v1, v2 or lines of id,index[,index2]", "!!")
+	demuxArger.Add("prefix", "-p", "--prefix", "output file prefix name, reads to prefix.sample.R1.fastq.gz", "demux")
+	demuxArger.Add("index1", "-i", "--index1", "i7 index reads file (I1), default index from read name", "")
+	demuxArger.Add("index2", "-I", "--index2", "i5 index reads file (I2), default index from read name", "")
+	demuxArger.Add("mismatches", "-m", "--mismatches", "mismatches allowed of each index (0-2)", 1)
+	demuxArger.Add("rc", "-r", "--rc-index2", "reverse complement index2 of sample sheet", false)
+	demuxArger.Add("top", "-n", "--top", "report top n undetermined barcodes", 20)
+}
+
+func demuxRunner(args ...string) {
+	if len(args) == 0 {
+		demuxArger.Usage()
+		os.Exit(1)
+	}
+	if err := demuxRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func demuxRun(args ...string) error {
+	if err := demuxArger.Parse(args...); err != nil {
+		return err
+	}
+
+	prefix := demuxArger.Get("prefix").(string)
+	index1 := demuxArger.Get("index1").(string)
+	index2 := demuxArger.Get("index2").(string)
+	filenames := demuxArger.Args
+	if len(filenames) == 0 || len(filenames) > 2 {
+		return fmt.Errorf("%s %s : need read1 or read1 read2 input!", mainName, demuxName)
+	}
+
+	samples, err := demux.LoadSampleSheet(demuxArger.Get("sheet").(string))
+	if err != nil {
+		return err
+	}
+	if demuxArger.Get("rc").(bool) {
+		for _, s := range samples {
+			s.Index2 = revComp(s.Index2)
+		}
+	}
+	matcher, err := demux.NewMatcher(samples, demuxArger.Get("mismatches").(int))
+	if err != nil {
+		return err
+	}
+
+	// reads files are followed by index files, all read in sync
+	files := filenames
+	for _, filename := range []string{index1, index2} {
+		if filename != "" {
+			files = append(files, filename)
+		}
+	}
+	outs, err := newDemuxOutputs(prefix, matcher.Samples, len(filenames))
+	if err != nil {
+		return err
+	}
+	err = demuxReads(matcher, outs, len(filenames), index1 != "", index2 != "", files...)
+	if e := outs.close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	return matcher.Save(prefix, demuxArger.Get("top").(int))
+}
+
+var indexComplement = strings.NewReplacer("A", "T", "T", "A", "C", "G", "G", "C")
+
+// revComp return reverse complement of index, bases other than ACGT are kept
+func revComp(index string) string {
+	rc := []byte(indexComplement.Replace(index))
+	for i, j := 0, len(rc)-1; i < j; i, j = i+1, j-1 {
+		rc[i], rc[j] = rc[j], rc[i]
+	}
+	return string(rc)
+}
+
+// demuxOutputs writers of each sample and the undetermined, samples of the same id
+// share writers
+type demuxOutputs struct {
+	writers [][]*bufio.Writer // by sample, the last is undetermined
+	closers []io.Closer
+}
+
+func newDemuxOutputs(prefix string, samples []*demux.Sample, reads int) (*demuxOutputs, error) {
+	outs := &demuxOutputs{}
+	byId := make(map[string][]*bufio.Writer)
+	ids := make([]string, 0, len(samples)+1)
+	for _, s := range samples {
+		ids = append(ids, s.ID)
+	}
+	ids = append(ids, demux.UNDETERMINED_ID)
+	for _, id := range ids {
+		if writers, ok := byId[id]; ok {
+			outs.writers = append(outs.writers, writers)
+			continue
+		}
+		writers := make([]*bufio.Writer, reads)
+		for i := range writers {
+			out, err := xopen.Xcreate(fmt.Sprintf("%s.%s.R%d.fastq.gz", prefix, id, i+1), "w")
+			if err != nil {
+				outs.close()
+				return nil, err
+			}
+			outs.closers = append(outs.closers, out)
+			writers[i] = bufio.NewWriter(out)
+		}
+		byId[id] = writers
+		outs.writers = append(outs.writers, writers)
+	}
+	return outs, nil
+}
+
+// close flush and close all writers, return the first error
+func (outs *demuxOutputs) close() error {
+	var err error
+	flushed := make(map[*bufio.Writer]bool)
+	for _, writers := range outs.writers {
+		for _, w := range writers {
+			if flushed[w] {
+				continue
+			}
+			flushed[w] = true
+			if e := w.Flush(); err == nil {
+				err = e
+			}
+		}
+	}
+	for _, c := range outs.closers {
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// demuxReads read files in sync, the first reads files are written to the matched sample,
+// index from index files if has1 or has2, else from name of read1
+func demuxReads(matcher *demux.Matcher, outs *demuxOutputs, reads int, has1, has2 bool, filenames ...string) error {
+	fqfiles, err := fastq.Opens(filenames...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, fqfile := range fqfiles {
+			fqfile.Close()
+		}
+	}()
+
+	fqs := make([]*fastq.Fastq, len(fqfiles))
+	for {
+		nexts := 0
+		for i, fqfile := range fqfiles {
+			if fqfile.Next() {
+				fqs[i] = fqfile.Fq()
+				nexts++
+			}
+		}
+		for _, fqfile := range fqfiles {
+			if err := fqfile.Err(); err != nil {
+				return err
+			}
+		}
+		if nexts == 0 {
+			return nil
+		} else if nexts != len(fqfiles) {
+			return fmt.Errorf("%s %s : input files have different reads number", mainName, demuxName)
+		}
+		for _, fq := range fqs[1:] {
+			if fq.MateId() != fqs[0].MateId() {
+				return fmt.Errorf("%s %s : reads out of sync: %s %s", mainName, demuxName, fqs[0].Id(), fq.Id())
+			}
+		}
+
+		i7, i5, _ := demux.HeaderIndex(fqs[0].Name)
+		n := reads
+		if has1 {
+			i7, n = string(fqs[n].Seq), n+1
+		}
+		if has2 {
+			i5 = string(fqs[n].Seq)
+		}
+		s := matcher.Assign(i7, i5)
+		if s == demux.UNDETERMINED {
+			s = len(outs.writers) - 1
+		}
+		for i, w := range outs.writers[s] {
+			fmt.Fprintln(w, fqs[i])
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/filter"
+	"gongs/xopen"
+	"io"
+	"os"
+)
+
+const filterName = "filter"
+const filterDesc = "filter reads by length, quality, N, complexity and name or sequence pattern"
+
+var filterArger = argparser.New(mainName, filterName)
+
+func init() {
+	filterArger.Add("prefix", "-p", "--prefix", "output file prefix name, reads to prefix.fastq or prefix.r1.fastq prefix.r2.fastq", "filter")
+	filterArger.Add("pair", "-P", "--pair", "inputs are read1 read2 pairs", false)
+	filterArger.Add("either", "-E", "--either", "a pair pass if either mate pass, default both mates must pass", false)
+	filterArger.Add("offset", "-o", "--offset", "quality encoding offset", 33)
+	filterArger.Add("minlen", "-l", "--min-len", "min read length", 0)
+	filterArger.Add("maxlen", "-L", "--max-len", "max read length, 0 no limit", 0)
+	filterArger.Add("minqual", "-q", "--min-qual", "min read mean quality", 0.0)
+	filterArger.Add("maxee", "-e", "--max-ee", "max expected errors (sum of base error probabilities), 0 no limit", 0.0)
+	filterArger.Add("maxn", "-n", "--max-n", "max fraction of N bases", 1.0)
+	filterArger.Add("dust", "-d", "--dust", "max DUST low complexity score (0-100, 7 suggested), 0 no limit", 0.0)
+	filterArger.Add("chastity", "-c", "--chastity", "drop reads failed Illumina chastity filter (:Y: in name)", false)
+	filterArger.Add("name", "-r", "--name-regex", "keep reads of name match regular expression", "")
+	filterArger.Add("seq", "-R", "--seq-regex", "keep reads of sequence match regular expression", "")
+}
+
+func filterRunner(args ...string) {
+	if len(args) == 0 {
+		filterArger.Usage()
+		os.Exit(1)
+	}
+	if err := filterRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// filterRules return rules of options, in order of cheap to expensive
+func filterRules() ([]filter.Rule, error) {
+	rules := []filter.Rule{}
+	offset := filterArger.Get("offset").(int)
+	if filterArger.Get("chastity").(bool) {
+		rules = append(rules, filter.Chastity())
+	}
+	if n := filterArger.Get("minlen").(int); n > 0 {
+		rules = append(rules, filter.MinLen(n))
+	}
+	if n := filterArger.Get("maxlen").(int); n > 0 {
+		rules = append(rules, filter.MaxLen(n))
+	}
+	if frac := filterArger.Get("maxn").(float64); frac < 1 {
+		rules = append(rules, filter.MaxNFrac(frac))
+	}
+	if q := filterArger.Get("minqual").(float64); q > 0 {
+		rules = append(rules, filter.MinMeanQual(q, offset))
+	}
+	if ee := filterArger.Get("maxee").(float64); ee > 0 {
+		rules = append(rules, filter.MaxEE(ee, offset))
+	}
+	if score := filterArger.Get("dust").(float64); score > 0 {
+		rules = append(rules, filter.MaxDust(score))
+	}
+	for _, m := range []struct {
+		opt   string
+		match func(string) (filter.Rule, error)
+	}{{"name", filter.NameMatch}, {"seq", filter.SeqMatch}} {
+		if pattern := filterArger.Get(m.opt).(string); pattern != "" {
+			r, err := m.match(pattern)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func filterRun(args ...string) error {
+	if err := filterArger.Parse(args...); err != nil {
+		return err
+	}
+
+	prefix := filterArger.Get("prefix").(string)
+	pair := filterArger.Get("pair").(bool)
+	filenames := filterArger.Args
+	if len(filenames) == 0 {
+		return fmt.Errorf("%s %s : no input given!", mainName, filterName)
+	}
+	if pair && len(filenames)%2 != 0 {
+		return fmt.Errorf("%s %s : %v", mainName, filterName, fastq.ErrUnPairInputFile)
+	}
+	rules, err := filterRules()
+	if err != nil {
+		return err
+	}
+	f := filter.New(rules...)
+	if filterArger.Get("either").(bool) {
+		f.Policy = filter.EITHER
+	}
+
+	if pair {
+		err = filterPair(f, prefix, filenames...)
+	} else {
+		err = filterSingle(f, prefix, filenames...)
+	}
+	if err != nil {
+		return err
+	}
+	if err := f.Save(prefix); err != nil {
+		return err
+	}
+	names, counts := f.Rejected()
+	fmt.Printf("total\t%d\npassed\t%d\n", f.Total(), f.Passed())
+	for i, name := range names {
+		fmt.Printf("%s\t%d\n", name, counts[i])
+	}
+	return nil
+}
+
+func filterSingle(f *filter.Filter, prefix string, filenames ...string) error {
+	out, err := xopen.Xcreate(prefix+".fastq", "w")
+	if err != nil {
+		return err
+	}
+	outter := bufio.NewWriter(out)
+
+	fqChan, errChan := fastq.Load(filenames...)
+	for fqChan != nil || errChan != nil {
+		select {
+		case fq, ok := <-fqChan:
+			if !ok {
+				fqChan = nil
+				continue
+			}
+			if f.Keep(fq) {
+				fmt.Fprintln(outter, fq)
+			}
+		case e := <-errChan:
+			if e != nil && err == nil {
+				err = e
+			}
+			errChan = nil
+		}
+	}
+	if e := outter.Flush(); err == nil {
+		err = e
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+func filterPair(f *filter.Filter, prefix string, filenames ...string) error {
+	out1, err := xopen.Xcreate(prefix+".r1.fastq", "w")
+	if err != nil {
+		return err
+	}
+	out2, err := xopen.Xcreate(prefix+".r2.fastq", "w")
+	if err != nil {
+		out1.Close()
+		return err
+	}
+	closers := []io.Closer{out1, out2}
+	outters := []*bufio.Writer{bufio.NewWriter(out1), bufio.NewWriter(out2)}
+
+	pChan, errChan := fastq.LoadPair(filenames...)
+	for pChan != nil || errChan != nil {
+		select {
+		case p, ok := <-pChan:
+			if !ok {
+				pChan = nil
+				continue
+			}
+			if f.KeepPair(p) {
+				fmt.Fprintln(outters[0], p.Read1)
+				fmt.Fprintln(outters[1], p.Read2)
+			}
+		case e := <-errChan:
+			if e != nil && err == nil {
+				err = e
+			}
+			errChan = nil
+		}
+	}
+	for i, c := range closers {
+		if e := outters[i].Flush(); err == nil {
+			err = e
+		}
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
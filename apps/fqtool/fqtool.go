@@ -18,8 +18,53 @@ func main() {
 		Runner: countRunner})
 	cmd.Add(&command.SubCommand{ // add sample command
 		Name:   sampleName,
-		Desc:   countDesc,
+		Desc:   sampleDesc,
 		Usage:  sampleArger.Usage,
 		Runner: sampleRunner})
+	cmd.Add(&command.SubCommand{ // add stat command
+		Name:   statName,
+		Desc:   statDesc,
+		Usage:  statUsage,
+		Runner: statRunner})
+	cmd.Add(&command.SubCommand{ // add locate command
+		Name:   locateName,
+		Desc:   locateDesc,
+		Usage:  locateArger.Usage,
+		Runner: locateRunner})
+	cmd.Add(&command.SubCommand{ // add filter command
+		Name:   filterName,
+		Desc:   filterDesc,
+		Usage:  filterArger.Usage,
+		Runner: filterRunner})
+	cmd.Add(&command.SubCommand{ // add demux command
+		Name:   demuxName,
+		Desc:   demuxDesc,
+		Usage:  demuxArger.Usage,
+		Runner: demuxRunner})
+	cmd.Add(&command.SubCommand{ // add umi-extract command
+		Name:   umiName,
+		Desc:   umiDesc,
+		Usage:  umiArger.Usage,
+		Runner: umiRunner})
+	cmd.Add(&command.SubCommand{ // add split command
+		Name:   splitName,
+		Desc:   splitDesc,
+		Usage:  splitArger.Usage,
+		Runner: splitRunner})
+	cmd.Add(&command.SubCommand{ // add merge command
+		Name:   mergeName,
+		Desc:   mergeDesc,
+		Usage:  mergeArger.Usage,
+		Runner: mergeRunner})
+	cmd.Add(&command.SubCommand{ // add sort command
+		Name:   sortName,
+		Desc:   sortDesc,
+		Usage:  sortArger.Usage,
+		Runner: sortRunner})
+	cmd.Add(&command.SubCommand{ // add dedup command
+		Name:   dedupName,
+		Desc:   dedupDesc,
+		Usage:  dedupArger.Usage,
+		Runner: dedupRunner})
 	cmd.Run(os.Args[1:]...)
 }
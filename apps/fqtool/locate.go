@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/align"
+	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/index"
+	"gongs/xopen"
+	"os"
+	"strings"
+)
+
+const locateName = "locate"
+const locateDesc = "locate reads on fasta reference by seed and extend"
+
+var locateArger = argparser.New(mainName, locateName)
+
+func init() {
+	locateArger.Add("ref", "-r", "--ref", "reference fasta files, separated by ','", "!!")
+	locateArger.Add("kmer", "-k", "--kmer", "kmer size of seed, in [1, 32]", 15)
+	locateArger.Add("error", "-e", "--error", "max error rate of alignment", 0.1)
+	locateArger.Add("seeds", "-m", "--min-seeds", "min seed number of a hit", 1)
+	locateArger.Add("best", "-b", "--best", "only report the best hit of each read", false)
+	locateArger.Add("output", "-o", "--output", "output file name", "**")
+}
+
+func locateRunner(args ...string) {
+	if len(args) == 0 {
+		locateArger.Usage()
+		os.Exit(1)
+	}
+
+	if err := locateArger.Parse(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ref := locateArger.Get("ref").(string)
+	kmer := locateArger.Get("kmer").(int)
+	errorRate := locateArger.Get("error").(float64)
+	seeds := locateArger.Get("seeds").(int)
+	best := locateArger.Get("best").(bool)
+	output := locateArger.Get("output").(string)
+
+	if ref == "!!" {
+		fmt.Fprintln(os.Stderr, mainName, locateName, ": no reference given!")
+		os.Exit(1)
+	}
+	if len(locateArger.Args) == 0 {
+		fmt.Fprintln(os.Stderr, mainName, locateName, ": no input given!")
+		os.Exit(1)
+	}
+
+	if output == "**" {
+		output = "-"
+	}
+	conf := align.DefaultConfig()
+	conf.ErrorRate = errorRate
+	if err := locateRun(strings.Split(ref, ","), kmer, seeds, best, conf, output, locateArger.Args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func locateRun(refs []string, k, seeds int, best bool, conf *align.Config, output string, filenames ...string) error {
+	idx, err := index.Build(k, refs...)
+	if err != nil {
+		return err
+	}
+
+	out, err := xopen.Xcreate(output, "w")
+	if err != nil {
+		return err
+	}
+	outter := bufio.NewWriter(out)
+
+	fmt.Fprintln(outter, "##read\tref\tstrand\tstart\tend\tqstart\tqend\tscore\terrors\tseeds")
+	fqChan, errChan := fastq.Load(filenames...)
+	for fqChan != nil || errChan != nil {
+		select {
+		case fq, ok := <-fqChan:
+			if !ok {
+				fqChan = nil
+				continue
+			}
+			if err != nil {
+				continue
+			}
+			var hits []index.Hit
+			if hits, err = idx.Locate(fq.Seq, conf, seeds); err != nil {
+				continue
+			}
+			if len(hits) == 0 {
+				fmt.Fprintf(outter, "%s\t*\t*\t*\t*\t*\t*\t*\t*\t*\n", fq.Id())
+				continue
+			}
+			if best {
+				hits = hits[:1]
+			}
+			for _, h := range hits {
+				fmt.Fprintf(outter, "%s\t%s\t%c\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", fq.Id(),
+					h.Ref, h.Strand, h.Start, h.End, h.Qstart, h.Qend, h.Score, h.Errors, h.Seeds)
+			}
+		case e := <-errChan:
+			if e != nil && err == nil {
+				err = e
+			}
+			errChan = nil
+		}
+	}
+	// write errors, eg. disk full, are only returned by Flush and Close
+	if e := outter.Flush(); err == nil {
+		err = e
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/merge"
+	"gongs/xopen"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const mergeName = "merge"
+const mergeDesc = "merge lanes of a sample into one file, check encoding and pairs, rewrite names"
+
+var mergeArger = argparser.New(mainName, mergeName)
+
+func init() {
+	mergeArger.Add("prefix", "-p", "--prefix", "output file prefix name, reads to prefix.fastq.gz or prefix.r1.fastq.gz prefix.r2.fastq.gz, counts to prefix.manifest", "merge")
+	mergeArger.Add("pair", "-P", "--pair", "inputs are pairs of read1 read2 of each lane", false)
+	mergeArger.Add("strip", "-c", "--strip-comment", "strip comment after read id", false)
+	mergeArger.Add("sample", "-s", "--sample", "add sample prefix to read id, as sample_id", "")
+	mergeArger.Add("renumber", "-r", "--renumber", "replace read id by the record number from 1", false)
+	mergeArger.Add("uncompress", "-u", "--uncompress", "output plain fastq, not gzip", false)
+}
+
+func mergeRunner(args ...string) {
+	if len(args) == 0 {
+		mergeArger.Usage()
+		os.Exit(1)
+	}
+	if err := mergeRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func mergeRun(args ...string) error {
+	if err := mergeArger.Parse(args...); err != nil {
+		return err
+	}
+
+	prefix := mergeArger.Get("prefix").(string)
+	pair := mergeArger.Get("pair").(bool)
+	filenames := mergeArger.Args
+	if len(filenames) == 0 {
+		return fmt.Errorf("%s %s : no input given!", mainName, mergeName)
+	}
+	mates := 1
+	if pair {
+		if len(filenames)%2 != 0 {
+			return fmt.Errorf("%s %s : %v", mainName, mergeName, fastq.ErrUnPairInputFile)
+		}
+		mates = 2
+	}
+	lanes := make([]*merge.Lane, 0, len(filenames)/mates)
+	for i := 0; i < len(filenames); i += mates {
+		lanes = append(lanes, merge.NewLane(filenames[i:i+mates]...))
+	}
+	renamer := &merge.Renamer{Strip: mergeArger.Get("strip").(bool), Sample: mergeArger.Get("sample").(string),
+		Renumber: mergeArger.Get("renumber").(bool)}
+
+	suffix := ".fastq.gz"
+	if mergeArger.Get("uncompress").(bool) {
+		suffix = ".fastq"
+	}
+	names := []string{prefix + suffix}
+	if pair {
+		names = []string{prefix + ".r1" + suffix, prefix + ".r2" + suffix}
+	}
+	// reads are written to temp files, renamed to names only if all lanes merged,
+	// encoding of a lane is known after it is written
+	temps := make([]string, len(names))
+	for i, name := range names {
+		temps[i] = strings.TrimSuffix(name, suffix) + ".tmp" + suffix
+	}
+	writers := make([]*bufio.Writer, 0, len(names))
+	closers := make([]io.Closer, 0, len(names))
+	closeAll := func() error {
+		var err error
+		for i, c := range closers {
+			if e := writers[i].Flush(); err == nil {
+				err = e
+			}
+			if e := c.Close(); err == nil {
+				err = e
+			}
+		}
+		return err
+	}
+	removeAll := func() {
+		for _, temp := range temps {
+			os.Remove(temp)
+		}
+	}
+	for _, temp := range temps {
+		out, err := xopen.Xcreate(temp, "w")
+		if err != nil {
+			closeAll()
+			removeAll()
+			return err
+		}
+		closers = append(closers, out)
+		writers = append(writers, bufio.NewWriter(out))
+	}
+
+	var err error
+	encoding := merge.UNKOWN
+	for _, lane := range lanes {
+		if err = mergeLane(lane, renamer, writers); err != nil {
+			break
+		}
+		// lanes of the same sample must be in the same encoding
+		if e := lane.Encoding(); e != merge.UNKOWN {
+			if encoding != merge.UNKOWN && encoding != e {
+				err = fmt.Errorf("%s %s : %s is %s, but the previous inputs are %s", mainName, mergeName,
+					strings.Join(lane.Filenames, ","), e, encoding)
+				break
+			}
+			encoding = e
+		}
+	}
+	if e := closeAll(); err == nil {
+		err = e
+	}
+	for i := 0; err == nil && i < len(names); i++ {
+		err = os.Rename(temps[i], names[i])
+	}
+	if err != nil {
+		removeAll()
+		return err
+	}
+	return mergeManifest(prefix, encoding, lanes)
+}
+
+// mergeLane write records of lane, mates are read in sync and checked by id
+func mergeLane(lane *merge.Lane, renamer *merge.Renamer, writers []*bufio.Writer) error {
+	fqfiles, err := fastq.Opens(lane.Filenames...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		for _, fqfile := range fqfiles {
+			fqfile.Close()
+		}
+	}()
+
+	fqs := make([]*fastq.Fastq, len(fqfiles))
+	for {
+		nexts := 0
+		for i, fqfile := range fqfiles {
+			if fqfile.Next() {
+				fqs[i] = fqfile.Fq()
+				nexts++
+			}
+		}
+		for _, fqfile := range fqfiles {
+			if err := fqfile.Err(); err != nil {
+				return err
+			}
+		}
+		if nexts == 0 {
+			return nil
+		} else if nexts != len(fqfiles) {
+			return fmt.Errorf("%s %s : %s have different reads number", mainName, mergeName, strings.Join(lane.Filenames, ","))
+		}
+		if len(fqs) > 1 && fqs[0].MateId() != fqs[1].MateId() {
+			return fmt.Errorf("%s %s : reads out of sync: %s %s", mainName, mergeName, fqs[0].Id(), fqs[1].Id())
+		}
+
+		lane.Count(fqs)
+		renamer.Rename(fqs)
+		for i, w := range writers {
+			fmt.Fprintln(w, fqs[i])
+		}
+	}
+}
+
+// mergeManifest save reads and quality encoding of each lane
+func mergeManifest(prefix, encoding string, lanes []*merge.Lane) error {
+	f, err := xopen.Xcreate(prefix+".manifest", "w")
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, lane := range lanes {
+		total += lane.Reads
+	}
+	fmt.Fprintln(f, "#: inputs:", len(lanes))
+	fmt.Fprintln(f, "#: reads:", total)
+	fmt.Fprintln(f, "#: encoding:", encoding)
+	fmt.Fprintln(f, "##", strings.Join([]string{"input", "reads", "encoding", "min_qual", "max_qual"}, "\t"))
+	for _, lane := range lanes {
+		min, max := "-", "-"
+		if lane.Reads > 0 {
+			min, max = strconv.Itoa(int(lane.MinQual)), strconv.Itoa(int(lane.MaxQual))
+		}
+		fmt.Fprintf(f, "%s\t%d\t%s\t%s\t%s\n", strings.Join(lane.Filenames, ","), lane.Reads, lane.Encoding(), min, max)
+	}
+	return f.Close()
+}
@@ -5,10 +5,11 @@ import (
 	"fmt"
 	"gongs/argparser"
 	"gongs/biofile/fastq"
+	"gongs/stat"
 	"gongs/xopen"
+	"io"
 	"math/rand"
 	"os"
-	"runtime"
 	"time"
 )
 
@@ -18,10 +19,12 @@ const sampleDesc = "sample a sub set from fastq file"
 var sampleArger = argparser.New(mainName, sampleName)
 
 func init() {
-	sampleArger.Add("rate", "-r", "--rate", "sample rate", 0.1)
+	sampleArger.Add("rate", "-r", "--rate", "sample rate, each read or pair is kept with the rate", 0.1)
+	sampleArger.Add("number", "-n", "--number", "sample exact number of reads or pairs, rate is ignored if > 0", 0)
+	sampleArger.Add("twopass", "-2", "--two-pass", "low memory exact number sampling, count reads then sample, can't read stdin", false)
 	sampleArger.Add("single", "-s", "--single", "input file is single file", false)
 	sampleArger.Add("prefix", "-p", "--prefix", "output file prefix name", "sample")
-	sampleArger.Add("seed", "-S", "--seed", "random seed", 0)
+	sampleArger.Add("seed", "-S", "--seed", "random seed, same seed same sample", 0)
 	sampleArger.Add("thread", "-t", "--threads", "threads number default use all", 0)
 }
 
@@ -37,91 +40,184 @@ func sampleRunner(args ...string) {
 	}
 
 	rate := sampleArger.Get("rate").(float64)
+	number := sampleArger.Get("number").(int)
 	single := sampleArger.Get("single").(bool)
 	prefix := sampleArger.Get("prefix").(string)
 	seed := int64(sampleArger.Get("seed").(int))
-	thread := sampleArger.Get("thread").(int)
+	setThread(sampleArger.Get("thread").(int))
 
-	if cpus := runtime.NumCPU(); thread < 1 || thread > cpus {
-		thread = runtime.NumCPU()
+	// records are sampled in input order by one rng, sample is the same of any threads
+	if seed == 0 {
+		seed = time.Now().UnixNano()
 	}
-	runtime.GOMAXPROCS(thread)
+	s := &sampler{rate: rate, number: number, twoPass: sampleArger.Get("twopass").(bool),
+		single: single, rng: rand.New(rand.NewSource(seed))}
 
-	if seed != 0 {
-		rand.Seed(seed)
-	} else {
-		rand.Seed(time.Now().UnixNano())
-	}
-
-	if err := sampleRun(single, rate, prefix, sampleArger.Args...); err != nil {
+	if err := s.run(prefix, sampleArger.Args...); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func sampleRun(single bool, rate float64, prefix string, filenames ...string) error {
-	if single {
-		return sampleSingleRun(rate, prefix, filenames...)
-	}
-	return samplePairRun(rate, prefix, filenames...)
+// sampler sample reads or pairs by rate, or exact number by reservoir or two pass
+type sampler struct {
+	rate    float64
+	number  int
+	twoPass bool
+	single  bool
+	rng     *rand.Rand
 }
 
-func sampleSingleRun(rate float64, prefix string, filenames ...string) error {
-	out, err := xopen.Xcreate(prefix+".fastq", "w")
-	if err != nil {
-		return err
+func (s *sampler) run(prefix string, filenames ...string) error {
+	if len(filenames) == 0 {
+		return fmt.Errorf("%s %s : no input given!", mainName, sampleName)
+	}
+	if !s.single && len(filenames)%2 != 0 {
+		return fmt.Errorf("%s %s : %v", mainName, sampleName, fastq.ErrUnPairInputFile)
+	}
+	if s.twoPass {
+		for _, filename := range filenames {
+			if filename == "-" {
+				return fmt.Errorf("%s %s : two pass can't read stdin", mainName, sampleName)
+			}
+		}
 	}
-	defer out.Close()
-	outter := bufio.NewWriter(out)
-	defer outter.Flush()
 
-	fqChan, errChan := fastq.Load(filenames...)
-	for {
-		select {
-		case fq, ok := <-fqChan:
-			if !ok {
-				return nil
+	names := []string{prefix + ".fastq"}
+	if !s.single {
+		names = []string{prefix + ".r1.fastq", prefix + ".r2.fastq"}
+	}
+	var closers []io.Closer
+	outters := make([]*bufio.Writer, len(names))
+	closeAll := func() error {
+		var err error
+		for i, c := range closers {
+			if e := outters[i].Flush(); err == nil {
+				err = e
 			}
-			if rate > rand.Float64() {
-				fmt.Fprintln(outter, fq)
+			if e := c.Close(); err == nil {
+				err = e
 			}
-		case err := <-errChan:
+		}
+		return err
+	}
+	for i, name := range names {
+		out, err := xopen.Xcreate(name, "w")
+		if err != nil {
+			closeAll()
 			return err
 		}
+		closers = append(closers, out)
+		outters[i] = bufio.NewWriter(out)
 	}
-	return nil
+	write := func(fqs []*fastq.Fastq) {
+		for i, outter := range outters {
+			fmt.Fprintln(outter, fqs[i])
+		}
+	}
+
+	var err error
+	switch {
+	case s.number > 0 && s.twoPass:
+		err = s.sampleTwoPass(write, filenames...)
+	case s.number > 0:
+		err = s.sampleReservoir(write, filenames...)
+	default:
+		err = s.each(func(fqs []*fastq.Fastq) {
+			if s.rate > s.rng.Float64() {
+				write(fqs)
+			}
+		}, filenames...)
+	}
+	if e := closeAll(); err == nil {
+		err = e
+	}
+	return err
 }
 
-func samplePairRun(rate float64, prefix string, filenames ...string) error {
-	out1, err := xopen.Xcreate(prefix+".r1.fastq", "w")
+// sampleReservoir keep number records in memory in one pass
+func (s *sampler) sampleReservoir(write func([]*fastq.Fastq), filenames ...string) error {
+	r := stat.NewReservoir[[]*fastq.Fastq](s.number, s.rng)
+	err := s.each(func(fqs []*fastq.Fastq) {
+		r.Add(fqs)
+	}, filenames...)
 	if err != nil {
 		return err
 	}
-	defer out1.Close()
-	out2, err := xopen.Xcreate(prefix+".r2.fastq", "w")
+	for _, fqs := range r.Items() {
+		write(fqs)
+	}
+	return nil
+}
+
+// sampleTwoPass count records, then write records of sampled indexes, keep number
+// indexes in memory
+func (s *sampler) sampleTwoPass(write func([]*fastq.Fastq), filenames ...string) error {
+	total := 0
+	err := s.each(func([]*fastq.Fastq) {
+		total++
+	}, filenames...)
 	if err != nil {
 		return err
 	}
-	defer out2.Close()
-	outter1 := bufio.NewWriter(out1)
-	outter2 := bufio.NewWriter(out2)
-	defer outter1.Flush()
-	defer outter2.Flush()
+	indexes := stat.SampleIndexes(total, s.number, s.rng)
+	i := 0
+	return s.each(func(fqs []*fastq.Fastq) {
+		if len(indexes) > 0 && indexes[0] == i {
+			indexes = indexes[1:]
+			write(fqs)
+		}
+		i++
+	}, filenames...)
+}
 
-	pchan, errchan := fastq.LoadPair(filenames...)
-	for {
+// each call fn with each read, or read1 and read2 of each pair, error if mates out of sync
+func (s *sampler) each(fn func([]*fastq.Fastq), filenames ...string) error {
+	var err error
+	if s.single {
+		fqChan, errChan := fastq.Load(filenames...)
+		for fqChan != nil || errChan != nil {
+			select {
+			case fq, ok := <-fqChan:
+				if !ok {
+					fqChan = nil
+					continue
+				}
+				if err == nil {
+					fn([]*fastq.Fastq{fq})
+				}
+			case e := <-errChan:
+				if e != nil && err == nil {
+					err = e
+				}
+				errChan = nil
+			}
+		}
+		return err
+	}
+
+	pChan, errChan := fastq.LoadPair(filenames...)
+	for pChan != nil || errChan != nil {
 		select {
-		case p, ok := <-pchan:
-			if !ok { // pchan closed, all ok
-				return nil
+		case p, ok := <-pChan:
+			if !ok {
+				pChan = nil
+				continue
 			}
-			if rate > rand.Float64() {
-				fmt.Fprintln(outter1, p.Read1)
-				fmt.Fprintln(outter2, p.Read1)
+			if err != nil {
+				continue
 			}
-		case err := <-errchan: // something wrong at input fastq files
-			return err
+			if p.Read1.MateId() != p.Read2.MateId() {
+				err = fmt.Errorf("%s %s : reads out of sync: %s %s", mainName, sampleName, p.Read1.Id(), p.Read2.Id())
+				continue
+			}
+			fn([]*fastq.Fastq{p.Read1, p.Read2})
+		case e := <-errChan:
+			if e != nil && err == nil {
+				err = e
+			}
+			errChan = nil
 		}
 	}
-	return nil
+	return err
 }
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/extsort"
+	"gongs/lib"
+	"gongs/xopen"
+	"io"
+	"os"
+)
+
+const sortName = "sort"
+const sortDesc = "sort reads by name or sequence with bounded memory"
+
+var sortArger = argparser.New(mainName, sortName)
+
+func init() {
+	sortArger.Add("prefix", "-p", "--prefix", "output file prefix name, reads to prefix.fastq.gz or prefix.r1.fastq.gz prefix.r2.fastq.gz", "sort")
+	sortArger.Add("pair", "-P", "--pair", "inputs are pairs of read1 read2, pairs are sorted by read1", false)
+	sortArger.Add("seq", "-s", "--by-seq", "sort by sequence, default by read id", false)
+	sortArger.Add("memory", "-m", "--memory", "memory of reads before saved to temp runs, eg. 512M, 2G", "512M")
+	sortArger.Add("tempdir", "-T", "--temp-dir", "dir of temp runs, default system temp dir", "")
+	sortArger.Add("uncompress", "-u", "--uncompress", "output plain fastq, not gzip", false)
+}
+
+func sortRunner(args ...string) {
+	if len(args) == 0 {
+		sortArger.Usage()
+		os.Exit(1)
+	}
+	if err := sortRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func sortRun(args ...string) error {
+	if err := sortArger.Parse(args...); err != nil {
+		return err
+	}
+
+	less := extsort.ByName
+	if sortArger.Get("seq").(bool) {
+		less = extsort.BySeq
+	}
+	s, err := newSorter(sortArger, sortName, less)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	outs, err := newSortOutputs(sortArger, s.Mates)
+	if err != nil {
+		return err
+	}
+	err = s.Sort(outs.write)
+	if e := outs.close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// newSorter return sorter of options of arger, reads of inputs are added
+func newSorter(arger *argparser.Parser, name string, less extsort.Less) (*extsort.Sorter, error) {
+	pair := arger.Get("pair").(bool)
+	filenames := arger.Args
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("%s %s : no input given!", mainName, name)
+	}
+	if pair && len(filenames)%2 != 0 {
+		return nil, fmt.Errorf("%s %s : %v", mainName, name, fastq.ErrUnPairInputFile)
+	}
+	memory, err := lib.ParseSize(arger.Get("memory").(string))
+	if err != nil {
+		return nil, err
+	}
+
+	mates := 1
+	if pair {
+		mates = 2
+	}
+	s := extsort.New(mates, less, memory, arger.Get("tempdir").(string))
+	if err := sortAdd(s, name, filenames...); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// sortAdd add reads or pairs of files to sorter, error if mates out of sync
+func sortAdd(s *extsort.Sorter, name string, filenames ...string) error {
+	var err error
+	if s.Mates == 1 {
+		fqChan, errChan := fastq.Load(filenames...)
+		for fqChan != nil || errChan != nil {
+			select {
+			case fq, ok := <-fqChan:
+				if !ok {
+					fqChan = nil
+					continue
+				}
+				if err == nil {
+					err = s.Add(fq)
+				}
+			case e := <-errChan:
+				if e != nil && err == nil {
+					err = e
+				}
+				errChan = nil
+			}
+		}
+		return err
+	}
+
+	pChan, errChan := fastq.LoadPair(filenames...)
+	for pChan != nil || errChan != nil {
+		select {
+		case p, ok := <-pChan:
+			if !ok {
+				pChan = nil
+				continue
+			}
+			if err != nil {
+				continue
+			}
+			if p.Read1.MateId() != p.Read2.MateId() {
+				err = fmt.Errorf("%s %s : reads out of sync: %s %s", mainName, name, p.Read1.Id(), p.Read2.Id())
+				continue
+			}
+			err = s.Add(p.Read1, p.Read2)
+		case e := <-errChan:
+			if e != nil && err == nil {
+				err = e
+			}
+			errChan = nil
+		}
+	}
+	return err
+}
+
+// sortOutputs writers of mates
+type sortOutputs struct {
+	writers []*bufio.Writer
+	closers []io.Closer
+}
+
+// newSortOutputs create prefix.fastq.gz, or prefix.r1.fastq.gz prefix.r2.fastq.gz of pairs
+func newSortOutputs(arger *argparser.Parser, mates int) (*sortOutputs, error) {
+	prefix := arger.Get("prefix").(string)
+	suffix := ".fastq.gz"
+	if arger.Get("uncompress").(bool) {
+		suffix = ".fastq"
+	}
+	names := []string{prefix + suffix}
+	if mates == 2 {
+		names = []string{prefix + ".r1" + suffix, prefix + ".r2" + suffix}
+	}
+	outs := &sortOutputs{}
+	for _, name := range names {
+		out, err := xopen.Xcreate(name, "w")
+		if err != nil {
+			outs.close()
+			return nil, err
+		}
+		outs.closers = append(outs.closers, out)
+		outs.writers = append(outs.writers, bufio.NewWriter(out))
+	}
+	return outs, nil
+}
+
+func (outs *sortOutputs) write(fqs []*fastq.Fastq) error {
+	for i, w := range outs.writers {
+		if _, err := fmt.Fprintln(w, fqs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close flush and close all writers, return the first error
+func (outs *sortOutputs) close() error {
+	var err error
+	for i, c := range outs.closers {
+		if e := outs.writers[i].Flush(); err == nil {
+			err = e
+		}
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
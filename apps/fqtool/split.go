@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"gongs/argparser"
+	"gongs/lib"
+	"gongs/split"
+	"os"
+	"strings"
+)
+
+const splitName = "split"
+const splitDesc = "split fastq files into parts by reads, bytes or number of parts"
+
+var splitArger = argparser.New(mainName, splitName)
+
+func init() {
+	splitArger.Add("prefix", "-p", "--prefix", "output file prefix name, parts to prefix.001.fastq.gz or prefix.001.r1.fastq.gz", "split")
+	splitArger.Add("pair", "-P", "--pair", "inputs are pairs of read1 read2", false)
+	splitArger.Add("reads", "-n", "--reads", "reads or pairs of each part", 0)
+	splitArger.Add("bytes", "-b", "--bytes", "uncompressed size of each part, eg. 500M, 2G", "")
+	splitArger.Add("parts", "-N", "--parts", "number of parts, reads are counted first, can't read stdin", 0)
+	splitArger.Add("roundrobin", "-R", "--round-robin", "deal reads to parts one by one with -N, no counting", false)
+	splitArger.Add("uncompress", "-u", "--uncompress", "output plain fastq, not gzip", false)
+}
+
+func splitRunner(args ...string) {
+	if len(args) == 0 {
+		splitArger.Usage()
+		os.Exit(1)
+	}
+	if err := splitRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// splitChunker return chunker of one of reads, bytes or parts option
+func splitChunker(pair bool, filenames ...string) (*split.Chunker, error) {
+	reads := splitArger.Get("reads").(int)
+	bytes := splitArger.Get("bytes").(string)
+	parts := splitArger.Get("parts").(int)
+	given := 0
+	for _, ok := range []bool{reads != 0, bytes != "", parts != 0} {
+		if ok {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, fmt.Errorf("%s %s : need one of -n, -b or -N!", mainName, splitName)
+	}
+	if splitArger.Get("roundrobin").(bool) && parts == 0 {
+		return nil, fmt.Errorf("%s %s : -R need -N!", mainName, splitName)
+	}
+
+	switch {
+	case reads != 0:
+		return split.ByReads(reads)
+	case bytes != "":
+		size, err := lib.ParseSize(bytes)
+		if err != nil {
+			return nil, err
+		}
+		return split.ByBytes(size)
+	case splitArger.Get("roundrobin").(bool):
+		return split.RoundRobin(parts)
+	}
+	total, err := split.Count(pair, filenames...)
+	if err != nil {
+		return nil, err
+	}
+	return split.ByParts(total, parts)
+}
+
+func splitRun(args ...string) error {
+	if err := splitArger.Parse(args...); err != nil {
+		return err
+	}
+
+	prefix := splitArger.Get("prefix").(string)
+	pair := splitArger.Get("pair").(bool)
+	filenames := splitArger.Args
+	if len(filenames) == 0 {
+		return fmt.Errorf("%s %s : no input given!", mainName, splitName)
+	}
+	c, err := splitChunker(pair, filenames...)
+	if err != nil {
+		return err
+	}
+
+	mates := 1
+	if pair {
+		mates = 2
+	}
+	w := split.NewWriter(prefix, mates, !splitArger.Get("uncompress").(bool))
+	err = split.Split(c, w, filenames...)
+	if e := w.Close(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+
+	indexes, reads, bytes := w.Parts()
+	fmt.Println(strings.Join([]string{"part", "reads", "bytes", "files"}, "\t"))
+	for n, i := range indexes {
+		names := make([]string, mates)
+		for mate := range names {
+			names[mate] = w.Name(i, mate)
+		}
+		fmt.Printf("%d\t%d\t%d\t%s\n", i+1, reads[n], bytes[n], strings.Join(names, ","))
+	}
+	return nil
+}
@@ -1,20 +1,35 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
 	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/qc"
+	"gongs/xopen"
 	"os"
+	"path/filepath"
+	"runtime"
 )
 
 const statName = "stat"
 const statDesc = "stat fastq file"
 const statVersion = "2015.08.19.1"
+const statBatch = 1024 // reads number sent to a worker once
 
 var statArger = argparser.New(mainName, statName)
 
 func init() {
 	statArger.Add("prefix", "-p", "--prefix", "output file prefix name", "stat")
 	statArger.Add("thread", "-t", "--thread", "runtime thread Number default (all available cpu)", 0)
+	statArger.Add("merge", "-m", "--merge", "inputs are json files saved by stat, merge them", false)
+	statArger.Add("pair", "-P", "--pair", "inputs are read1 read2 pairs, count pair duplication", false)
+	statArger.Add("hll", "-H", "--hll", "estimate percent unique of all reads by HyperLogLog", false)
+	statArger.Add("tiledev", "-d", "--tile-dev", "a tile cycle is bad if mean quality lower than its lane by more than this", qc.DefaultTileThreshold().Deviation)
+	statArger.Add("tilecycles", "-y", "--tile-cycles", "flag tiles with bad cycles more than this fraction of cycles", qc.DefaultTileThreshold().Cycles)
+	statArger.Add("tilefilter", "-T", "--tile-filter", "write reads not from flagged tiles of each input to prefix.tilefilter.input", false)
+	statArger.Add("compare", "-C", "--compare", "json file saved by stat as reference, test differences to prefix.compare", "")
+	statArger.Add("contaminants", "-c", "--contaminants", "contaminant list file of name<tab>sequence lines, replace the built-in list", "")
 }
 
 func statUsage() {
@@ -37,9 +52,233 @@ func statRun(args ...string) error {
 		return err
 	}
 
-	// prefix := statArger.Get("prefix").(string)
+	prefix := statArger.Get("prefix").(string)
+	merge := statArger.Get("merge").(bool)
+	pair := statArger.Get("pair").(bool)
+	hll := statArger.Get("hll").(bool)
+	contaminants := statArger.Get("contaminants").(string)
+	compare := statArger.Get("compare").(string)
+	tileTh := &qc.TileThreshold{Deviation: statArger.Get("tiledev").(float64), Cycles: statArger.Get("tilecycles").(float64)}
+	tileFilter := statArger.Get("tilefilter").(bool)
+	filenames := statArger.Args
+	if len(filenames) == 0 {
+		return fmt.Errorf("%s %s : no input given!", mainName, statName)
+	}
+	if pair && len(filenames)%2 != 0 {
+		return fmt.Errorf("%s %s : %v", mainName, statName, fastq.ErrUnPairInputFile)
+	}
 
 	// setting multi-threads
 	setThread(statArger.Get("thread").(int))
+
+	var report *qc.Report
+	var err error
+	if merge {
+		report, err = statMerge(filenames...)
+	} else {
+		report, err = statCount(runtime.GOMAXPROCS(0), pair, hll, filenames...)
+	}
+	if err != nil {
+		return err
+	}
+	if contaminants != "" {
+		conts, err := qc.LoadContaminants(contaminants)
+		if err != nil {
+			return err
+		}
+		if report.Annotator, err = qc.NewAnnotator(conts); err != nil {
+			return err
+		}
+	}
+
+	report.TileTh = tileTh
+
+	if err := report.Save(prefix); err != nil {
+		return err
+	}
+	if tileFilter && !merge {
+		if err := statTileFilter(report, prefix, filenames...); err != nil {
+			return err
+		}
+	}
+	for _, m := range report.Summary() {
+		fmt.Println(m)
+	}
+	if compare != "" {
+		ref, err := qc.LoadJSON(compare)
+		if err != nil {
+			return fmt.Errorf("%s: %v", compare, err)
+		}
+		if err := report.SaveCompare(prefix, ref); err != nil {
+			return err
+		}
+		for _, c := range report.Compare(ref) {
+			if c.Warn {
+				fmt.Println(c)
+			}
+		}
+	}
+	return nil
+}
+
+// statMerge merge reports saved as json
+func statMerge(filenames ...string) (*qc.Report, error) {
+	report := qc.NewReport()
+	for _, filename := range filenames {
+		r, err := qc.LoadJSON(filename)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		report.Merge(r)
+	}
+	return report, nil
+}
+
+// statLoad load reads of files, mates of a pair are sent one after another if pair
+func statLoad(pair bool, filenames ...string) (<-chan *fastq.Fastq, <-chan error) {
+	if !pair {
+		return fastq.Load(filenames...)
+	}
+	pChan, errChan := fastq.LoadPair(filenames...)
+	if pChan == nil {
+		return nil, errChan
+	}
+	fqChan := make(chan *fastq.Fastq, 2)
+	go func(pChan <-chan *fastq.Pair, fqChan chan<- *fastq.Fastq) {
+		for p := range pChan {
+			fqChan <- p.Read1
+			fqChan <- p.Read2
+		}
+		close(fqChan)
+	}(pChan, fqChan)
+	return fqChan, errChan
+}
+
+// newStatReport return an empty report of the duplication mode
+func newStatReport(hll bool) *qc.Report {
+	r := qc.NewReport()
+	if hll {
+		r.EnableHLL()
+	}
+	return r
+}
+
+// statCount count reads by workers, each worker has its own report, merged at the end,
+// reads are counted in pairs if pair
+func statCount(workers int, pair, hll bool, filenames ...string) (*qc.Report, error) {
+	// file size and md5 stat along with reads counting
+	infoChan := make(chan []*qc.FileInfo, 1)
+	infoErr := make(chan error, 1)
+	go func(filenames []string) {
+		infos := make([]*qc.FileInfo, 0, len(filenames))
+		for _, filename := range filenames {
+			info, err := qc.Stat(filename)
+			if err != nil {
+				infoErr <- err
+				return
+			}
+			infos = append(infos, info)
+		}
+		infoChan <- infos
+	}(filenames)
+
+	batchChan := make(chan []*fastq.Fastq, 2*workers)
+	reportChan := make(chan *qc.Report, workers)
+	for i := 0; i < workers; i++ {
+		go func(batchChan <-chan []*fastq.Fastq, reportChan chan<- *qc.Report) {
+			r := newStatReport(hll)
+			for batch := range batchChan {
+				if pair { // batch size is even, pairs are not splitted
+					for i := 0; i+1 < len(batch); i += 2 {
+						r.CountPair(batch[i], batch[i+1])
+					}
+					continue
+				}
+				for _, fq := range batch {
+					r.Count(fq)
+				}
+			}
+			reportChan <- r
+		}(batchChan, reportChan)
+	}
+
+	// send reads to workers in batches
+	var err error
+	batch := make([]*fastq.Fastq, 0, statBatch)
+	fqChan, errChan := statLoad(pair, filenames...)
+	for fqChan != nil || errChan != nil {
+		select {
+		case fq, ok := <-fqChan:
+			if !ok {
+				fqChan = nil
+				continue
+			}
+			if batch = append(batch, fq); len(batch) == statBatch {
+				batchChan <- batch
+				batch = make([]*fastq.Fastq, 0, statBatch)
+			}
+		case e := <-errChan:
+			if e != nil && err == nil {
+				err = e
+			}
+			errChan = nil
+		}
+	}
+	batchChan <- batch
+	close(batchChan)
+
+	report := newStatReport(hll)
+	for i := 0; i < workers; i++ {
+		report.Merge(<-reportChan)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case report.Files = <-infoChan:
+	case err := <-infoErr:
+		return nil, err
+	}
+	return report, nil
+}
+
+// statTileFilter write reads not from flagged tiles of each input to prefix.tilefilter.input,
+// mates of a pair are in the same tile, so pairs are kept in sync
+func statTileFilter(report *qc.Report, prefix string, filenames ...string) error {
+	filter := qc.NewTileFilter(report.Tile.TileDevs(report.TileTh))
+	dropped := 0
+	for _, filename := range filenames {
+		if filename == "-" {
+			return fmt.Errorf("%s %s : tile filter can't read stdin again", mainName, statName)
+		}
+		fqfile, err := fastq.Open(filename)
+		if err != nil {
+			return err
+		}
+		out, err := xopen.Xcreate(prefix+".tilefilter."+filepath.Base(filename), "w")
+		if err != nil {
+			fqfile.Close()
+			return err
+		}
+		outter := bufio.NewWriter(out)
+		for fqfile.Next() {
+			if fq := fqfile.Fq(); filter.Keep(fq) {
+				fmt.Fprintln(outter, fq)
+			} else {
+				dropped++
+			}
+		}
+		err = fqfile.Err()
+		fqfile.Close()
+		if e := outter.Flush(); err == nil {
+			err = e
+		}
+		out.Close()
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "tile filter: %d tiles flagged, %d reads dropped\n", filter.Len(), dropped)
 	return nil
 }
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/argparser"
+	"gongs/biofile/fastq"
+	"gongs/umi"
+	"gongs/xopen"
+	"io"
+	"os"
+	"strings"
+)
+
+const umiName = "umi-extract"
+const umiDesc = "move UMI from read sequence or index read to read name as umi_tools"
+
+var umiArger = argparser.New(mainName, umiName)
+
+func init() {
+	umiArger.Add("prefix", "-p", "--prefix", "output file prefix name, reads to prefix.fastq or prefix.r1.fastq prefix.r2.fastq", "umi")
+	umiArger.Add("pair", "-P", "--pair", "inputs are read1 read2", false)
+	umiArger.Add("pattern", "-b", "--pattern", "UMI pattern of read1, eg. NNNNNNNNXXXX, N{6,10}(GTAC){s<=1}", "")
+	umiArger.Add("pattern2", "-B", "--pattern2", "UMI pattern of read2, UMI of read2 is appended to UMI of read1", "")
+	umiArger.Add("umiread", "-u", "--umi-read", "index read file of UMI, eg. I1, reads are not changed", "")
+	umiArger.Add("umipattern", "-U", "--umi-pattern", "UMI pattern of umi read, default the whole umi read", "")
+	umiArger.Add("top", "-n", "--top", "report top n UMIs", 20)
+}
+
+func umiRunner(args ...string) {
+	if len(args) == 0 {
+		umiArger.Usage()
+		os.Exit(1)
+	}
+	if err := umiRun(args...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// umiExtractor extract UMI of reads and the UMI read, nil pattern is not used
+type umiExtractor struct {
+	patterns   []*umi.Pattern // of read1, read2
+	umiPattern *umi.Pattern
+	umiRead    bool
+}
+
+// extract tag UMI to reads and remove UMI bases from reads, umiFq is nil if no UMI read,
+// return false if any pattern not match
+func (e *umiExtractor) extract(fqs []*fastq.Fastq, umiFq *fastq.Fastq) (string, bool) {
+	umis := []string{}
+	for i, p := range e.patterns {
+		if p == nil {
+			continue
+		}
+		u, kept, err := p.Extract(fqs[i])
+		if err != nil {
+			return "", false
+		}
+		fqs[i] = kept
+		umis = append(umis, u)
+	}
+	if umiFq != nil {
+		if e.umiPattern == nil {
+			umis = append(umis, strings.ToUpper(string(umiFq.Seq)))
+		} else if u, _, err := e.umiPattern.Extract(umiFq); err == nil {
+			umis = append(umis, u)
+		} else {
+			return "", false
+		}
+	}
+	u := strings.Join(umis, "")
+	for _, fq := range fqs {
+		umi.Tag(fq, u)
+	}
+	return u, true
+}
+
+func newUmiExtractor(pair bool) (*umiExtractor, error) {
+	e := &umiExtractor{umiRead: umiArger.Get("umiread").(string) != ""}
+	opts := []string{"pattern", "pattern2"}
+	if !pair {
+		opts = opts[:1]
+	}
+	for _, opt := range opts {
+		var p *umi.Pattern
+		if text := umiArger.Get(opt).(string); text != "" {
+			var err error
+			if p, err = umi.NewPattern(text); err != nil {
+				return nil, err
+			}
+		}
+		e.patterns = append(e.patterns, p)
+	}
+	if text := umiArger.Get("umipattern").(string); text != "" {
+		p, err := umi.NewPattern(text)
+		if err != nil {
+			return nil, err
+		}
+		e.umiPattern = p
+	}
+	used := e.umiRead
+	for _, p := range e.patterns {
+		used = used || p != nil
+	}
+	if !used {
+		return nil, fmt.Errorf("%s %s : no pattern or umi read given!", mainName, umiName)
+	}
+	return e, nil
+}
+
+func umiRun(args ...string) error {
+	if err := umiArger.Parse(args...); err != nil {
+		return err
+	}
+
+	prefix := umiArger.Get("prefix").(string)
+	pair := umiArger.Get("pair").(bool)
+	filenames := umiArger.Args
+	if pair && len(filenames) != 2 || !pair && len(filenames) != 1 {
+		return fmt.Errorf("%s %s : need read1, or read1 read2 with -P input!", mainName, umiName)
+	}
+	extractor, err := newUmiExtractor(pair)
+	if err != nil {
+		return err
+	}
+	input, err := openUmiInput(filenames, umiArger.Get("umiread").(string))
+	if err != nil {
+		return err
+	}
+	defer input.close()
+
+	names := []string{prefix + ".fastq"}
+	if pair {
+		names = []string{prefix + ".r1.fastq", prefix + ".r2.fastq"}
+	}
+	writers := make([]*bufio.Writer, len(names))
+	closers := make([]io.Closer, 0, len(names))
+	closeAll := func() error {
+		var err error
+		for i, c := range closers {
+			if e := writers[i].Flush(); err == nil {
+				err = e
+			}
+			if e := c.Close(); err == nil {
+				err = e
+			}
+		}
+		return err
+	}
+	for i, name := range names {
+		out, err := xopen.Xcreate(name, "w")
+		if err != nil {
+			closeAll()
+			return err
+		}
+		closers = append(closers, out)
+		writers[i] = bufio.NewWriter(out)
+	}
+
+	stats := umi.NewStats()
+	err = umiExtract(extractor, input, writers, stats)
+	if e := closeAll(); err == nil {
+		err = e
+	}
+	if err != nil {
+		return err
+	}
+	return stats.Save(prefix, umiArger.Get("top").(int))
+}
+
+// umiExtract extract UMI of each record, reads not match patterns are dropped
+func umiExtract(extractor *umiExtractor, input *umiInput, writers []*bufio.Writer, stats *umi.Stats) error {
+	for input.next() {
+		for _, fq := range input.all[1:] {
+			if fq.MateId() != input.all[0].MateId() {
+				return fmt.Errorf("%s %s : reads out of sync: %s %s", mainName, umiName, input.all[0].Id(), fq.Id())
+			}
+		}
+		fqs, umiFq := input.reads()
+		u, ok := extractor.extract(fqs, umiFq)
+		if !ok {
+			stats.Unmatch()
+			continue
+		}
+		stats.Add(u)
+		for i, w := range writers {
+			fmt.Fprintln(w, fqs[i])
+		}
+	}
+	return input.err()
+}
+
+// umiInput read1, read2 and umi read in sync, read1 and read2 or read1 and umi read
+// by FastqPairFile, umi read of pairs by another FastqFile
+type umiInput struct {
+	ff    *fastq.FastqFile     // read1 only
+	pf    *fastq.FastqPairFile // read1 and read2 or umi read
+	uf    *fastq.FastqFile     // umi read of pairs
+	umi   bool
+	all   []*fastq.Fastq // reads of current record, umi read last
+	short bool           // a file ends before others
+}
+
+func openUmiInput(filenames []string, umiRead string) (*umiInput, error) {
+	in := &umiInput{umi: umiRead != ""}
+	var err error
+	switch {
+	case len(filenames) == 2:
+		if in.pf, err = fastq.OpenPair(filenames[0], filenames[1]); err == nil && in.umi {
+			if in.uf, err = fastq.Open(umiRead); err != nil {
+				in.pf.Close()
+			}
+		}
+	case in.umi:
+		in.pf, err = fastq.OpenPair(filenames[0], umiRead)
+	default:
+		in.ff, err = fastq.Open(filenames[0])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return in, nil
+}
+
+// next read the next record of all files, false if any file ends
+func (in *umiInput) next() bool {
+	if in.ff != nil {
+		if !in.ff.Next() {
+			return false
+		}
+		in.all = []*fastq.Fastq{in.ff.Fq()}
+		return true
+	}
+	if !in.pf.Next() {
+		return false
+	}
+	p := in.pf.Pair()
+	in.all = []*fastq.Fastq{p.Read1, p.Read2}
+	if in.uf != nil {
+		if !in.uf.Next() {
+			in.short = true
+			return false
+		}
+		in.all = append(in.all, in.uf.Fq())
+	}
+	return true
+}
+
+// reads return reads to write and the umi read, nil if no umi read
+func (in *umiInput) reads() ([]*fastq.Fastq, *fastq.Fastq) {
+	if !in.umi {
+		return in.all, nil
+	}
+	n := len(in.all) - 1
+	return in.all[:n], in.all[n]
+}
+
+func (in *umiInput) err() error {
+	var err error
+	if in.ff != nil {
+		err = in.ff.Err()
+	} else if err = in.pf.Err(); err == nil && in.uf != nil {
+		err = in.uf.Err()
+	}
+	if err == nil && in.short {
+		err = fmt.Errorf("%s %s : input files have different reads number", mainName, umiName)
+	}
+	return err
+}
+
+func (in *umiInput) close() {
+	if in.ff != nil {
+		in.ff.Close()
+		return
+	}
+	in.pf.Close()
+	if in.uf != nil {
+		in.uf.Close()
+	}
+}
@@ -37,10 +37,10 @@ func (fa Fasta) String() string {
 	if len(fa.Seq) > MaxLineWidth {
 		buf := []string{}
 		for start, end := 0, len(fa.Seq); start < end; start += LineWidth {
-			if start+LineWidth < l {
-				buf = append(buf, fa.Seq[start:start+LineWidth])
+			if start+LineWidth < end {
+				buf = append(buf, string(fa.Seq[start:start+LineWidth]))
 			} else {
-				buf = append(buf, fa.Seq[start:end])
+				buf = append(buf, string(fa.Seq[start:end]))
 			}
 		}
 		return fmt.Sprintf(">%s\n%s", fa.Name, strings.Join(buf, "\n"))
@@ -93,6 +93,12 @@ func (ff *FastaFile) Close() error {
 	return ff.file.Close()
 }
 
+func (ff *FastaFile) setErr(err error) {
+	if ff.err == nil {
+		ff.err = err
+	}
+}
+
 func (ff *FastaFile) Next() bool {
 	if ff.err != nil {
 		return false
@@ -126,8 +132,11 @@ func (ff *FastaFile) Next() bool {
 	return true
 }
 
+// Fa return a copy of current record, safe to keep after calling Next
 func (ff *FastaFile) Fa() *Fasta {
-	return &Fasta{Name: ff.name, Seq: ff.seq}
+	seq := make([]byte, len(ff.seq))
+	copy(seq, ff.seq)
+	return &Fasta{Name: ff.name, Seq: seq}
 }
 
 func (ff *FastaFile) Value() (string, []byte, []byte) {
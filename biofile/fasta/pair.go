@@ -2,7 +2,6 @@ package fasta
 
 import (
 	"errors"
-	"gongs/xopen"
 	"sync"
 )
 
@@ -52,41 +51,32 @@ func (pf *FastaPairFile) Next() bool {
 }
 
 func (pf *FastaPairFile) Value() *Pair {
-	return &Pair{Read1: pf.ff1.Value(), Read2: pf.ff2.Value()}
+	return &Pair{Read1: pf.ff1.Fa(), Read2: pf.ff2.Fa()}
 }
 
-func (pairfile *FastaPairFile) Read() chan *Pair {
-	chan1 := pairfile.file1.Read()
-	chan2 := pairfile.file2.Read()
-
+func (pf *FastaPairFile) Iter() <-chan *Pair {
 	out := make(chan *Pair)
-	go func(chan1, chan2 <-chan *Fasta, out chan *Pair) {
-		for {
-			read1, ok1 := <-chan1
-			read2, ok2 := <-chan2
-			if ok1 && ok2 {
-				out <- &Pair{Read1: read1, Read2: read2}
-			} else {
-				break
-			}
+	go func(pf *FastaPairFile, out chan *Pair) {
+		for pf.Next() {
+			out <- pf.Value()
 		}
 		close(out)
-	}(chan1, chan2, out)
+	}(pf, out)
 	return out
 }
 
 func OpenPair(filename1, filename2 string) (*FastaPairFile, error) {
-	file1, err := xopen.Xopen(filename1)
+	ff1, err := Open(filename1)
 	if err != nil {
 		return nil, err
 	}
-	file2, err := xopen.Xopen(filename2)
+	ff2, err := Open(filename2)
 	if err != nil {
 		return nil, err
 	}
 	return &FastaPairFile{
-		ff1: file1,
-		ff2: file2,
+		ff1: ff1,
+		ff2: ff2,
 	}, nil
 }
 
@@ -120,7 +110,7 @@ func Iter(filenames ...string) (<-chan *Pair, error) {
 		wg := &sync.WaitGroup{}
 		wg.Add(len(pfs))
 		for _, pf := range pfs {
-			go func(ch chan *Pair, pf *FastqPairFile, wg *sync.WaitGroup) {
+			go func(ch chan *Pair, pf *FastaPairFile, wg *sync.WaitGroup) {
 				defer pf.Close()
 				for pf.Next() {
 					ch <- pf.Value()
@@ -59,6 +59,15 @@ func (fq Fastq) Id() string {
 	return fq.Name
 }
 
+// MateId return read id without /1 /2 suffix of old Illumina names, same for mates
+func (fq Fastq) MateId() string {
+	id := fq.Id()
+	if n := len(id); n > 2 && id[n-2] == '/' {
+		return id[:n-2]
+	}
+	return id
+}
+
 type FastqFile struct {
 	Name  string
 	file  io.ReadCloser
@@ -154,8 +163,13 @@ func (ff *FastqFile) Next() bool {
 	return false
 }
 
+// Fq return a copy of current record, safe to keep after calling Next
 func (ff *FastqFile) Fq() *Fastq {
-	return &Fastq{Name: ff.name, Seq: ff.seq, Qual: ff.qual}
+	seq := make([]byte, len(ff.seq))
+	copy(seq, ff.seq)
+	qual := make([]byte, len(ff.qual))
+	copy(qual, ff.qual)
+	return &Fastq{Name: ff.name, Seq: seq, Qual: qual}
 }
 
 func (ff *FastqFile) Value() (string, []byte, []byte) {
@@ -46,6 +46,21 @@ func Test_Fastq_String(t *testing.T) {
 	}
 }
 
+func TestMateId(t *testing.T) {
+	for name, expect := range map[string]string{
+		"M1:1:FC1:1:1101:1:1 1:N:0:ACGT": "M1:1:FC1:1:1101:1:1",
+		"HWI-1:1:1:1:1#0/1":              "HWI-1:1:1:1:1",
+		"read/2":                         "read",
+		"read/2 comment":                 "read",
+		"read":                           "read",
+		"/1":                             "/1",
+	} {
+		if id := (Fastq{Name: name}).MateId(); id != expect {
+			t.Errorf("MateId(%s) expect: %v get: %v", name, expect, id)
+		}
+	}
+}
+
 func Test_FastqFile_stdin(t *testing.T) {
 	fqfile, err := Open("-")
 	if err != nil {
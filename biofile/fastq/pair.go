@@ -117,7 +117,7 @@ func OpenPairs(filenames ...string) ([]*FastqPairFile, error) {
 	} else if n%2 != 0 {
 		return nil, ErrUnPairInputFile
 	}
-	pfs := make([]*FastqPairFile, n%2)
+	pfs := make([]*FastqPairFile, n/2)
 	for i := 0; i < n; i += 2 {
 		pf, err := OpenPair(filenames[i], filenames[i+1])
 		if err != nil {
@@ -0,0 +1,126 @@
+package demux
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadSampleSheet(t *testing.T) {
+	dir := t.TempDir()
+	sheets := map[string]string{
+		"v1.csv":    "[Header]\nIEMFileVersion,4\n\n[Reads]\n151\n\n[Data]\nSample_ID,Sample_Name,I7_Index_ID,index,I5_Index_ID,index2\nS1,s1,D701,acgtacgt,D501,TTTTGGGG\nS2,s2,D702,TGCATGCA,D502,AAAACCCC\n",
+		"v2.csv":    "[Header]\nFileFormatVersion,2\n\n[BCLConvert_Settings]\nBarcodeMismatchesIndex1,1\n\n[BCLConvert_Data]\nLane,Sample_ID,Index,Index2\n1,S1,ACGTACGT,TTTTGGGG\n1,S2,TGCATGCA,AAAACCCC\n",
+		"plain.csv": "# samples\nS1,ACGTACGT,TTTTGGGG\nS2,TGCATGCA,AAAACCCC\n",
+	}
+	for name, content := range sheets {
+		filename := filepath.Join(dir, name)
+		if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		samples, err := LoadSampleSheet(filename)
+		if err != nil {
+			t.Errorf("%s LoadSampleSheet expect: %v get: %v", name, nil, err)
+			continue
+		}
+		if len(samples) != 2 || *samples[0] != (Sample{"S1", "ACGTACGT", "TTTTGGGG"}) || samples[1].Index2 != "AAAACCCC" {
+			t.Errorf("%s LoadSampleSheet expect: %v get: %v %v", name, "S1 S2", samples[0], samples[len(samples)-1])
+		}
+	}
+
+	// samples of multiple lanes are loaded by rows, duplicate rows removed by matcher
+	filename := filepath.Join(dir, "lanes.csv")
+	content := "[Header]\nFileFormatVersion,2\n\n[BCLConvert_Data]\nLane,Sample_ID,Index,Index2\n" +
+		"1,S1,ACGTACGT,TTTTGGGG\n1,S2,TGCATGCA,AAAACCCC\n2,S1,ACGTACGT,TTTTGGGG\n2,S2,TGCATGCA,AAAACCCC\n"
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	samples, err := LoadSampleSheet(filename)
+	if err != nil || len(samples) != 4 {
+		t.Fatalf("lanes.csv LoadSampleSheet expect: %v get: %v %v", 4, len(samples), err)
+	}
+	for _, mismatches := range []int{0, 1} {
+		m, err := NewMatcher(samples, mismatches)
+		if err != nil {
+			t.Errorf("lanes.csv NewMatcher(%d) expect: %v get: %v", mismatches, nil, err)
+			continue
+		}
+		if len(m.Samples) != 2 || m.Match("TGCATGCA", "AAAACCCC") != 1 {
+			t.Errorf("lanes.csv NewMatcher(%d) expect: %v get: %v", mismatches, "S1 S2", m.Samples)
+		}
+	}
+
+	// ids used in output file names, reserved or out of prefix ones rejected
+	for _, id := range []string{"Undetermined", "undetermined", "../S1", "run/S1", `run\S1`, "..", ""} {
+		filename := filepath.Join(dir, "bad.csv")
+		if err := os.WriteFile(filename, []byte(id+",ACGTACGT,TTTTGGGG\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadSampleSheet(filename); err == nil {
+			t.Errorf("LoadSampleSheet id %q expect: %v get: %v", id, ErrSampleID, err)
+		}
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	samples := []*Sample{{"S1", "ACGTACGT", "TTTTGGGG"}, {"S2", "TGCATGCA", "AAAACCCC"}, {"S3", "ACGTACGT", "GGGGAAAA"}}
+	m, err := NewMatcher(samples, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		i7, i5 string
+		expect int
+	}{
+		{"ACGTACGT", "TTTTGGGG", 0}, {"ACGTACGA", "TTTTGGGG", 0}, {"ACGTACGTAT", "TTTTGGGN", 0},
+		{"ACGTACGT", "GGGGAAAA", 2}, {"tgcatgca", "aaaacccc", 1},
+		{"ACGTACAA", "TTTTGGGG", UNDETERMINED}, {"ACGTACGT", "AAAACCCC", UNDETERMINED},
+	} {
+		if s := m.Assign(c.i7, c.i5); s != c.expect {
+			t.Errorf("Match %s+%s expect: %v get: %v", c.i7, c.i5, c.expect, s)
+		}
+	}
+	if barcodes, counts := m.Undetermined(1); len(barcodes) != 1 || counts[0] != 1 {
+		t.Errorf("Undetermined expect: %v get: %v %v", 1, barcodes, counts)
+	}
+	if m.perfect[0] != 1 || m.counts[0] != 3 {
+		t.Errorf("S1 perfect reads expect: %v %v get: %v %v", 1, 3, m.perfect[0], m.counts[0])
+	}
+
+	collide := []*Sample{{"S1", "ACGTACGT", ""}, {"S2", "ACGTACCA", ""}}
+	if _, err := NewMatcher(collide, 1); err == nil {
+		t.Errorf("NewMatcher collision expect: %v get: %v", "error", err)
+	}
+	if _, err := NewMatcher(collide, 0); err != nil {
+		t.Errorf("NewMatcher expect: %v get: %v", nil, err)
+	}
+	// indexes of the same id may collide, the perfect matched is returned
+	multi := []*Sample{{"S1", "ACGTACGT", ""}, {"S1", "ACGTACCA", ""}, {"S2", "TGCATGCA", ""}}
+	m, err = NewMatcher(multi, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i7, expect := range map[string]int{"ACGTACGT": 0, "ACGTACCA": 1, "ACGTACCT": 0, "ACGTACGA": 0, "TGCATGCA": 2} {
+		if s := m.Match(i7, ""); s != expect {
+			t.Errorf("Match %s expect: %v get: %v", i7, expect, s)
+		}
+	}
+
+	if _, err := NewMatcher([]*Sample{{"S1", "ACGT", ""}, {"S2", "ACGTA", ""}}, 0); err == nil {
+		t.Errorf("NewMatcher index length expect: %v get: %v", "error", err)
+	}
+}
+
+func TestHeaderIndex(t *testing.T) {
+	for name, expect := range map[string][2]string{
+		"M1:1:FC:1:1101:1:1 1:N:0:ACGTACGT+TTTTGGGG": {"ACGTACGT", "TTTTGGGG"},
+		"M1:1:FC:1:1101:1:1 1:N:0:ACGTACGT":          {"ACGTACGT", ""},
+	} {
+		if i7, i5, ok := HeaderIndex(name); !ok || i7 != expect[0] || i5 != expect[1] {
+			t.Errorf("HeaderIndex expect: %v get: %v %v %v", expect, i7, i5, ok)
+		}
+	}
+	if _, _, ok := HeaderIndex("M1:1:FC:1:1101:1:1"); ok {
+		t.Errorf("HeaderIndex expect: %v get: %v", false, ok)
+	}
+}
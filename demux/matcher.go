@@ -0,0 +1,250 @@
+package demux
+
+import (
+	"errors"
+	"fmt"
+	"gongs/xopen"
+	"sort"
+	"strings"
+)
+
+const UNDETERMINED = -1
+
+var (
+	ErrIndexLength = errors.New("Sample Indexes Of Different Length")
+	ErrMismatches  = errors.New("Mismatches Must Be 0, 1 or 2")
+)
+
+// hamming return mismatches of a and b of the same length, N mismatch any base
+func hamming(a, b string) int {
+	n := 0
+	for i := 0; i < len(a); i++ {
+		if a[i] != b[i] || a[i] == 'N' {
+			n++
+		}
+	}
+	return n
+}
+
+// variants add all sequences within mismatches of seq into m, value is the sample
+func variants(m map[string][]int, seq string, mismatches, sample int) {
+	var add func(s []byte, from, left int)
+	add = func(s []byte, from, left int) {
+		key := string(s)
+		if n := len(m[key]); n == 0 || m[key][n-1] != sample {
+			m[key] = append(m[key], sample)
+		}
+		if left == 0 {
+			return
+		}
+		for i := from; i < len(s); i++ {
+			orig := s[i]
+			for _, nt := range []byte("ACGTN") {
+				if nt != orig {
+					s[i] = nt
+					add(s, i+1, left-1)
+				}
+			}
+			s[i] = orig
+		}
+	}
+	add([]byte(seq), 0, mismatches)
+}
+
+// Matcher assign index sequences to samples, allow mismatches of each index,
+// index of reads longer than sample index are truncated
+type Matcher struct {
+	Samples    []*Sample
+	Mismatches int
+	i7         map[string][]int // sequences within mismatches of sample i7 to samples
+	i5         map[string][]int
+	len7       int
+	len5       int // 0 if single index
+
+	counts       []int // reads by sample
+	perfect      []int // reads of no mismatch by sample
+	undetermined map[string]int
+}
+
+// uniqSamples return samples without duplicate rows of the same id and indexes,
+// eg. a sample sequenced in multiple lanes of sample sheet v2
+func uniqSamples(samples []*Sample) []*Sample {
+	seen := make(map[Sample]bool)
+	uniq := make([]*Sample, 0, len(samples))
+	for _, s := range samples {
+		if !seen[*s] {
+			seen[*s] = true
+			uniq = append(uniq, s)
+		}
+	}
+	return uniq
+}
+
+// NewMatcher return matcher of samples, error if indexes of different length or two samples
+// collide, barcodes within 2 * mismatches in both indexes can't be told apart.
+// Duplicate rows are removed, indexes of the same id may collide, eg. a sample of multiple indexes
+func NewMatcher(samples []*Sample, mismatches int) (*Matcher, error) {
+	samples = uniqSamples(samples)
+	if len(samples) == 0 {
+		return nil, ErrNoSample
+	}
+	if mismatches < 0 || mismatches > 2 {
+		return nil, ErrMismatches
+	}
+	m := &Matcher{Samples: samples, Mismatches: mismatches, i7: make(map[string][]int), i5: make(map[string][]int),
+		len7: len(samples[0].Index), len5: len(samples[0].Index2),
+		counts: make([]int, len(samples)), perfect: make([]int, len(samples)), undetermined: make(map[string]int)}
+	for i, s := range samples {
+		if len(s.Index) != m.len7 || len(s.Index2) != m.len5 {
+			return nil, fmt.Errorf("%v: %s %s+%s", ErrIndexLength, s.ID, s.Index, s.Index2)
+		}
+		for j := 0; j < i; j++ {
+			o := samples[j]
+			if s.ID != o.ID && hamming(s.Index, o.Index) <= 2*mismatches && hamming(s.Index2, o.Index2) <= 2*mismatches {
+				return nil, fmt.Errorf("Index collision of %s %s+%s and %s %s+%s within %d mismatches, reduce mismatches",
+					s.ID, s.Index, s.Index2, o.ID, o.Index, o.Index2, mismatches)
+			}
+		}
+		variants(m.i7, s.Index, mismatches, i)
+		if m.len5 > 0 {
+			variants(m.i5, s.Index2, mismatches, i)
+		}
+	}
+	return m, nil
+}
+
+// HeaderIndex return i7 and i5 of Casava 1.8 read name, eg. "name 1:N:0:ATCACG+GTTTCG",
+// false if name has no index
+func HeaderIndex(name string) (string, string, bool) {
+	n := strings.IndexByte(name, ' ')
+	if n < 0 {
+		return "", "", false
+	}
+	comment := name[n+1:]
+	barcode := comment[strings.LastIndexByte(comment, ':')+1:]
+	if barcode == "" || strings.Count(comment, ":") < 3 {
+		return "", "", false
+	}
+	if i := strings.IndexByte(barcode, '+'); i >= 0 {
+		return barcode[:i], barcode[i+1:], true
+	}
+	return barcode, "", true
+}
+
+// truncate return the first n bases of seq, or seq padded with N
+func truncate(seq string, n int) string {
+	if len(seq) >= n {
+		return seq[:n]
+	}
+	return seq + strings.Repeat("N", n-len(seq))
+}
+
+// Match return sample of index i7 and i5, UNDETERMINED if no sample or ambiguous,
+// i5 is ignored for single index samples. Of indexes of the same id, the perfect matched is returned
+func (m *Matcher) Match(i7, i5 string) int {
+	i7, i5 = truncate(strings.ToUpper(i7), m.len7), truncate(strings.ToUpper(i5), m.len5)
+	found := UNDETERMINED
+	for _, s := range m.i7[i7] {
+		if m.len5 > 0 {
+			matched := false
+			for _, s5 := range m.i5[i5] {
+				if s == s5 {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if found == UNDETERMINED {
+			found = s
+		} else if m.Samples[s].ID != m.Samples[found].ID {
+			return UNDETERMINED
+		} else if m.Samples[s].Index == i7 && m.Samples[s].Index2 == i5 {
+			found = s
+		}
+	}
+	return found
+}
+
+// Assign match and count i7 and i5, undetermined barcodes are counted
+func (m *Matcher) Assign(i7, i5 string) int {
+	i7, i5 = truncate(strings.ToUpper(i7), m.len7), truncate(strings.ToUpper(i5), m.len5)
+	s := m.Match(i7, i5)
+	if s == UNDETERMINED {
+		barcode := i7
+		if m.len5 > 0 {
+			barcode += "+" + i5
+		}
+		m.undetermined[barcode]++
+		return s
+	}
+	m.counts[s]++
+	if i7 == m.Samples[s].Index && i5 == m.Samples[s].Index2 {
+		m.perfect[s]++
+	}
+	return s
+}
+
+// Undetermined return undetermined barcodes and counts, most frequent first, top n if n > 0
+func (m *Matcher) Undetermined(n int) ([]string, []int) {
+	barcodes := make([]string, 0, len(m.undetermined))
+	for barcode := range m.undetermined {
+		barcodes = append(barcodes, barcode)
+	}
+	sort.Slice(barcodes, func(i, j int) bool {
+		ci, cj := m.undetermined[barcodes[i]], m.undetermined[barcodes[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return barcodes[i] < barcodes[j]
+	})
+	if n > 0 && len(barcodes) > n {
+		barcodes = barcodes[:n]
+	}
+	counts := make([]int, len(barcodes))
+	for i, barcode := range barcodes {
+		counts[i] = m.undetermined[barcode]
+	}
+	return barcodes, counts
+}
+
+// Save save reads of each sample and the top undetermined barcodes
+func (m *Matcher) Save(prefix string, top int) error {
+	f, err := xopen.Xcreate(prefix+".demux", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	total, undetermined := 0, 0
+	for _, c := range m.counts {
+		total += c
+	}
+	for _, c := range m.undetermined {
+		undetermined += c
+	}
+	total += undetermined
+	percent := func(n int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(n*100) / float64(total)
+	}
+
+	fmt.Fprintln(f, "#: reads:", total)
+	fmt.Fprintln(f, "#: mismatches:", m.Mismatches)
+	fmt.Fprintf(f, "#: undetermined: %d\t%.2f\n", undetermined, percent(undetermined))
+	fmt.Fprintln(f, "##", strings.Join([]string{"sample", "index", "index2", "reads", "percent", "perfect"}, "\t"))
+	for i, s := range m.Samples {
+		fmt.Fprintf(f, "%s\t%s\t%s\t%d\t%.2f\t%d\n", s.ID, s.Index, s.Index2, m.counts[i], percent(m.counts[i]), m.perfect[i])
+	}
+	fmt.Fprintln(f, "#!", strings.Repeat("=", 20), "undetermined barcodes", strings.Repeat("=", 20))
+	fmt.Fprintln(f, "##", strings.Join([]string{"barcode", "reads", "percent"}, "\t"))
+	barcodes, counts := m.Undetermined(top)
+	for i, barcode := range barcodes {
+		fmt.Fprintf(f, "%s\t%d\t%.2f\n", barcode, counts[i], percent(counts[i]))
+	}
+	return nil
+}
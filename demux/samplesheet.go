@@ -0,0 +1,125 @@
+// demux package assign reads to samples by i7 and i5 index with mismatches
+
+package demux
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gongs/xopen"
+	"strings"
+)
+
+// UNDETERMINED_ID output name of reads matched no sample, reserved from sample ids
+const UNDETERMINED_ID = "Undetermined"
+
+var (
+	ErrNoSample    = errors.New("No Sample In Sample Sheet")
+	ErrSampleIndex = errors.New("Sample Without Index")
+	ErrSampleID    = errors.New("Invalid Sample ID")
+)
+
+// validID check id is usable in output file names: not empty, not the reserved
+// UNDETERMINED_ID, no path separators or ..
+func validID(id string) bool {
+	return id != "" && !strings.EqualFold(id, UNDETERMINED_ID) && !strings.ContainsAny(id, `/\`) && !strings.Contains(id, "..")
+}
+
+// Sample sample id with its i7 index and i5 index, Index2 is empty for single index
+type Sample struct {
+	ID     string
+	Index  string
+	Index2 string
+}
+
+// header names of id, index and index2 columns, lower case, of Illumina sample sheet v1 and v2
+var (
+	idColumns     = []string{"sample_id", "sampleid", "sample", "id"}
+	indexColumns  = []string{"index", "i7", "index1", "i7_index"}
+	index2Columns = []string{"index2", "i5", "i5_index"}
+)
+
+// findColumn return index of the first field in names, -1 if not found
+func findColumn(fields []string, names []string) int {
+	for _, name := range names {
+		for i, field := range fields {
+			if strings.ToLower(strings.TrimSpace(field)) == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// LoadSampleSheet load samples of a csv sample sheet, formats:
+//  1. Illumina SampleSheet v1, samples in [Data] section
+//  2. Illumina SampleSheet v2, samples in [BCLConvert_Data] section
+//  3. plain csv with header line of Sample_ID,index[,index2]
+//  4. plain csv of id,index[,index2] lines without header
+//
+// lines start with # and empty lines are skipped
+func LoadSampleSheet(filename string) ([]*Sample, error) {
+	file, err := xopen.Xopen(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	section := ""
+	sectioned := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.Trim(line, ",") == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			section = strings.ToLower(strings.Trim(strings.Split(line, ",")[0], "[]"))
+			sectioned = true
+			continue
+		}
+		if !sectioned || section == "data" || section == "bclconvert_data" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrNoSample
+	}
+
+	idCol, indexCol, index2Col := 0, 1, 2 // columns of csv without header
+	fields := strings.Split(lines[0], ",")
+	if col := findColumn(fields, indexColumns); col >= 0 {
+		idCol, indexCol, index2Col = findColumn(fields, idColumns), col, findColumn(fields, index2Columns)
+		if idCol < 0 {
+			return nil, fmt.Errorf("%s: no Sample_ID column in header: %s", filename, lines[0])
+		}
+		lines = lines[1:]
+	}
+
+	samples := []*Sample{}
+	for _, line := range lines {
+		fields := strings.Split(line, ",")
+		get := func(col int) string {
+			if col < 0 || col >= len(fields) {
+				return ""
+			}
+			return strings.TrimSpace(fields[col])
+		}
+		s := &Sample{ID: get(idCol), Index: strings.ToUpper(get(indexCol)), Index2: strings.ToUpper(get(index2Col))}
+		if !validID(s.ID) {
+			return nil, fmt.Errorf("%s: %v: %q", filename, ErrSampleID, s.ID)
+		}
+		if s.Index == "" {
+			return nil, fmt.Errorf("%s: %v: %s", filename, ErrSampleIndex, s.ID)
+		}
+		samples = append(samples, s)
+	}
+	if len(samples) == 0 {
+		return nil, ErrNoSample
+	}
+	return samples, nil
+}
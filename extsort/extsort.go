@@ -0,0 +1,255 @@
+// extsort package sort fastq records larger than memory, records are sorted in memory
+// in chunks, chunks are saved to compressed temp runs, then runs are k-way merged
+
+package extsort
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"gongs/biofile/fastq"
+	"gongs/xopen"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const SORT_MEMORY = 512 << 20 // default memory of records before saved to a run
+
+var (
+	ErrMates  = errors.New("Record Of Wrong Mates Number")
+	ErrSorted = errors.New("Sorter Already Sorted")
+)
+
+// Less return true if record a sort before b, a record is a read or mates of a pair
+type Less func(a, b []*fastq.Fastq) bool
+
+// Sorter sort records by less with bounded memory, records of equal keys keep the
+// input order, temp runs are saved in a temp dir removed by Close
+type Sorter struct {
+	Mates     int   // reads of a record, 1 or 2 of pairs
+	MaxMemory int64 // bytes of records in memory
+	less      Less
+	tempDir   string // parent of dir of runs, os.TempDir() if ""
+	dir       string
+	runs      []string
+	records   [][]*fastq.Fastq
+	bytes     int64
+	sorted    bool
+}
+
+// New return sorter of records of mates, runs are saved in a new dir under tempDir
+func New(mates int, less Less, maxMemory int64, tempDir string) *Sorter {
+	if maxMemory <= 0 {
+		maxMemory = SORT_MEMORY
+	}
+	return &Sorter{Mates: mates, MaxMemory: maxMemory, less: less, tempDir: tempDir}
+}
+
+// size return memory estimated of a record
+func size(fqs []*fastq.Fastq) int64 {
+	n := 0
+	for _, fq := range fqs {
+		n += len(fq.Name) + len(fq.Seq) + len(fq.Qual) + 80 // struct and slice headers
+	}
+	return int64(n)
+}
+
+// Add add a record, records in memory are saved to a run if over memory
+func (s *Sorter) Add(fqs ...*fastq.Fastq) error {
+	if s.sorted {
+		return ErrSorted
+	}
+	if len(fqs) != s.Mates {
+		return fmt.Errorf("%v: %d of %d", ErrMates, len(fqs), s.Mates)
+	}
+	s.records = append(s.records, fqs)
+	s.bytes += size(fqs)
+	if s.bytes >= s.MaxMemory {
+		return s.spill()
+	}
+	return nil
+}
+
+// Runs return number of temp runs saved
+func (s *Sorter) Runs() int {
+	return len(s.runs)
+}
+
+func (s *Sorter) sortRecords() {
+	sort.SliceStable(s.records, func(i, j int) bool {
+		return s.less(s.records[i], s.records[j])
+	})
+}
+
+// spill sort records in memory and save them to a gzip run, mates are saved one by one
+func (s *Sorter) spill() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	if s.dir == "" {
+		dir, err := os.MkdirTemp(s.tempDir, "extsort")
+		if err != nil {
+			return err
+		}
+		s.dir = dir
+	}
+	s.sortRecords()
+	name := filepath.Join(s.dir, fmt.Sprintf("run%05d.fastq.gz", len(s.runs)))
+	out, err := xopen.Xcreate(name, "w")
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, name)
+	w := bufio.NewWriter(out)
+	for _, fqs := range s.records {
+		for _, fq := range fqs {
+			fmt.Fprintln(w, fq)
+		}
+	}
+	err = w.Flush()
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	s.records, s.bytes = nil, 0
+	return err
+}
+
+// run a sorted source of records, a temp run or records in memory
+type run struct {
+	index   int // input order of runs, for ties
+	ff      *fastq.FastqFile
+	records [][]*fastq.Fastq
+	current []*fastq.Fastq
+}
+
+// next read the next record to current, false if run ends
+func (r *run) next(mates int) (bool, error) {
+	if r.ff == nil {
+		if len(r.records) == 0 {
+			return false, nil
+		}
+		r.current, r.records = r.records[0], r.records[1:]
+		return true, nil
+	}
+	fqs := make([]*fastq.Fastq, 0, mates)
+	for len(fqs) < mates && r.ff.Next() {
+		fqs = append(fqs, r.ff.Fq())
+	}
+	if err := r.ff.Err(); err != nil {
+		return false, err
+	}
+	if len(fqs) == 0 {
+		return false, nil
+	} else if len(fqs) != mates {
+		return false, fmt.Errorf("%v: %d of %d in %s", ErrMates, len(fqs), mates, r.ff.Name)
+	}
+	r.current = fqs
+	return true, nil
+}
+
+// runHeap min heap of runs by current record
+type runHeap struct {
+	runs []*run
+	less Less
+}
+
+func (h *runHeap) Len() int { return len(h.runs) }
+
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.runs[i], h.runs[j]
+	if h.less(a.current, b.current) {
+		return true
+	} else if h.less(b.current, a.current) {
+		return false
+	}
+	return a.index < b.index
+}
+
+func (h *runHeap) Swap(i, j int) { h.runs[i], h.runs[j] = h.runs[j], h.runs[i] }
+
+func (h *runHeap) Push(x any) { h.runs = append(h.runs, x.(*run)) }
+
+func (h *runHeap) Pop() any {
+	n := len(h.runs)
+	r := h.runs[n-1]
+	h.runs = h.runs[:n-1]
+	return r
+}
+
+// Sort call fn with each record in order, records in memory are merged with runs
+// without saving, stop at the first error of fn
+func (s *Sorter) Sort(fn func([]*fastq.Fastq) error) error {
+	if s.sorted {
+		return ErrSorted
+	}
+	s.sorted = true
+	s.sortRecords()
+
+	h := &runHeap{less: s.less}
+	defer func() {
+		for _, r := range h.runs {
+			if r.ff != nil {
+				r.ff.Close()
+			}
+		}
+	}()
+	add := func(r *run) error {
+		ok, err := r.next(s.Mates)
+		if err != nil {
+			return err
+		}
+		if ok {
+			h.runs = append(h.runs, r)
+		} else if r.ff != nil {
+			r.ff.Close()
+		}
+		return nil
+	}
+	for i, name := range s.runs {
+		ff, err := fastq.Open(name)
+		if err != nil {
+			return err
+		}
+		if err := add(&run{index: i, ff: ff}); err != nil {
+			ff.Close()
+			return err
+		}
+	}
+	// records in memory are the last added
+	if err := add(&run{index: len(s.runs), records: s.records}); err != nil {
+		return err
+	}
+	s.records = nil
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		r := h.runs[0]
+		if err := fn(r.current); err != nil {
+			return err
+		}
+		ok, err := r.next(s.Mates)
+		if err != nil {
+			return err
+		}
+		if ok {
+			heap.Fix(h, 0)
+			continue
+		}
+		heap.Pop(h)
+		if r.ff != nil {
+			r.ff.Close()
+		}
+	}
+	return nil
+}
+
+// Close remove temp runs
+func (s *Sorter) Close() error {
+	s.records = nil
+	if s.dir == "" {
+		return nil
+	}
+	return os.RemoveAll(s.dir)
+}
@@ -0,0 +1,110 @@
+package extsort
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"math/rand"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func record(name, seq, qual string) *fastq.Fastq {
+	return &fastq.Fastq{Name: name, Seq: []byte(seq), Qual: []byte(qual)}
+}
+
+func TestSorter(t *testing.T) {
+	dir := t.TempDir()
+	rng := rand.New(rand.NewSource(1))
+	ids := make([]string, 1000)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("r%04d", rng.Intn(500)) // with duplicated ids
+	}
+
+	// about 10 records a run
+	s := New(2, ByName, 10*size([]*fastq.Fastq{record("r0000 1:N:0", "ACGT", "IIII"), record("r0000 2:N:0", "ACGT", "IIII")}), dir)
+	// input index in sequence of read2, to check records of the same id keep input order
+	index := func(i int) string {
+		seq := []byte{}
+		for k := 0; k < 5; k++ {
+			seq = append([]byte{"ACGT"[i%4]}, seq...)
+			i /= 4
+		}
+		return string(seq)
+	}
+	for i, id := range ids {
+		r1 := record(id+" 1:N:0", "ACGT", "IIII")
+		r2 := record(id+" 2:N:0", index(i), "IIIII")
+		if err := s.Add(r1, r2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Add(record("r", "A", "I")); err == nil {
+		t.Errorf("Sorter Add expect: %v get: %v", ErrMates, err)
+	}
+	if s.Runs() < 50 {
+		t.Errorf("Sorter Runs expect: %v get: %v", ">= 50", s.Runs())
+	}
+
+	order := make([]int, len(ids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return ids[order[i]] < ids[order[j]] })
+	n := 0
+	err := s.Sort(func(fqs []*fastq.Fastq) error {
+		if fqs[0].Id() != fqs[1].Id() {
+			t.Fatalf("Sorter mates expect: %v get: %v %v", "same id", fqs[0].Name, fqs[1].Name)
+		}
+		if n < len(order) && string(fqs[1].Seq) != index(order[n]) {
+			t.Fatalf("Sorter order at %d expect: %v get: %v", n, index(order[n]), string(fqs[1].Seq))
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(ids) {
+		t.Errorf("Sorter records expect: %v get: %v", len(ids), n)
+	}
+
+	if err := s.Sort(func([]*fastq.Fastq) error { return nil }); err != ErrSorted {
+		t.Errorf("Sorter Sort twice expect: %v get: %v", ErrSorted, err)
+	}
+	runDir := s.dir
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(runDir); !os.IsNotExist(err) {
+		t.Errorf("Sorter Close expect: %v get: %v", "runs removed", err)
+	}
+}
+
+func TestDedup(t *testing.T) {
+	for _, memory := range []int64{1, SORT_MEMORY} {
+		s := New(1, BySeq, memory, t.TempDir())
+		for _, fq := range []*fastq.Fastq{
+			record("r1", "ACGT", "IIII"),
+			record("r2", "TTTT", "IIII"),
+			record("r3", "ACGT", "JJJJ"),
+			record("r4", "ACGT", "JJJJ"),
+			record("r5", "AAAA", "####"),
+		} {
+			s.Add(fq)
+		}
+		kept := []string{}
+		total, unique, err := Dedup(s, func(fqs []*fastq.Fastq) error {
+			kept = append(kept, fqs[0].Name)
+			return nil
+		})
+		s.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 5 || unique != 3 || strings.Join(kept, ",") != "r5,r3,r2" {
+			t.Errorf("Dedup memory %d expect: %v get: %v %v %v", memory, "5 3 r5,r3,r2", total, unique, kept)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package extsort
+
+import (
+	"bytes"
+	"gongs/biofile/fastq"
+)
+
+// ByName sort records by id of the first read, then the whole name
+func ByName(a, b []*fastq.Fastq) bool {
+	if ia, ib := a[0].Id(), b[0].Id(); ia != ib {
+		return ia < ib
+	}
+	return a[0].Name < b[0].Name
+}
+
+// compareSeq compare sequences of mates one by one
+func compareSeq(a, b []*fastq.Fastq) int {
+	for i := range a {
+		if c := bytes.Compare(a[i].Seq, b[i].Seq); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// BySeq sort records by sequences of mates, then by name
+func BySeq(a, b []*fastq.Fastq) bool {
+	if c := compareSeq(a, b); c != 0 {
+		return c < 0
+	}
+	return ByName(a, b)
+}
+
+// quality return sum of quality of mates
+func quality(fqs []*fastq.Fastq) int {
+	q := 0
+	for _, fq := range fqs {
+		for _, b := range fq.Qual {
+			q += int(b)
+		}
+	}
+	return q
+}
+
+// Dedup call fn with one record of each sequence, the copy of the highest quality sum,
+// the first by name if tie, sorter must sort by BySeq, return records and unique records
+func Dedup(s *Sorter, fn func([]*fastq.Fastq) error) (int, int, error) {
+	total, unique := 0, 0
+	var best []*fastq.Fastq
+	bestQual := 0
+	err := s.Sort(func(fqs []*fastq.Fastq) error {
+		total++
+		if best != nil && compareSeq(best, fqs) == 0 {
+			if q := quality(fqs); q > bestQual {
+				best, bestQual = fqs, q
+			}
+			return nil
+		}
+		if best != nil {
+			if err := fn(best); err != nil {
+				return err
+			}
+		}
+		unique++
+		best, bestQual = fqs, quality(fqs)
+		return nil
+	})
+	if err == nil && best != nil {
+		err = fn(best)
+	}
+	return total, unique, err
+}
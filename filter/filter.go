@@ -0,0 +1,160 @@
+package filter
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"gongs/xopen"
+	"strings"
+)
+
+// PairPolicy how mates decide a pair pass
+type PairPolicy int
+
+const (
+	BOTH   PairPolicy = iota // pair pass if both mates pass
+	EITHER                   // pair pass if either mate pass
+)
+
+func (p PairPolicy) String() string {
+	switch p {
+	case BOTH:
+		return "both"
+	case EITHER:
+		return "either"
+	}
+	return "Unkown"
+}
+
+// Filter apply rules in order, a read is rejected by the first rule it not pass,
+// count reads or pairs rejected by each rule
+type Filter struct {
+	Policy   PairPolicy
+	rules    []Rule
+	total    int
+	rejected []int // rejected count by rule
+	pairs    bool  // pairs are checked by KeepPair
+}
+
+func New(rules ...Rule) *Filter {
+	return &Filter{rules: rules, rejected: make([]int, len(rules))}
+}
+
+// Add append rules
+func (f *Filter) Add(rules ...Rule) {
+	f.rules = append(f.rules, rules...)
+	f.rejected = append(f.rejected, make([]int, len(rules))...)
+}
+
+// Len return rules number
+func (f *Filter) Len() int {
+	return len(f.rules)
+}
+
+// check return index of the first rule fq not pass, -1 if pass all
+func (f *Filter) check(fq *fastq.Fastq) int {
+	for i, r := range f.rules {
+		if !r.Pass(fq) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Check return the rejection reason of fq, "" if pass, not counted
+func (f *Filter) Check(fq *fastq.Fastq) string {
+	if i := f.check(fq); i >= 0 {
+		return f.rules[i].Name()
+	}
+	return ""
+}
+
+// Keep return true if fq pass all rules, count the rejection reason
+func (f *Filter) Keep(fq *fastq.Fastq) bool {
+	f.total++
+	if i := f.check(fq); i >= 0 {
+		f.rejected[i]++
+		return false
+	}
+	return true
+}
+
+// KeepPair return true if pair pass by Policy, a rejected pair is counted to the
+// reason of read1, or read2 if read1 pass
+func (f *Filter) KeepPair(p *fastq.Pair) bool {
+	f.total++
+	f.pairs = true
+	i1, i2 := f.check(p.Read1), f.check(p.Read2)
+	pass := i1 < 0 && i2 < 0
+	if f.Policy == EITHER {
+		pass = i1 < 0 || i2 < 0
+	}
+	if pass {
+		return true
+	}
+	if i1 >= 0 {
+		f.rejected[i1]++
+	} else {
+		f.rejected[i2]++
+	}
+	return false
+}
+
+// Total return reads or pairs number checked by Keep or KeepPair
+func (f *Filter) Total() int {
+	return f.total
+}
+
+// Passed return reads or pairs number passed
+func (f *Filter) Passed() int {
+	n := f.total
+	for _, c := range f.rejected {
+		n -= c
+	}
+	return n
+}
+
+// Rejected return rejected count by reason, in order of rules
+func (f *Filter) Rejected() ([]string, []int) {
+	names := make([]string, len(f.rules))
+	for i, r := range f.rules {
+		names[i] = r.Name()
+	}
+	return names, append([]int{}, f.rejected...)
+}
+
+// Merge add counts of other into f, rules of both must be the same
+func (f *Filter) Merge(other *Filter) {
+	f.total += other.total
+	f.pairs = f.pairs || other.pairs
+	for i, c := range other.rejected {
+		f.rejected[i] += c
+	}
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n*100) / float64(total)
+}
+
+// Save save rejected count and percent by reason
+func (f *Filter) Save(prefix string) error {
+	file, err := xopen.Xcreate(prefix+".filter", "w")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fmt.Fprintln(file, "#: total:", f.total)
+	fmt.Fprintf(file, "#: passed: %d\t%.2f\n", f.Passed(), percent(f.Passed(), f.total))
+	if f.pairs {
+		fmt.Fprintln(file, "#: pair policy:", f.Policy)
+	}
+	fmt.Fprintln(file, "##", strings.Join([]string{"reason", "rejected", "percent"}, "\t"))
+	names, counts := f.Rejected()
+	for i, name := range names {
+		fmt.Fprintf(file, "%s\t%d\t%.2f\n", name, counts[i], percent(counts[i], f.total))
+	}
+	return nil
+}
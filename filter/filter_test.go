@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"gongs/biofile/fastq"
+	"strings"
+	"testing"
+)
+
+func newFq(name, seq string, qual byte) *fastq.Fastq {
+	return &fastq.Fastq{Name: name, Seq: []byte(seq), Qual: []byte(strings.Repeat(string(qual), len(seq)))}
+}
+
+func TestRules(t *testing.T) {
+	fq := newFq("r1 1:N:0:1", "ACGTNACGTA", '+') // Q10
+	name, _ := NameMatch("^r1 ")
+	seq, _ := SeqMatch("^ACGT")
+	for _, c := range []struct {
+		rule   Rule
+		expect bool
+	}{
+		{MinLen(10), true}, {MinLen(11), false}, {MaxLen(9), false},
+		{MinMeanQual(10, 33), true}, {MinMeanQual(10.5, 33), false},
+		{MaxEE(1, 33), true}, {MaxEE(0.99, 33), false},
+		{MaxNFrac(0.1), true}, {MaxNFrac(0.05), false},
+		{Chastity(), true}, {Chastity(), true},
+		{name, true}, {seq, true}, {Not(seq), false},
+		{All(MinLen(5), MaxLen(5)), false}, {Any(MinLen(5), MaxLen(5)), true},
+	} {
+		if pass := c.rule.Pass(fq); pass != c.expect {
+			t.Errorf("%s expect: %v get: %v", c.rule.Name(), c.expect, pass)
+		}
+	}
+	if Chastity().Pass(newFq("r1 1:Y:0:1", "A", 'I')) {
+		t.Errorf("Chastity expect: %v get: %v", false, true)
+	}
+	if _, err := SeqMatch("("); err == nil {
+		t.Errorf("SeqMatch expect: %v get: %v", "error", err)
+	}
+}
+
+func TestDust(t *testing.T) {
+	for _, n := range []int{4, 50, 64, 100} {
+		if d := Dust([]byte(strings.Repeat("A", n))); d != 100 {
+			t.Errorf("Dust homopolymer of %d expect: %v get: %v", n, 100, d)
+		}
+	}
+	if d := Dust([]byte(strings.Repeat("CA", 50))); d < 40 {
+		t.Errorf("Dust dinucleotide expect: %v get: %v", "> 40", d)
+	}
+	if d := Dust([]byte("ACGTTGCAAGCTTCGATCGGATCCATGCAGTACGTAGCTAGGCTACGATCGACTAGCTTAGC")); d > 7 {
+		t.Errorf("Dust random expect: %v get: %v", "< 7", d)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := New(MinLen(5), MinMeanQual(20, 33))
+	for _, fq := range []*fastq.Fastq{newFq("a", "ACGTACGT", 'I'), newFq("b", "ACG", 'I'), newFq("c", "ACGTACGT", '#')} {
+		f.Keep(fq)
+	}
+	names, counts := f.Rejected()
+	if f.Total() != 3 || f.Passed() != 1 || counts[0] != 1 || counts[1] != 1 {
+		t.Errorf("Filter expect: %v get: %v %v %v %v", "3 1 [1 1]", f.Total(), f.Passed(), names, counts)
+	}
+
+	good, bad := newFq("a", "ACGTACGT", 'I'), newFq("b", "ACG", 'I')
+	both, either := New(MinLen(5)), New(MinLen(5))
+	either.Policy = EITHER
+	for _, p := range []*fastq.Pair{{Read1: good, Read2: bad}, {Read1: good, Read2: good}} {
+		both.KeepPair(p)
+		either.KeepPair(p)
+	}
+	if both.Passed() != 1 || either.Passed() != 2 {
+		t.Errorf("KeepPair both either expect: %v %v get: %v %v", 1, 2, both.Passed(), either.Passed())
+	}
+}
@@ -0,0 +1,258 @@
+// filter package filter fastq reads and pairs by composable rules
+
+package filter
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	DUST_WINDOW = 64 // window size of DUST score
+	DUST_STEP   = 32
+)
+
+// Rule a predicate on read, Name is the rejection reason if read not pass
+type Rule interface {
+	Name() string
+	Pass(fq *fastq.Fastq) bool
+}
+
+type funcRule struct {
+	name string
+	pass func(fq *fastq.Fastq) bool
+}
+
+func (r *funcRule) Name() string {
+	return r.name
+}
+
+func (r *funcRule) Pass(fq *fastq.Fastq) bool {
+	return r.pass(fq)
+}
+
+// NewRule return rule of name by a predicate function
+func NewRule(name string, pass func(fq *fastq.Fastq) bool) Rule {
+	return &funcRule{name: name, pass: pass}
+}
+
+// ****************************** predicates **********************************
+
+// MinLen pass reads of length >= n
+func MinLen(n int) Rule {
+	return NewRule(fmt.Sprintf("length<%d", n), func(fq *fastq.Fastq) bool {
+		return len(fq.Seq) >= n
+	})
+}
+
+// MaxLen pass reads of length <= n
+func MaxLen(n int) Rule {
+	return NewRule(fmt.Sprintf("length>%d", n), func(fq *fastq.Fastq) bool {
+		return len(fq.Seq) <= n
+	})
+}
+
+// MeanQual return mean phred quality of read, 0 if empty
+func MeanQual(qual []byte, offset int) float64 {
+	if len(qual) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, q := range qual {
+		sum += int(q) - offset
+	}
+	return float64(sum) / float64(len(qual))
+}
+
+// MinMeanQual pass reads of mean phred quality >= q
+func MinMeanQual(q float64, offset int) Rule {
+	return NewRule(fmt.Sprintf("mean_qual<%g", q), func(fq *fastq.Fastq) bool {
+		return MeanQual(fq.Qual, offset) >= q
+	})
+}
+
+// errProbs error probability of phred quality 0-93
+var errProbs = func() []float64 {
+	probs := make([]float64, 94)
+	for q := range probs {
+		probs[q] = math.Pow(10, -float64(q)/10)
+	}
+	return probs
+}()
+
+// ExpectedErrors return sum of error probabilities of bases
+func ExpectedErrors(qual []byte, offset int) float64 {
+	ee := 0.0
+	for _, q := range qual {
+		p := int(q) - offset
+		if p < 0 {
+			p = 0
+		} else if p >= len(errProbs) {
+			p = len(errProbs) - 1
+		}
+		ee += errProbs[p]
+	}
+	return ee
+}
+
+// MaxEE pass reads of expected errors <= ee
+func MaxEE(ee float64, offset int) Rule {
+	return NewRule(fmt.Sprintf("expected_errors>%g", ee), func(fq *fastq.Fastq) bool {
+		return ExpectedErrors(fq.Qual, offset) <= ee
+	})
+}
+
+// NFrac return fraction of N bases, 0 if empty
+func NFrac(seq []byte) float64 {
+	if len(seq) == 0 {
+		return 0
+	}
+	n := 0
+	for _, nt := range seq {
+		if nt == 'N' || nt == 'n' {
+			n++
+		}
+	}
+	return float64(n) / float64(len(seq))
+}
+
+// MaxNFrac pass reads of N fraction <= frac
+func MaxNFrac(frac float64) Rule {
+	return NewRule(fmt.Sprintf("n_fraction>%g", frac), func(fq *fastq.Fastq) bool {
+		return NFrac(fq.Seq) <= frac
+	})
+}
+
+// dustCode code of base in triplet, other bases share one code
+var dustCode = func() [256]int {
+	var code [256]int
+	for i := range code {
+		code[i] = 4
+	}
+	for i, nt := range []byte("ACGT") {
+		code[nt], code[nt+'a'-'A'] = i, i
+	}
+	return code
+}()
+
+// dustWindow return DUST score of a window, sum of c(c-1)/2 of triplets count c
+// divided by triplets number - 1
+func dustWindow(seq []byte) float64 {
+	if len(seq) < 4 {
+		return 0
+	}
+	var counts [125]int
+	for i := 0; i+3 <= len(seq); i++ {
+		counts[dustCode[seq[i]]*25+dustCode[seq[i+1]]*5+dustCode[seq[i+2]]]++
+	}
+	score := 0
+	for _, c := range counts {
+		score += c * (c - 1) / 2
+	}
+	return float64(score) / float64(len(seq)-3)
+}
+
+// Dust return DUST low complexity score of seq scaled to 0-100 as prinseq, the mean
+// score of windows of DUST_WINDOW by DUST_STEP, each scaled by the score (len-2)/2 of
+// a homopolymer window of its length, so a homopolymer of any length scores 100
+func Dust(seq []byte) float64 {
+	if len(seq) < 4 {
+		return 0
+	}
+	sum, n := 0.0, 0
+	for start := 0; ; start += DUST_STEP {
+		end := start + DUST_WINDOW
+		if end > len(seq) {
+			end = len(seq)
+		}
+		if end-start >= 4 {
+			max := float64(end-start-2) / 2 // score of a homopolymer window
+			sum += dustWindow(seq[start:end]) * 100 / max
+			n++
+		}
+		if end == len(seq) {
+			break
+		}
+	}
+	return math.Min(100, sum/float64(n))
+}
+
+// MaxDust pass reads of DUST score <= score, prinseq suggests 7
+func MaxDust(score float64) Rule {
+	return NewRule(fmt.Sprintf("dust>%g", score), func(fq *fastq.Fastq) bool {
+		return Dust(fq.Seq) <= score
+	})
+}
+
+// Chastity pass reads not flagged as filtered by the Illumina chastity filter (:Y: in name)
+func Chastity() Rule {
+	return NewRule("chastity", func(fq *fastq.Fastq) bool {
+		return !fq.IsFilter()
+	})
+}
+
+// NameMatch pass reads of name match pattern
+func NameMatch(pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return NewRule(fmt.Sprintf("name!~%s", pattern), func(fq *fastq.Fastq) bool {
+		return re.MatchString(fq.Name)
+	}), nil
+}
+
+// SeqMatch pass reads of sequence match pattern
+func SeqMatch(pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return NewRule(fmt.Sprintf("seq!~%s", pattern), func(fq *fastq.Fastq) bool {
+		return re.Match(fq.Seq)
+	}), nil
+}
+
+// ****************************** combinators *********************************
+
+// Not pass reads not pass r
+func Not(r Rule) Rule {
+	return NewRule("not("+r.Name()+")", func(fq *fastq.Fastq) bool {
+		return !r.Pass(fq)
+	})
+}
+
+// All pass reads pass all rules
+func All(rules ...Rule) Rule {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name()
+	}
+	return NewRule("all("+strings.Join(names, ",")+")", func(fq *fastq.Fastq) bool {
+		for _, r := range rules {
+			if !r.Pass(fq) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Any pass reads pass any rule
+func Any(rules ...Rule) Rule {
+	names := make([]string, len(rules))
+	for i, r := range rules {
+		names[i] = r.Name()
+	}
+	return NewRule("any("+strings.Join(names, ",")+")", func(fq *fastq.Fastq) bool {
+		for _, r := range rules {
+			if r.Pass(fq) {
+				return true
+			}
+		}
+		return false
+	})
+}
@@ -0,0 +1,288 @@
+// index package build exact k-mer index of fasta reference, locate short
+// sequence (read, primer, probe) on both strands by seed and extend:
+//   1. seed:   look up each query k-mer in index, group seeds by diagonal
+//   2. extend: glocal align query to the reference region of each diagonal
+
+package index
+
+import (
+	"errors"
+	"fmt"
+	"gongs/align"
+	"gongs/biofile/fasta"
+	"sort"
+)
+
+const (
+	MaxK   = 32   // k-mer encoded in uint64 with 2 bits each base
+	MaxOcc = 1000 // skip k-mer occur more than MaxOcc times (repeat)
+)
+
+var (
+	ErrKmerSize = errors.New("Kmer size should be in [1, 32]")
+)
+
+// ****************************** Hit *****************************************
+
+// Hit record a located region of query on reference
+type Hit struct {
+	Ref    string // reference name
+	Strand byte   // '+' query on forward strand, '-' reverse complement of query
+	Start  int    // start position on reference forward strand, 0-based
+	End    int    // end position on reference forward strand
+	Qstart int    // start position on query (reverse complemented if strand is '-')
+	Qend   int    // end position on query
+	Score  int    // alignment score
+	Errors int    // alignment errors
+	Seeds  int    // seed number support the hit
+}
+
+func (h Hit) String() string {
+	return fmt.Sprintf("Hit(ref:%s, strand:%c, start:%d, end:%d, qstart:%d, qend:%d, score:%d, errors:%d, seeds:%d)",
+		h.Ref, h.Strand, h.Start, h.End, h.Qstart, h.Qend, h.Score, h.Errors, h.Seeds)
+}
+
+// ****************************** Index ***************************************
+
+type pos struct {
+	ref int32 // reference id
+	off int32 // k-mer start on reference
+}
+
+type Index struct {
+	K     int
+	names []string         // reference names
+	seqs  [][]byte         // reference seqs
+	table map[uint64][]pos // k-mer positions
+}
+
+// New return an empty index with kmer size k
+func New(k int) (*Index, error) {
+	if k < 1 || k > MaxK {
+		return nil, ErrKmerSize
+	}
+	return &Index{K: k, table: make(map[uint64][]pos)}, nil
+}
+
+// Build build index with kmer size k from fasta files
+func Build(k int, filenames ...string) (*Index, error) {
+	idx, err := New(k)
+	if err != nil {
+		return nil, err
+	}
+	for _, filename := range filenames {
+		ff, err := fasta.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		for ff.Next() {
+			fa := ff.Fa()
+			idx.Add(fa.Id(), fa.Seq)
+		}
+		err = ff.Err()
+		ff.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Add add a reference sequence into index
+func (idx *Index) Add(name string, seq []byte) {
+	ref := int32(len(idx.names))
+	idx.names = append(idx.names, name)
+	idx.seqs = append(idx.seqs, seq)
+	eachKmer(seq, idx.K, func(off int, kmer uint64) {
+		idx.table[kmer] = append(idx.table[kmer], pos{ref: ref, off: int32(off)})
+	})
+}
+
+// Len return reference number in index
+func (idx *Index) Len() int {
+	return len(idx.names)
+}
+
+func (idx *Index) String() string {
+	return fmt.Sprintf("Index(k:%d, refs:%d, kmers:%d)", idx.K, len(idx.names), len(idx.table))
+}
+
+// ****************************** kmer ****************************************
+
+var code = [256]int8{}
+
+func init() {
+	for i := range code {
+		code[i] = -1
+	}
+	for i, nt := range []byte("ACGT") {
+		code[nt] = int8(i)
+		code[nt-'A'+'a'] = int8(i)
+	}
+}
+
+// eachKmer call fn with offset and 2-bit code of each k-mer, skip k-mer contain non ACGT base
+func eachKmer(seq []byte, k int, fn func(off int, kmer uint64)) {
+	var kmer uint64
+	mask := uint64(1)<<uint(2*k) - 1
+	if k == MaxK {
+		mask = ^uint64(0)
+	}
+	valid := 0 // valid bases end at current position
+	for i, nt := range seq {
+		c := code[nt]
+		if c < 0 {
+			valid = 0
+			continue
+		}
+		kmer = (kmer<<2 | uint64(c)) & mask
+		if valid++; valid >= k {
+			fn(i-k+1, kmer)
+		}
+	}
+}
+
+var complement = [256]byte{}
+
+func init() {
+	for i := range complement {
+		complement[i] = byte(i)
+	}
+	pairs := []string{"AT", "CG", "at", "cg", "RY", "KM", "ry", "km", "BV", "DH", "bv", "dh"}
+	for _, p := range pairs {
+		complement[p[0]] = p[1]
+		complement[p[1]] = p[0]
+	}
+}
+
+// RevComp return reverse complement of seq
+func RevComp(seq []byte) []byte {
+	rc := make([]byte, len(seq))
+	for i, l := 0, len(seq); i < l; i++ {
+		rc[l-1-i] = complement[seq[i]]
+	}
+	return rc
+}
+
+// ****************************** Locate **************************************
+
+// candidate reference region on a diagonal (reference offset - query offset)
+type candidate struct {
+	ref   int32
+	diag  int
+	seeds int
+}
+
+// seed group seeds of query by reference and diagonal, diagonal within maxShift merged
+func (idx *Index) seed(query []byte, maxShift int) []candidate {
+	counts := make(map[candidate]int)
+	eachKmer(query, idx.K, func(off int, kmer uint64) {
+		ps := idx.table[kmer]
+		if len(ps) > MaxOcc {
+			return
+		}
+		for _, p := range ps {
+			counts[candidate{ref: p.ref, diag: int(p.off) - off}]++
+		}
+	})
+
+	cands := make([]candidate, 0, len(counts))
+	for c, n := range counts {
+		c.seeds = n
+		cands = append(cands, c)
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].ref != cands[j].ref {
+			return cands[i].ref < cands[j].ref
+		}
+		return cands[i].diag < cands[j].diag
+	})
+
+	// merge near diagonals caused by indels, keep the diagonal with most seeds
+	merged := []candidate{}
+	for _, c := range cands {
+		if l := len(merged) - 1; l >= 0 && merged[l].ref == c.ref && c.diag-merged[l].diag <= maxShift {
+			if c.seeds > merged[l].seeds {
+				merged[l].diag = c.diag
+			}
+			merged[l].seeds += c.seeds
+			continue
+		}
+		merged = append(merged, c)
+	}
+	return merged
+}
+
+// extend align query to reference region of each candidate
+func (idx *Index) extend(query []byte, strand byte, cands []candidate, conf *align.Config, minSeeds, slack int) ([]Hit, error) {
+	hits := []Hit{}
+	if len(cands) == 0 {
+		return hits, nil
+	}
+	aligner, err := align.New("glocal", string(query), conf)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range cands {
+		if c.seeds < minSeeds {
+			continue
+		}
+		ref := idx.seqs[c.ref]
+		start, end := c.diag-slack, c.diag+len(query)+slack
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ref) {
+			end = len(ref)
+		}
+		if start >= end {
+			continue
+		}
+		ar := aligner.Align(string(ref[start:end]))
+		if ar.Qend == 0 && ar.Tend == 0 { // no alignment found
+			continue
+		}
+		hits = append(hits, Hit{
+			Ref:    idx.names[c.ref],
+			Strand: strand,
+			Start:  start + ar.Tstart,
+			End:    start + ar.Tend,
+			Qstart: ar.Qstart,
+			Qend:   ar.Qend,
+			Score:  ar.Score,
+			Errors: ar.Errors,
+			Seeds:  c.seeds,
+		})
+	}
+	return hits, nil
+}
+
+// Locate find query on both strands of reference, hits need at least minSeeds seeds,
+// use align.DefaultConfig if conf is nil
+func (idx *Index) Locate(query []byte, conf *align.Config, minSeeds int) ([]Hit, error) {
+	if conf == nil {
+		conf = align.DefaultConfig()
+	}
+	if minSeeds < 1 {
+		minSeeds = 1
+	}
+	slack := int(conf.ErrorRate*float64(len(query))) + 1 // indels allowed at both ends
+
+	hits, err := idx.extend(query, '+', idx.seed(query, slack), conf, minSeeds, slack)
+	if err != nil {
+		return nil, err
+	}
+	rc := RevComp(query)
+	rhits, err := idx.extend(rc, '-', idx.seed(rc, slack), conf, minSeeds, slack)
+	if err != nil {
+		return nil, err
+	}
+	hits = append(hits, rhits...)
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Errors < hits[j].Errors
+	})
+	return hits, nil
+}
@@ -0,0 +1,83 @@
+package index
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func randSeq(r *rand.Rand, n int) []byte {
+	seq := make([]byte, n)
+	for i := range seq {
+		seq[i] = "ACGT"[r.Intn(4)]
+	}
+	return seq
+}
+
+func Test_New(t *testing.T) {
+	for _, k := range []int{0, 33} {
+		if _, err := New(k); err != ErrKmerSize {
+			t.Errorf("New(%d) expect: %v get: %v", k, ErrKmerSize, err)
+		}
+	}
+	if _, err := New(MaxK); err != nil {
+		t.Errorf("New(%d) expect: nil get: %v", MaxK, err)
+	}
+}
+
+func Test_RevComp(t *testing.T) {
+	if rc := string(RevComp([]byte("AACGTN"))); rc != "NACGTT" {
+		t.Errorf("RevComp expect: NACGTT get: %s", rc)
+	}
+}
+
+func Test_eachKmer(t *testing.T) {
+	offs := []int{}
+	eachKmer([]byte("ACGTNACGTA"), 3, func(off int, kmer uint64) {
+		offs = append(offs, off)
+	})
+	expect := []int{0, 1, 5, 6, 7}
+	if len(offs) != len(expect) {
+		t.Fatalf("eachKmer expect: %v get: %v", expect, offs)
+	}
+	for i := range offs {
+		if offs[i] != expect[i] {
+			t.Errorf("eachKmer expect: %v get: %v", expect, offs)
+		}
+	}
+}
+
+func Test_Locate(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	idx, _ := New(11)
+	idx.Add("chr1", randSeq(r, 2000))
+	ref := randSeq(r, 3000)
+	idx.Add("chr2", ref)
+
+	query := append([]byte{}, ref[1200:1300]...)
+	query[50] = "CGTA"[strings.IndexByte("ACGT", query[50])] // one mismatch
+	for _, c := range []struct {
+		query  []byte
+		strand byte
+	}{
+		{query, '+'},
+		{RevComp(query), '-'},
+	} {
+		hits, err := idx.Locate(c.query, nil, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hits) == 0 {
+			t.Fatalf("Locate strand %c expect hits get none", c.strand)
+		}
+		h := hits[0]
+		if h.Ref != "chr2" || h.Strand != c.strand || h.Start != 1200 || h.End != 1300 || h.Errors != 1 {
+			t.Errorf("Locate strand %c expect: chr2 %c 1200 1300 errors:1 get: %v", c.strand, c.strand, h)
+		}
+	}
+
+	hits, _ := idx.Locate(randSeq(r, 100), nil, 2)
+	if len(hits) != 0 {
+		t.Errorf("Locate random query expect: no hits get: %v", hits)
+	}
+}
@@ -1,11 +1,16 @@
 package lib
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"os"
+	"strconv"
+	"strings"
 )
 
+var ErrSize = errors.New("Wrong Size, eg. 1024, 100K, 1.5G")
+
 // Humanity trans number to human readable size
 func Humanity(size int64) string {
 	units := []string{"", "K", "M", "G", "T", "P", "E", "Z"}
@@ -19,6 +24,23 @@ func Humanity(size int64) string {
 	return fmt.Sprintf("%.2f%sB", s, "Y")
 }
 
+// ParseSize parse human readable size to bytes, units K M G T of 1024, B suffix is optional
+func ParseSize(size string) (int64, error) {
+	s := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(size)), "B")
+	scale := 1.0
+	if n := len(s); n > 0 {
+		if i := strings.IndexByte("KMGT", s[n-1]); i >= 0 {
+			scale = math.Pow(1024, float64(i+1))
+			s = s[:n-1]
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("%v: %s", ErrSize, size)
+	}
+	return int64(f * scale), nil
+}
+
 // FileSize get file size
 func FileSize(filename string) string {
 	f, err := os.Open(filename)
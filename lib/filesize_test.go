@@ -18,6 +18,21 @@ func TestHumanity(t *testing.T) {
 	}
 }
 
+func TestParseSize(t *testing.T) {
+	strs := []string{"1", "1K", "4kb", "1.5M", "2G", "100B"}
+	sizes := []int64{1, 1024, 4096, 1572864, 2147483648, 100}
+	for i, str := range strs {
+		if size, err := ParseSize(str); err != nil || size != sizes[i] {
+			t.Errorf("ParseSize %s expect: %v get: %v %v", str, sizes[i], size, err)
+		}
+	}
+	for _, str := range []string{"", "K", "1X", "-1M"} {
+		if _, err := ParseSize(str); err == nil {
+			t.Errorf("ParseSize %q expect: %v get: %v", str, ErrSize, err)
+		}
+	}
+}
+
 func TestFileSize(t *testing.T) {
 	size := 1234567
 	tempfile, err := ioutil.TempFile(os.TempDir(), "test")
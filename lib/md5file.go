@@ -3,20 +3,19 @@ package lib
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"fmt"
+	"errors"
 	"io"
 	"os"
 )
 
-const Md5SumError = "Md5Sum Read bytes not equal Write bytes"
+var ErrMd5Sum = errors.New("Md5Sum Read bytes not equal Write bytes")
 
-func Md5File(filename string) string {
+func Md5File(filename string) (string, error) {
 	digest := md5.New()
-	fmt.Println(os.TempDir())
 
 	f, err := os.Open(filename)
 	if err != nil {
-		return err.Error()
+		return "", err
 	}
 	defer f.Close()
 	buf := make([]byte, 4096)
@@ -25,15 +24,15 @@ func Md5File(filename string) string {
 		if err == io.EOF {
 			break
 		} else if err != nil {
-			return err.Error()
+			return "", err
 		}
 		wn, err := digest.Write(buf[:rn])
 		if err != nil {
-			return err.Error()
+			return "", err
 		}
 		if rn != wn {
-			return Md5SumError
+			return "", ErrMd5Sum
 		}
 	}
-	return hex.EncodeToString(digest.Sum(nil))
+	return hex.EncodeToString(digest.Sum(nil)), nil
 }
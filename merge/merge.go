@@ -0,0 +1,83 @@
+// merge package check lanes of a sample before merged: quality encoding and read
+// number of each lane, and rewrite read names of merged records
+
+package merge
+
+import (
+	"gongs/biofile/fastq"
+	"strconv"
+)
+
+const (
+	PHRED33 = "Phred+33"
+	PHRED64 = "Phred+64"
+	UNKOWN  = "Unkown"
+)
+
+// Lane a lane of single or paired files, reads and quality range counted
+type Lane struct {
+	Filenames []string
+	Reads     int
+	MinQual   byte
+	MaxQual   byte
+}
+
+func NewLane(filenames ...string) *Lane {
+	return &Lane{Filenames: filenames, MinQual: 255}
+}
+
+// Count count reads and quality range of mates of a record
+func (l *Lane) Count(fqs []*fastq.Fastq) {
+	l.Reads++
+	for _, fq := range fqs {
+		for _, q := range fq.Qual {
+			if q < l.MinQual {
+				l.MinQual = q
+			}
+			if q > l.MaxQual {
+				l.MaxQual = q
+			}
+		}
+	}
+}
+
+// Encoding return quality encoding guessed by quality range as qc.Tilestat.GuessEncoding,
+// UNKOWN if can't tell, eg. all bases of high quality
+func (l *Lane) Encoding() string {
+	switch {
+	case l.Reads == 0:
+		return UNKOWN
+	case l.MinQual < 59:
+		return PHRED33
+	case l.MinQual >= 64 && l.MaxQual > 74:
+		return PHRED64
+	}
+	return UNKOWN
+}
+
+// Renamer rewrite read names of merged records
+type Renamer struct {
+	Strip    bool   // strip comment after read id
+	Sample   string // add sample prefix to read id, as sample_id
+	Renumber bool   // replace read id by the record number from 1
+	records  int
+}
+
+// Rename rewrite names of mates of a record in the same way
+func (r *Renamer) Rename(fqs []*fastq.Fastq) {
+	r.records++
+	for _, fq := range fqs {
+		id := fq.Id()
+		comment := fq.Name[len(id):]
+		if r.Strip {
+			comment = ""
+		}
+		if r.Renumber {
+			id = strconv.Itoa(r.records) + id[len(fq.MateId()):] // keep /1 /2
+		}
+		if r.Sample != "" {
+			id = r.Sample + "_" + id
+		}
+		fq.Name = id + comment
+	}
+}
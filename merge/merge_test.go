@@ -0,0 +1,57 @@
+package merge
+
+import (
+	"gongs/biofile/fastq"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	for _, c := range []struct {
+		quals  []string
+		expect string
+	}{
+		{nil, UNKOWN},
+		{[]string{"#III"}, PHRED33},
+		{[]string{"IIII", "II:I"}, PHRED33}, // 58, the last of Phred+33 not Solexa
+		{[]string{"IIII"}, UNKOWN},          // 40 of Phred+33 or 9 of Phred+64
+		{[]string{"@hhh"}, PHRED64},
+		{[]string{"BJJJ"}, UNKOWN}, // max 74 can't tell
+		{[]string{";hhh"}, UNKOWN}, // Solexa+64
+	} {
+		l := NewLane("test.fq")
+		for _, qual := range c.quals {
+			l.Count([]*fastq.Fastq{{Name: "r", Qual: []byte(qual)}})
+		}
+		if e := l.Encoding(); e != c.expect {
+			t.Errorf("Encoding of %v expect: %v get: %v", c.quals, c.expect, e)
+		}
+	}
+
+	l := NewLane("r1.fq", "r2.fq")
+	l.Count([]*fastq.Fastq{{Qual: []byte("II")}, {Qual: []byte("#J")}})
+	if l.Reads != 1 || l.MinQual != '#' || l.MaxQual != 'J' {
+		t.Errorf("Count expect: %v get: %v %c %c", "1 # J", l.Reads, l.MinQual, l.MaxQual)
+	}
+}
+
+func TestRenamer(t *testing.T) {
+	names := []string{"M1:1:FC1:1:1101:1:1 1:N:0:ACGT", "M1:1:FC1:1:1101:1:1 2:N:0:ACGT"}
+	old := []string{"HWI-1:1:1:1:1/1", "HWI-1:1:1:1:1/2"}
+	for _, c := range []struct {
+		r      *Renamer
+		names  []string
+		expect []string
+	}{
+		{&Renamer{}, names, names},
+		{&Renamer{Strip: true}, names, []string{"M1:1:FC1:1:1101:1:1", "M1:1:FC1:1:1101:1:1"}},
+		{&Renamer{Sample: "S1"}, names, []string{"S1_M1:1:FC1:1:1101:1:1 1:N:0:ACGT", "S1_M1:1:FC1:1:1101:1:1 2:N:0:ACGT"}},
+		{&Renamer{Renumber: true, records: 9}, names, []string{"10 1:N:0:ACGT", "10 2:N:0:ACGT"}},
+		{&Renamer{Renumber: true, Sample: "S1", Strip: true}, old, []string{"S1_1/1", "S1_1/2"}},
+	} {
+		fqs := []*fastq.Fastq{{Name: c.names[0]}, {Name: c.names[1]}}
+		c.r.Rename(fqs)
+		if fqs[0].Name != c.expect[0] || fqs[1].Name != c.expect[1] {
+			t.Errorf("Rename %+v expect: %v get: %v %v", c.r, c.expect, fqs[0].Name, fqs[1].Name)
+		}
+	}
+}
@@ -1,69 +1,155 @@
 package qc
 
+import (
+	"fmt"
+	"gongs/xopen"
+	"strconv"
+	"strings"
+)
+
 type Base struct {
-	stat map[byte]int // A,C,G,T,N
-	pos  map[int]map[byte]int
+	pos []counts // bases count by position
 }
 
 func NewBase() *Base {
-	return &Base{
-		stat: make(map[byte]int),
-		pos:  make(map[int]map[byte]int),
-	}
+	return &Base{}
 }
 
 func (b *Base) Count(seq string) {
-	for i, nt := range []byte(seq) {
-		b.stat[nt]++
-		mm, ok := b.pos[i]
-		if !ok {
-			mm = make(map[byte]int)
-			b.pos[i] = mm
-		}
-		mm[nt]++
+	b.pos = grow(b.pos, len(seq))
+	for i := 0; i < len(seq); i++ {
+		b.pos[i][seq[i]]++
+	}
+}
+
+// CountBytes same as Count, avoid converting []byte to string
+func (b *Base) CountBytes(seq []byte) {
+	b.pos = grow(b.pos, len(seq))
+	for i, nt := range seq {
+		b.pos[i][nt]++
 	}
 }
 
+// stat return bases count of all positions
+func (b *Base) stat() *counts {
+	return sumCounts(b.pos)
+}
+
 func (b *Base) GC() float64 {
-	gc := b.G() + b.C()
-	tot := b.A() + b.T() + gc
+	c := b.stat()
+	gc := c['G'] + c['g'] + c['C'] + c['c']
+	tot := c['A'] + c['a'] + c['T'] + c['t'] + gc
+	if tot == 0 {
+		return 0
+	}
 	return float64(gc*100) / float64(tot)
 }
 
 func (b *Base) Total() int {
-	tot := 0
-	for key, val := range b.stat {
-		if key == 'A' || key == 'a' || key == 'C' || key == 'c' || key == 'G' || key == 'g' || key == 'T' || key == 't' {
-			tot += val
-		}
+	c := b.stat()
+	tot := uint64(0)
+	for _, nt := range []byte("ACGTacgt") {
+		tot += c[nt]
 	}
-	return tot
+	return int(tot)
 }
 
 func (b *Base) TotalAll() int {
-	tot := 0
-	for _, val := range b.stat {
-		tot += val
-	}
-	return tot
+	return int(b.stat().total())
+}
+
+// count return count of base nt of all positions, both upper and lower case
+func (b *Base) count(nt byte) int {
+	c := b.stat()
+	return int(c[nt] + c[nt+'a'-'A'])
 }
 
 func (b *Base) A() int {
-	return b.stat['A'] + b.stat['a']
+	return b.count('A')
 }
 
 func (b *Base) C() int {
-	return b.stat['C'] + b.stat['c']
+	return b.count('C')
 }
 
 func (b *Base) G() int {
-	return b.stat['G'] + b.stat['g']
+	return b.count('G')
 }
 
 func (b *Base) T() int {
-	return b.stat['T'] + b.stat['t']
+	return b.count('T')
 }
 
 func (b *Base) N() int {
-	return b.stat['N'] + b.stat['n']
+	return b.count('N')
+}
+
+// Len return the number of positions counted, the max read length
+func (b *Base) Len() int {
+	return len(b.pos)
+}
+
+// At return count of base nt (both upper and lower case) at position pos
+func (b *Base) At(pos int, nt byte) int {
+	if pos < 0 || pos >= len(b.pos) {
+		return 0
+	}
+	if nt >= 'a' && nt <= 'z' {
+		nt -= 'a' - 'A'
+	}
+	return int(b.pos[pos][nt] + b.pos[pos][nt+'a'-'A'])
+}
+
+// TotalAt return all bases count at position pos
+func (b *Base) TotalAt(pos int) int {
+	if pos < 0 || pos >= len(b.pos) {
+		return 0
+	}
+	return int(b.pos[pos].total())
+}
+
+// Percent return percent of base nt at position pos
+func (b *Base) Percent(pos int, nt byte) float64 {
+	tot := b.TotalAt(pos)
+	if tot == 0 {
+		return 0
+	}
+	return float64(b.At(pos, nt)*100) / float64(tot)
+}
+
+// NContent return N content by position of b
+func (b *Base) NContent() *NContent {
+	return &NContent{base: b}
+}
+
+// SaveBaseStat save base content percent by position
+func (b *Base) SaveBaseStat(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".basestat", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// print header line
+	header := []string{"cycle", "A", "C", "G", "T", "N", "GC"}
+	fmt.Fprintln(f, "##", strings.Join(header, "\t"))
+
+	// print data line
+	for i, l := 0, b.Len(); i < l; i++ {
+		result := []string{strconv.Itoa(i + 1)}
+		for _, nt := range []byte("ACGTN") {
+			result = append(result, fmt.Sprintf("%.2f", b.Percent(i, nt)))
+		}
+		result = append(result, fmt.Sprintf("%.2f", b.Percent(i, 'G')+b.Percent(i, 'C')))
+		fmt.Fprintln(f, strings.Join(result, "\t"))
+	}
+	return nil
+}
+
+// Merge add counts of other into b
+func (b *Base) Merge(other *Base) {
+	b.pos = grow(b.pos, len(other.pos))
+	for i := range other.pos {
+		b.pos[i].add(&other.pos[i])
+	}
 }
@@ -0,0 +1,156 @@
+package qc
+
+import (
+	"gongs/biofile/fastq"
+	"gongs/stat"
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+// mapBase mapQual mapCycles are the map based collectors before dense counts, kept to compare with
+type mapBase struct {
+	stat map[byte]int
+	pos  map[int]map[byte]int
+}
+
+func (b *mapBase) Count(seq string) {
+	for i, nt := range []byte(seq) {
+		b.stat[nt]++
+		mm, ok := b.pos[i]
+		if !ok {
+			mm = make(map[byte]int)
+			b.pos[i] = mm
+		}
+		mm[nt]++
+	}
+}
+
+type mapCycles struct {
+	quals  map[int]int
+	cycles map[int]*stat.IntMap
+}
+
+func (c *mapCycles) Count(qual []byte) {
+	for i, q := range qual {
+		c.quals[int(q)]++
+		m, ok := c.cycles[i]
+		if !ok {
+			m = stat.NewIntMap(make(map[int]int))
+			c.cycles[i] = m
+		}
+		m.Data[int(q)]++
+	}
+}
+
+func benchFastqs(n, length int) []*fastq.Fastq {
+	r := rand.New(rand.NewSource(1))
+	fqs := make([]*fastq.Fastq, n)
+	for i := range fqs {
+		seq := make([]byte, length)
+		qual := make([]byte, length)
+		for j := range seq {
+			seq[j] = "ACGTN"[r.Intn(5)]
+			qual[j] = byte(33 + r.Intn(42))
+		}
+		name := "M1:1:FC1:1:" + strconv.Itoa(1101+i%4) + ":" + strconv.Itoa(i) + ":1"
+		fqs[i] = &fastq.Fastq{Name: name, Seq: seq, Qual: qual}
+	}
+	return fqs
+}
+
+func Test_DenseCompat(t *testing.T) {
+	fqs := benchFastqs(500, 150)
+	base := NewBase()
+	tile := NewTile()
+	mbase := &mapBase{stat: make(map[byte]int), pos: make(map[int]map[byte]int)}
+	mcycles := &mapCycles{quals: make(map[int]int), cycles: make(map[int]*stat.IntMap)}
+	for _, fq := range fqs {
+		base.CountBytes(fq.Seq)
+		tile.Count(fq)
+		mbase.Count(string(fq.Seq))
+		mcycles.Count(fq.Qual)
+	}
+
+	if base.A() != mbase.stat['A'] || base.N() != mbase.stat['N'] {
+		t.Errorf("Base A/N expect: %v/%v get: %v/%v", mbase.stat['A'], mbase.stat['N'], base.A(), base.N())
+	}
+	gc := float64((mbase.stat['G']+mbase.stat['C'])*100) / float64(mbase.stat['A']+mbase.stat['C']+mbase.stat['G']+mbase.stat['T'])
+	if math.Abs(base.GC()-gc) > 1e-9 {
+		t.Errorf("Base GC expect: %v get: %v", gc, base.GC())
+	}
+	if base.At(10, 'G') != mbase.pos[10]['G'] {
+		t.Errorf("Base At expect: %v get: %v", mbase.pos[10]['G'], base.At(10, 'G'))
+	}
+
+	for i := 0; i < 150; i++ {
+		c := &tile.qualByCycle[i]
+		for _, p := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+			if expect := mcycles.cycles[i].Percentile(p); c.percentile(p) != expect {
+				t.Errorf("cycle %d percentile %v expect: %v get: %v", i, p, expect, c.percentile(p))
+			}
+		}
+	}
+
+	tot, q20 := 0, 0
+	for q, n := range mcycles.quals {
+		tot += n
+		if q-33 >= 20 {
+			q20 += n
+		}
+	}
+	if expect := float64(q20*100) / float64(tot); tile.Q20() != expect {
+		t.Errorf("Q20 expect: %v get: %v", expect, tile.Q20())
+	}
+}
+
+func BenchmarkBaseMap(b *testing.B) {
+	fqs := benchFastqs(1000, 150)
+	base := &mapBase{stat: make(map[byte]int), pos: make(map[int]map[byte]int)}
+	b.SetBytes(150)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		base.Count(string(fqs[i%len(fqs)].Seq))
+	}
+}
+
+func BenchmarkBaseDense(b *testing.B) {
+	fqs := benchFastqs(1000, 150)
+	base := NewBase()
+	b.SetBytes(150)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		base.CountBytes(fqs[i%len(fqs)].Seq)
+	}
+}
+
+func BenchmarkCycleMap(b *testing.B) {
+	fqs := benchFastqs(1000, 150)
+	c := &mapCycles{quals: make(map[int]int), cycles: make(map[int]*stat.IntMap)}
+	b.SetBytes(150)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Count(fqs[i%len(fqs)].Qual)
+	}
+}
+
+func BenchmarkTileDense(b *testing.B) {
+	fqs := benchFastqs(1000, 150)
+	tile := NewTile()
+	b.SetBytes(150)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tile.Count(fqs[i%len(fqs)])
+	}
+}
+
+func BenchmarkReport(b *testing.B) {
+	fqs := benchFastqs(1000, 150)
+	r := NewReport()
+	b.SetBytes(150)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Count(fqs[i%len(fqs)])
+	}
+}
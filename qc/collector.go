@@ -0,0 +1,222 @@
+// per read collectors count biofile.Seqer, work on fasta as well as fastq,
+// Seqstat and N content of Base are built on them
+
+package qc
+
+import (
+	"fmt"
+	"gongs/biofile"
+	"gongs/stat"
+	"gongs/xopen"
+	"math"
+	"strings"
+)
+
+// Collector count a fasta or fastq record
+type Collector interface {
+	Count(biofile.Seqer)
+}
+
+// gcPercent return GC percent of A, C, G, T bases rounded to int, false if seq without ACGT
+func gcPercent(seq []byte) (int, bool) {
+	gc, at := 0, 0
+	for _, nt := range seq {
+		switch nt {
+		case 'G', 'g', 'C', 'c':
+			gc++
+		case 'A', 'a', 'T', 't':
+			at++
+		}
+	}
+	if gc+at == 0 {
+		return 0, false
+	}
+	return int(math.Floor(float64(gc*100)/float64(gc+at) + 0.5)), true
+}
+
+// gcNormal return theoretical normal distribution of GC percent (0-100) fitted
+// by mean and sd of counts, and percent of reads deviate from it
+func gcNormal(counts map[int]int) ([]float64, float64) {
+	theory := make([]float64, 101)
+	m := stat.NewIntMap(counts)
+	n := m.Items()
+	if n == 0 {
+		return theory, 0
+	}
+	mean := m.Mean()
+	variance := 0.0
+	for gc, count := range counts {
+		variance += float64(count) * (float64(gc) - mean) * (float64(gc) - mean)
+	}
+	sd := math.Sqrt(variance / float64(n))
+
+	deviation := 0.0
+	for gc := range theory {
+		if sd == 0 {
+			if gc == int(mean+0.5) {
+				theory[gc] = float64(n)
+			}
+		} else {
+			z := (float64(gc) - mean) / sd
+			theory[gc] = float64(n) * math.Exp(-z*z/2) / (sd * math.Sqrt(2*math.Pi))
+		}
+		deviation += math.Abs(float64(counts[gc]) - theory[gc])
+	}
+	return theory, deviation * 100 / float64(n)
+}
+
+// ****************************** GCDist **************************************
+
+// GCDist per read GC percent distribution, reads without ACGT skipped
+type GCDist struct {
+	counts map[int]int
+}
+
+func NewGCDist() *GCDist {
+	return &GCDist{counts: make(map[int]int)}
+}
+
+func (g *GCDist) Count(s biofile.Seqer) {
+	g.CountBytes(s.GetSeq())
+}
+
+// CountBytes count GC percent of seq
+func (g *GCDist) CountBytes(seq []byte) {
+	if gc, ok := gcPercent(seq); ok {
+		g.counts[gc]++
+	}
+}
+
+// Dist return GC percent distribution
+func (g *GCDist) Dist() *stat.IntMap {
+	return stat.NewIntMap(g.counts)
+}
+
+// Normal return theoretical normal distribution of GC percent (0-100) fitted by
+// mean and sd of observed distribution, and percent of reads deviate from it
+func (g *GCDist) Normal() ([]float64, float64) {
+	return gcNormal(g.counts)
+}
+
+// Save save GC percent distribution with the theoretical normal distribution
+func (g *GCDist) Save(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".gcdist", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	theory, deviation := g.Normal()
+	fmt.Fprintf(f, "#: deviation: %.2f\n", deviation)
+	fmt.Fprintln(f, "##", strings.Join([]string{"gc", "count", "theoretical"}, "\t"))
+	for gc, t := range theory {
+		fmt.Fprintf(f, "%d\t%d\t%.1f\n", gc, g.counts[gc], t)
+	}
+	return nil
+}
+
+func (g *GCDist) Merge(other *GCDist) {
+	for gc, count := range other.counts {
+		g.counts[gc] += count
+	}
+}
+
+// ****************************** LenDist *************************************
+
+// LenDist read length distribution
+type LenDist struct {
+	counts map[int]int
+}
+
+func NewLenDist() *LenDist {
+	return &LenDist{counts: make(map[int]int)}
+}
+
+func (l *LenDist) Count(s biofile.Seqer) {
+	l.CountBytes(s.GetSeq())
+}
+
+// CountBytes count length of seq
+func (l *LenDist) CountBytes(seq []byte) {
+	l.counts[len(seq)]++
+}
+
+// Dist return read length distribution
+func (l *LenDist) Dist() *stat.IntMap {
+	return stat.NewIntMap(l.counts)
+}
+
+// Save save read length distribution
+func (l *LenDist) Save(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".lendist", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "##", strings.Join([]string{"length", "count"}, "\t"))
+	m := l.Dist()
+	for _, length := range m.Keys() {
+		fmt.Fprintf(f, "%d\t%d\n", length, m.Data[length])
+	}
+	return nil
+}
+
+func (l *LenDist) Merge(other *LenDist) {
+	for length, count := range other.counts {
+		l.counts[length] += count
+	}
+}
+
+// ****************************** NContent ************************************
+
+// NContent N base percent by position, a view of Base count
+type NContent struct {
+	base *Base
+}
+
+func NewNContent() *NContent {
+	return &NContent{base: NewBase()}
+}
+
+func (c *NContent) Count(s biofile.Seqer) {
+	c.base.CountBytes(s.GetSeq())
+}
+
+// Len return the number of positions counted, the max read length
+func (c *NContent) Len() int {
+	return c.base.Len()
+}
+
+// Percent return N percent at position pos, both upper and lower case
+func (c *NContent) Percent(pos int) float64 {
+	return c.base.Percent(pos, 'N')
+}
+
+// Max return the max N percent of all positions
+func (c *NContent) Max() float64 {
+	n := 0.0
+	for i, l := 0, c.Len(); i < l; i++ {
+		n = math.Max(n, c.Percent(i))
+	}
+	return n
+}
+
+// Save save N percent by position
+func (c *NContent) Save(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".ncontent", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "##", strings.Join([]string{"cycle", "N"}, "\t"))
+	for i, l := 0, c.Len(); i < l; i++ {
+		fmt.Fprintf(f, "%d\t%.2f\n", i+1, c.Percent(i))
+	}
+	return nil
+}
+
+func (c *NContent) Merge(other *NContent) {
+	c.base.Merge(other.base)
+}
@@ -0,0 +1,60 @@
+package qc
+
+import (
+	"gongs/biofile"
+	"gongs/biofile/fasta"
+	"gongs/biofile/fastq"
+	"testing"
+)
+
+func TestCollectors(t *testing.T) {
+	records := []biofile.Seqer{
+		&fasta.Fasta{Name: "a", Seq: []byte("ACGTACGTNN")},
+		&fastq.Fastq{Name: "b", Seq: []byte("GGGGCCCCAT"), Qual: []byte("IIIIIIIIII")},
+		&fasta.Fasta{Name: "c", Seq: []byte("NNNN")},
+	}
+	gc, length, n := NewGCDist(), NewLenDist(), NewNContent()
+	for _, s := range records {
+		for _, c := range []Collector{gc, length, n} {
+			c.Count(s)
+		}
+	}
+
+	if expect := map[int]int{50: 1, 80: 1}; len(gc.counts) != len(expect) || gc.counts[50] != 1 || gc.counts[80] != 1 {
+		t.Errorf("GCDist expect: %v get: %v", expect, gc.counts)
+	}
+	if _, deviation := gc.Normal(); deviation <= 0 {
+		t.Errorf("GCDist Normal deviation expect: > 0 get: %v", deviation)
+	}
+	if m := length.Dist(); m.Data[10] != 2 || m.Data[4] != 1 {
+		t.Errorf("LenDist expect: %v get: %v", map[int]int{10: 2, 4: 1}, m.Data)
+	}
+	if n.Len() != 10 {
+		t.Errorf("NContent Len expect: %v get: %v", 10, n.Len())
+	}
+	for pos, expect := range map[int]float64{0: 100.0 / 3, 8: 50, 9: 50, 5: 0} {
+		if p := n.Percent(pos); p != expect {
+			t.Errorf("NContent Percent(%d) expect: %v get: %v", pos, expect, p)
+		}
+	}
+
+	// the same as Seqstat and Base counted by Report
+	r := NewReport()
+	for _, s := range records {
+		r.Count(&fastq.Fastq{Name: s.GetName(), Seq: s.GetSeq()})
+	}
+	_, expect := gc.Normal()
+	if _, deviation := r.Seq.GCNormal(); deviation != expect || r.Seq.Lengths().Data[10] != 2 {
+		t.Errorf("Seqstat expect: %v get: %v %v", expect, deviation, r.Seq.Lengths().Data)
+	}
+	if nc := r.Base.NContent(); nc.Len() != n.Len() || nc.Percent(8) != n.Percent(8) || nc.Max() != 50 {
+		t.Errorf("Base NContent expect: %v get: %v %v %v", n.Len(), nc.Len(), nc.Percent(8), nc.Max())
+	}
+
+	other := NewNContent()
+	other.Count(&fasta.Fasta{Name: "d", Seq: []byte("NNNNNNNNNNNN")})
+	n.Merge(other)
+	if n.Len() != 12 || n.Percent(11) != 100 || n.Percent(5) != 100.0/3 {
+		t.Errorf("NContent Merge expect: %v get: %v %v %v", "12 100 33.33", n.Len(), n.Percent(11), n.Percent(5))
+	}
+}
@@ -0,0 +1,134 @@
+// compare qc profiles of runs or lanes by statistical tests, a difference is
+// warned only if both significant and large, tests on millions of bases find
+// any tiny difference significant
+
+package qc
+
+import (
+	"fmt"
+	"gongs/stat"
+	"gongs/xopen"
+	"math"
+	"strings"
+)
+
+const (
+	COMPARE_PVALUE     = 0.001 // p-value threshold of a significant difference
+	COMPARE_CONFIDENCE = 0.95  // confidence level of rate intervals
+	COMPARE_KS_D       = 0.05  // min distance of cumulative distributions
+	COMPARE_CHI_W      = 0.1   // min effect size w = sqrt(chi-square / n), Cohen's small effect
+	COMPARE_RATE       = 1.0   // min difference of rate percent
+)
+
+// Comparison result of a test between a report and its reference
+type Comparison struct {
+	Module    string
+	Statistic float64
+	PValue    float64
+	Effect    float64 // effect size compared with the threshold, meaning depends on the test
+	Warn      bool
+	Message   string
+}
+
+func (c *Comparison) verdict() Verdict {
+	if c.Warn {
+		return WARN
+	}
+	return PASS
+}
+
+func (c *Comparison) String() string {
+	return fmt.Sprintf("%s\t%s\tp=%.3g\t%s", c.verdict(), c.Module, c.PValue, c.Message)
+}
+
+// compareKS test two count distributions by Kolmogorov-Smirnov, nil if any one is empty,
+// offset is removed from medians in message, eg. quality encoding offset
+func compareKS(module string, m, ref *stat.IntMap, offset int) *Comparison {
+	r, err := stat.KSTwoSample(m, ref)
+	if err != nil {
+		return nil
+	}
+	return &Comparison{Module: module, Statistic: r.Statistic, PValue: r.PValue, Effect: r.Statistic,
+		Warn:    r.PValue < COMPARE_PVALUE && r.Statistic >= COMPARE_KS_D,
+		Message: fmt.Sprintf("median %.1f vs %.1f, D %.3f", m.Median()-float64(offset), ref.Median()-float64(offset), r.Statistic)}
+}
+
+// compareBases test ACGT composition of b against ref by chi-square
+func compareBases(b, ref *Base) *Comparison {
+	c, rc := b.stat(), ref.stat()
+	observed, expected := make([]float64, 4), make([]float64, 4)
+	n := 0.0
+	for i, nt := range []byte("ACGT") {
+		observed[i] = float64(c[nt] + c[nt+'a'-'A'])
+		expected[i] = float64(rc[nt] + rc[nt+'a'-'A'])
+		n += observed[i]
+	}
+	r, err := stat.ChiSquareGOF(observed, expected)
+	if err != nil {
+		return nil
+	}
+	w := math.Sqrt(r.Statistic / n)
+	return &Comparison{Module: "Base composition", Statistic: r.Statistic, PValue: r.PValue, Effect: w,
+		Warn:    r.PValue < COMPARE_PVALUE && w >= COMPARE_CHI_W,
+		Message: fmt.Sprintf("GC %.2f%% vs %.2f%%, w %.3f", b.GC(), ref.GC(), w)}
+}
+
+// compareQ30 test Q30 rates by two proportion z-test, intervals by Wilson score
+func compareQ30(t, ref *Tilestat) *Comparison {
+	k1, n1 := t.qCount(30)
+	k2, n2 := ref.qCount(30)
+	if n1 == 0 || n2 == 0 {
+		return nil
+	}
+	p1, p2 := float64(k1)/float64(n1), float64(k2)/float64(n2)
+	p := float64(k1+k2) / float64(n1+n2)
+	se := math.Sqrt(p * (1 - p) * (1/float64(n1) + 1/float64(n2)))
+	z, pvalue := 0.0, 1.0
+	if se > 0 {
+		z = (p1 - p2) / se
+		pvalue = math.Erfc(math.Abs(z) / math.Sqrt2)
+	}
+	lo1, hi1 := stat.BinomialCI(k1, n1, COMPARE_CONFIDENCE)
+	lo2, hi2 := stat.BinomialCI(k2, n2, COMPARE_CONFIDENCE)
+	diff := math.Abs(p1-p2) * 100
+	return &Comparison{Module: "Q30", Statistic: z, PValue: pvalue, Effect: diff,
+		Warn: pvalue < COMPARE_PVALUE && diff >= COMPARE_RATE && (hi1 < lo2 || hi2 < lo1),
+		Message: fmt.Sprintf("%.2f%% [%.2f-%.2f] vs %.2f%% [%.2f-%.2f]",
+			p1*100, lo1*100, hi1*100, p2*100, lo2*100, hi2*100)}
+}
+
+// Compare test r against a reference report, eg. another run or lane of the same library,
+// modules without data in either report are skipped
+func (r *Report) Compare(ref *Report) []*Comparison {
+	comps := []*Comparison{
+		compareBases(r.Base, ref.Base),
+		compareKS("Base quality", r.Tile.quals().intMap(), ref.Tile.quals().intMap(), r.Tile.Offset()),
+		compareQ30(r.Tile, ref.Tile),
+		compareKS("Sequence quality", r.Seq.Quals(), ref.Seq.Quals(), r.Tile.Offset()),
+		compareKS("Sequence GC", r.Seq.GCs(), ref.Seq.GCs(), 0),
+		compareKS("Sequence length", r.Seq.Lengths(), ref.Seq.Lengths(), 0),
+	}
+	results := []*Comparison{}
+	for _, c := range comps {
+		if c != nil {
+			results = append(results, c)
+		}
+	}
+	return results
+}
+
+// SaveCompare save comparisons of r against ref
+func (r *Report) SaveCompare(prefix string, ref *Report) error {
+	f, err := xopen.Xcreate(prefix+".compare", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "#: pvalue: %g\n", COMPARE_PVALUE)
+	fmt.Fprintln(f, "##", strings.Join([]string{"module", "statistic", "pvalue", "effect", "verdict", "message"}, "\t"))
+	for _, c := range r.Compare(ref) {
+		fmt.Fprintf(f, "%s\t%.4g\t%.4g\t%.4g\t%s\t%s\n", c.Module, c.Statistic, c.PValue, c.Effect, c.verdict(), c.Message)
+	}
+	return nil
+}
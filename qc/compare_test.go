@@ -0,0 +1,34 @@
+package qc
+
+import (
+	"gongs/biofile/fastq"
+	"strings"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	newReport := func(qual byte, n int) *Report {
+		r := NewReport()
+		for i := 0; i < n; i++ {
+			seq := []byte(strings.Repeat("ACGT", 10))
+			r.Count(&fastq.Fastq{Name: "M1:1:FC1:1:1101:0:0", Seq: seq, Qual: []byte(strings.Repeat(string(qual), len(seq)))})
+		}
+		return r
+	}
+	good, bad := newReport('I', 1000), newReport('5', 1000)
+
+	for _, c := range good.Compare(newReport('I', 500)) {
+		if c.Warn {
+			t.Errorf("Compare same expect: %v get: %v", "no warning", c)
+		}
+	}
+	warns := map[string]bool{}
+	for _, c := range bad.Compare(good) {
+		warns[c.Module] = c.Warn
+	}
+	for module, expect := range map[string]bool{"Base composition": false, "Base quality": true, "Q30": true, "Sequence quality": true, "Sequence length": false} {
+		if warns[module] != expect {
+			t.Errorf("Compare %s warn expect: %v get: %v", module, expect, warns[module])
+		}
+	}
+}
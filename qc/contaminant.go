@@ -0,0 +1,130 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/align"
+	"gongs/scan"
+	"gongs/xopen"
+	"strings"
+)
+
+const (
+	NO_HIT          = "No Hit"
+	CONTAM_MIN_LEN  = 20  // min aligned length of a contaminant hit, whole sequence or contaminant if shorter
+	CONTAM_ERR_RATE = 0.1 // max errors / aligned length of a contaminant hit
+)
+
+// Contaminant known sequence may occur in reads, eg. adapter, primer
+type Contaminant struct {
+	Name string
+	Seq  string
+}
+
+// Contaminants built-in contaminant list: common Illumina adapters and primers, PhiX fragments
+var Contaminants = []*Contaminant{
+	{"Illumina Single End Adapter 1", "GATCGGAAGAGCTCGTATGCCGTCTTCTGCTTG"},
+	{"Illumina Single End Adapter 2", "CAAGCAGAAGACGGCATACGAGCTCTTCCGATCT"},
+	{"Illumina Single End PCR Primer 1", "AATGATACGGCGACCACCGAGATCTACACTCTTTCCCTACACGACGCTCTTCCGATCT"},
+	{"Illumina Single End Sequencing Primer", "ACACTCTTTCCCTACACGACGCTCTTCCGATCT"},
+	{"Illumina Paired End Adapter 2", "GATCGGAAGAGCGGTTCAGCAGGAATGCCGAG"},
+	{"Illumina Paired End PCR Primer 2", "CAAGCAGAAGACGGCATACGAGATCGGTCTCGGCATTCCTGCTGAACCGCTCTTCCGATCT"},
+	{"Illumina Paired End Sequencing Primer 2", "CGGTCTCGGCATTCCTGCTGAACCGCTCTTCCGATCT"},
+	{"Illumina Multiplexing PCR Primer 2.01", "GTGACTGGAGTTCAGACGTGTGCTCTTCCGATCT"},
+	{"Illumina Multiplexing Index Sequencing Primer", "GATCGGAAGAGCACACGTCTGAACTCCAGTCAC"},
+	{"TruSeq Adapter, Index 1", "GATCGGAAGAGCACACGTCTGAACTCCAGTCACATCACGATCTCGTATGCCGTCTTCTGCTTG"},
+	{"TruSeq Small RNA 3' Adapter", "TGGAATTCTCGGGTGCCAAGG"},
+	{"Illumina Small RNA 5' Adapter", "GTTCAGAGTTCTACAGTCCGACGATC"},
+	{"Nextera Transposase Read 1", "TCGTCGGCAGCGTCAGATGTGTATAAGAGACAG"},
+	{"Nextera Transposase Read 2", "GTCTCGTGGGCTCGGAGATGTGTATAAGAGACAG"},
+	{"Nextera Adapter", "CTGTCTCTTATACACATCT"},
+	{"PhiX174 Fragment 1", "GAGTTTTATCGCTTCCATGACGCAGAAGTTAACACTTTCGGATATTTCTGATGAGTCGAAAAATTATCTTGATAAAGCAGGAATTACTACTGCTTGTTTACGAATTAAATCGAAGTGGACTGCTGGCGGAAAATGAGAAAATTCGACCTATCCTTGCGCAGCTCGAGAAGCTCTTACTTTGCGACCTTTCGCCATCAACTAACGATTCTGTCAAAAACTGACGCGTTGGATGAGGAGAAGTGGCTTAATATGCTTGGCACGTTCGTCAAGGACTGG"},
+	{"Poly A", "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+	{"Poly G", "GGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGGG"},
+}
+
+// LoadContaminants load contaminants of FastQC contaminant list format:
+// name and sequence separated by tabs each line, # line is comment line
+func LoadContaminants(filename string) ([]*Contaminant, error) {
+	f, err := xopen.Xopen(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	conts := []*Contaminant{}
+	s := scan.New(f)
+	for s.Scan() {
+		text := strings.TrimSpace(s.Line())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(text, '\t')
+		if i < 0 {
+			return nil, fmt.Errorf("Unkown contaminant line %d of %s: %s", s.Lid(), filename, text)
+		}
+		conts = append(conts, &Contaminant{Name: strings.TrimSpace(text[:i]), Seq: strings.ToUpper(text[i+1:])})
+	}
+	return conts, s.Err()
+}
+
+var contamComplement = strings.NewReplacer("A", "T", "T", "A", "C", "G", "G", "C", "a", "t", "t", "a", "c", "g", "g", "c")
+
+// revComp return reverse complement of seq, bases other than ACGT are kept
+func revComp(seq string) string {
+	rc := []byte(contamComplement.Replace(seq))
+	for i, j := 0, len(rc)-1; i < j; i, j = i+1, j-1 {
+		rc[i], rc[j] = rc[j], rc[i]
+	}
+	return string(rc)
+}
+
+// Annotator find the contaminant source of sequences
+type Annotator struct {
+	conts    []*Contaminant
+	aligners []align.Aligner
+}
+
+func NewAnnotator(conts []*Contaminant) (*Annotator, error) {
+	conf := align.DefaultConfig()
+	conf.Wild = align.WILD_NONE
+	conf.ErrorRate = CONTAM_ERR_RATE
+	a := &Annotator{conts: conts, aligners: make([]align.Aligner, len(conts))}
+	for i, c := range conts {
+		aligner, err := align.New("local", c.Seq, conf)
+		if err != nil {
+			return nil, fmt.Errorf("Contaminant %s: %v", c.Name, err)
+		}
+		a.aligners[i] = aligner
+	}
+	return a, nil
+}
+
+// Annotate return the contaminant aligned most bases to seq or its reverse complement,
+// as FastQC: name (identity% over length bp), NO_HIT if none aligned
+func (a *Annotator) Annotate(seq string) string {
+	source, best := NO_HIT, 0
+	for _, s := range []string{seq, revComp(seq)} {
+		for i, aligner := range a.aligners {
+			ar := aligner.Align(s)
+			length := ar.Tend - ar.Tstart
+			if length < min(CONTAM_MIN_LEN, len(seq), len(a.conts[i].Seq)) || ar.Matchs <= best {
+				continue
+			}
+			best = ar.Matchs
+			source = fmt.Sprintf("%s (%.0f%% over %dbp)", a.conts[i].Name, float64(ar.Matchs*100)/float64(length), length)
+		}
+	}
+	return source
+}
+
+// Contains return the first contaminant contains seq in either strand, NO_HIT if none,
+// for short sequences eg. k-mers
+func (a *Annotator) Contains(seq string) string {
+	rc := revComp(seq)
+	for _, c := range a.conts {
+		if strings.Contains(c.Seq, seq) || strings.Contains(c.Seq, rc) {
+			return c.Name
+		}
+	}
+	return NO_HIT
+}
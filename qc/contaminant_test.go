@@ -0,0 +1,70 @@
+package qc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadContaminants(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "contaminants.txt")
+	data := "# comment\n\nAdapter A\t\tGATCGGAAGAGCacacg\r\nPoly T\tTTTTTTTTTTTTTTTTTTTT\n"
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	conts, err := LoadContaminants(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conts) != 2 || *conts[0] != (Contaminant{"Adapter A", "GATCGGAAGAGCACACG"}) || conts[1].Name != "Poly T" {
+		t.Errorf("LoadContaminants expect: %v get: %v", "Adapter A and Poly T", conts)
+	}
+
+	if err := os.WriteFile(filename, []byte("Adapter A\tACGT\nno tab\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadContaminants(filename); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("LoadContaminants expect: %v get: %v", "error of line 2", err)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	a, err := NewAnnotator(Contaminants)
+	if err != nil {
+		t.Fatal(err)
+	}
+	adapter := "GTTCAGAGTTCTACAGTCCGACGATC" // Illumina Small RNA 5' Adapter
+	for _, c := range []struct {
+		seq    string
+		expect string
+	}{
+		{"TTAGGCATCGATTACGGCTA" + adapter + "AAAAAAAAAA", "Illumina Small RNA 5' Adapter (100% over 26bp)"},
+		{revComp("TTAGGCATCGATTACGGCTA" + adapter), "Illumina Small RNA 5' Adapter (100% over 26bp)"},
+		{"TTAGGCATCGATTACGGCTA" + strings.Replace(adapter, "CTAC", "CAAC", 1), "Illumina Small RNA 5' Adapter (96% over 26bp)"},
+		{"TTAGGCATCGATTACGGCTAACCTTAGCAGT", NO_HIT},
+		// contaminant shorter than CONTAM_MIN_LEN, also the end of Nextera Transposase Read 1 in reverse complement
+		{"TTAGGCATCGATTACGGCTA" + "CTGTCTCTTATACACATCT", "Nextera Adapter (100% over 19bp)"},
+	} {
+		if get := a.Annotate(c.seq); get != c.expect {
+			t.Errorf("Annotate(%s) expect: %v get: %v", c.seq, c.expect, get)
+		}
+	}
+
+	for _, c := range []struct {
+		seq    string
+		expect string
+	}{
+		{"TATACAC", "Nextera Transposase Read 1"},
+		{revComp("CATCTCCGAGCC"), "Nextera Transposase Read 2"},
+		{"ACGTACGTACGT", NO_HIT},
+	} {
+		if get := a.Contains(c.seq); get != c.expect {
+			t.Errorf("Contains(%s) expect: %v get: %v", c.seq, c.expect, get)
+		}
+	}
+
+	if rc := revComp("ACGTNacgg"); rc != "ccgtNACGT" {
+		t.Errorf("revComp expect: %v get: %v", "ccgtNACGT", rc)
+	}
+}
@@ -0,0 +1,136 @@
+package qc
+
+import (
+	"gongs/stat"
+	"math"
+)
+
+// counts count of each byte value, eg. bases or qualities at a read position,
+// dense array instead of map, no hashing when counting
+type counts [256]uint64
+
+func (c *counts) total() uint64 {
+	var n uint64
+	for _, v := range c {
+		n += v
+	}
+	return n
+}
+
+// sum return sum of value * count
+func (c *counts) sum() uint64 {
+	var s uint64
+	for v, n := range c {
+		s += uint64(v) * n
+	}
+	return s
+}
+
+func (c *counts) mean() float64 {
+	n := c.total()
+	if n == 0 {
+		return 0
+	}
+	return float64(c.sum()) / float64(n)
+}
+
+// min return the min value counted, -1 if nothing counted
+func (c *counts) min() int {
+	for v, n := range c {
+		if n > 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// max return the max value counted, -1 if nothing counted
+func (c *counts) max() int {
+	for v := len(c) - 1; v >= 0; v-- {
+		if c[v] > 0 {
+			return v
+		}
+	}
+	return -1
+}
+
+// at return the value of rank i (start from 0) in sorted values
+func (c *counts) at(i uint64) int {
+	var n uint64
+	for v, count := range c {
+		n += count
+		if n > i {
+			return v
+		}
+	}
+	return 0
+}
+
+// percentile interpolate between the closest ranks, same as stat.IntMap.Percentile
+func (c *counts) percentile(p float64) float64 {
+	n := c.total()
+	if n == 0 {
+		return 0
+	}
+	k := float64(n-1) * p
+	f := math.Floor(k)
+	ce := math.Ceil(k)
+	if f == ce {
+		return float64(c.at(uint64(k)))
+	}
+	d0 := float64(c.at(uint64(f))) * (ce - k)
+	d1 := float64(c.at(uint64(ce))) * (k - f)
+	return d0 + d1
+}
+
+func (c *counts) add(other *counts) {
+	for v, n := range other {
+		c[v] += n
+	}
+}
+
+// toMap return not zero counts as map, key is the value
+func (c *counts) toMap() map[int]int {
+	m := make(map[int]int)
+	for v, n := range c {
+		if n > 0 {
+			m[v] = int(n)
+		}
+	}
+	return m
+}
+
+// fromMap add counts of map, keys out of [0, 255] are ignored
+func (c *counts) fromMap(m map[int]int) {
+	for v, n := range m {
+		if v >= 0 && v < len(c) {
+			c[v] += uint64(n)
+		}
+	}
+}
+
+func (c *counts) intMap() *stat.IntMap {
+	return stat.NewIntMap(c.toMap())
+}
+
+// summary return five-number summary, mean and sd
+func (c *counts) summary() *stat.Summary {
+	return stat.Counts[int](c.toMap()).Summary()
+}
+
+// grow return cs with at least n counts
+func grow(cs []counts, n int) []counts {
+	if n > len(cs) {
+		cs = append(cs, make([]counts, n-len(cs))...)
+	}
+	return cs
+}
+
+// sumCounts return counts sum of all positions
+func sumCounts(cs []counts) *counts {
+	c := &counts{}
+	for i := range cs {
+		c.add(&cs[i])
+	}
+	return c
+}
@@ -0,0 +1,270 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/stat"
+	"gongs/xopen"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	DUP_LIMIT    = 100000 // max unique sequences tracked
+	DUP_TRIM     = 50     // sequences longer than DUP_TRIM_MIN are truncated to DUP_TRIM
+	DUP_TRIM_MIN = 75
+)
+
+// duplication level bins: level i count sequences occur [DupLevels[i], DupLevels[i+1]) times
+var DupLevels = []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 50, 100, 500, 1000, 5000, 10000}
+
+const HLL_PRECISION = 14 // 16KB registers, about 0.8% standard error
+
+// Dup count sequence duplication as FastQC, only the first DUP_LIMIT unique
+// sequences are tracked, reads after the limit only count tracked sequences,
+// duplication of untracked sequences is extrapolated from the tracked.
+// With HyperLogLog, distinct sequences of all reads are estimated in fixed memory
+type Dup struct {
+	counts  map[string]int // occurrence of tracked sequences
+	total   int            // reads number
+	tracked int            // reads number of tracked sequences
+	limit   int            // reads number when DUP_LIMIT unique sequences reached, equal total if not reached
+	hll     *stat.HLL      // distinct sequences estimation of all reads, nil if not used
+	key     []byte         // pair key buffer
+}
+
+// levelName return name of duplication level, eg. 1, 2, >10
+func levelName(level int) string {
+	if level >= 10 {
+		return fmt.Sprintf(">%d", level)
+	}
+	return fmt.Sprintf("%d", level)
+}
+
+func NewDup() *Dup {
+	return &Dup{counts: make(map[string]int)}
+}
+
+// NewDupHLL return Dup estimate percent unique of all reads by HyperLogLog
+func NewDupHLL() *Dup {
+	d := NewDup()
+	d.hll, _ = stat.NewHLL(HLL_PRECISION)
+	return d
+}
+
+// empty return an empty Dup of the same mode
+func (d *Dup) empty() *Dup {
+	if d.hll != nil {
+		return NewDupHLL()
+	}
+	return NewDup()
+}
+
+// HLL return if distinct sequences are estimated by HyperLogLog
+func (d *Dup) HLL() bool {
+	return d.hll != nil
+}
+
+// trimDup truncate sequence longer than DUP_TRIM_MIN to DUP_TRIM
+func trimDup(seq []byte) []byte {
+	if len(seq) > DUP_TRIM_MIN {
+		return seq[:DUP_TRIM]
+	}
+	return seq
+}
+
+func (d *Dup) Count(seq []byte) {
+	d.count(trimDup(seq))
+}
+
+// CountPair count pair duplication, a pair is duplicated only if prefixes of both mates are the same
+func (d *Dup) CountPair(seq1, seq2 []byte) {
+	d.key = append(append(append(d.key[:0], trimDup(seq1)...), '+'), trimDup(seq2)...)
+	d.count(d.key)
+}
+
+func (d *Dup) count(key []byte) {
+	d.total++
+	if d.hll != nil {
+		d.hll.Add(key)
+	}
+	if len(d.counts) < DUP_LIMIT {
+		d.limit = d.total
+	}
+	if _, ok := d.counts[string(key)]; ok {
+		d.counts[string(key)]++
+	} else if len(d.counts) < DUP_LIMIT {
+		d.counts[string(key)] = 1
+	} else {
+		return
+	}
+	d.tracked++
+}
+
+// Reads return reads number counted
+func (d *Dup) Reads() int {
+	return d.total
+}
+
+// corrected return distinct sequences estimated of n sequences observed count times, as FastQC:
+// a sequence of count occurrences untracked if it not seen in the first limit reads,
+// n is divided by the probability of seen
+func (d *Dup) corrected(count, n int) float64 {
+	if d.limit >= d.total || d.total-n < d.limit {
+		return float64(n)
+	}
+	notSeen := 1.0
+	careless := 1 - float64(n)/(float64(n)+0.01) // probability too small to change the result
+	for i := 0; i < d.limit; i++ {
+		notSeen *= float64(d.total-i-count) / float64(d.total-i)
+		if notSeen < careless {
+			notSeen = 0
+			break
+		}
+	}
+	return float64(n) / (1 - notSeen)
+}
+
+// levels return estimated distinct sequences and reads in each level of DupLevels
+func (d *Dup) levels() ([]float64, []float64) {
+	seqs := make([]float64, len(DupLevels))
+	reads := make([]float64, len(DupLevels))
+	collated := make(map[int]int) // count: sequences number
+	for _, count := range d.counts {
+		collated[count]++
+	}
+	for count, n := range collated {
+		i := sort.SearchInts(DupLevels, count+1) - 1
+		c := d.corrected(count, n)
+		seqs[i] += c
+		reads[i] += c * float64(count)
+	}
+	return seqs, reads
+}
+
+func sumFloats(fs []float64) float64 {
+	sum := 0.0
+	for _, f := range fs {
+		sum += f
+	}
+	return sum
+}
+
+// Levels return percent of distinct sequences and percent of reads in each level of DupLevels
+func (d *Dup) Levels() ([]float64, []float64) {
+	seqs, reads := d.levels()
+	if len(d.counts) == 0 {
+		return seqs, reads
+	}
+	nseq, nread := sumFloats(seqs), sumFloats(reads)
+	for i := range seqs {
+		seqs[i] = seqs[i] * 100 / nseq
+		reads[i] = reads[i] * 100 / nread
+	}
+	return seqs, reads
+}
+
+// Distinct return estimated distinct sequences of all reads, by HyperLogLog if used
+func (d *Dup) Distinct() float64 {
+	if d.hll != nil {
+		return math.Min(d.hll.Estimate(), float64(d.total))
+	}
+	return float64(d.total) * d.Remaining() / 100
+}
+
+// Remaining return percent of reads remaining if deduplicated
+func (d *Dup) Remaining() float64 {
+	if d.tracked == 0 {
+		return 100
+	}
+	if d.hll != nil {
+		return d.Distinct() * 100 / float64(d.total)
+	}
+	seqs, reads := d.levels()
+	return sumFloats(seqs) * 100 / sumFloats(reads)
+}
+
+// Overseq overrepresented sequence
+type Overseq struct {
+	Seq     string
+	Count   int
+	Percent float64 // percent of all reads
+	Source  string  // possible source of sequence
+}
+
+// Overrepresented return sequences occur more than rate percent of all reads, most first
+func (d *Dup) Overrepresented(rate float64) []*Overseq {
+	seqs := []*Overseq{}
+	for seq, count := range d.counts {
+		if percent := float64(count*100) / float64(d.total); percent > rate {
+			seqs = append(seqs, &Overseq{Seq: seq, Count: count, Percent: percent, Source: NO_HIT})
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool {
+		if seqs[i].Count != seqs[j].Count {
+			return seqs[i].Count > seqs[j].Count
+		}
+		return seqs[i].Seq < seqs[j].Seq
+	})
+	return seqs
+}
+
+// SaveDupStat save sequence duplication levels
+func (d *Dup) SaveDupStat(prefix string) error {
+	return d.saveLevels(prefix + ".dupstat")
+}
+
+// SavePairDup save pair duplication levels
+func (d *Dup) SavePairDup(prefix string) error {
+	return d.saveLevels(prefix + ".pairdup")
+}
+
+func (d *Dup) saveLevels(filename string) error {
+	f, err := xopen.Xcreate(filename, "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if d.hll != nil {
+		fmt.Fprintln(f, "#: mode: hll")
+	} else {
+		fmt.Fprintln(f, "#: mode: fastqc")
+	}
+	fmt.Fprintf(f, "#: distinct: %.0f\n", d.Distinct())
+	fmt.Fprintf(f, "#: remaining: %.2f\n", d.Remaining())
+	fmt.Fprintln(f, "##", strings.Join([]string{"level", "deduplicated", "total"}, "\t"))
+	seqs, reads := d.Levels()
+	for i, level := range DupLevels {
+		fmt.Fprintf(f, "%s\t%.2f\t%.2f\n", levelName(level), seqs[i], reads[i])
+	}
+	return nil
+}
+
+// Merge add counts of other into d, sequences not tracked by d are added until DUP_LIMIT reached,
+// limit is summed as reads tracked before limit, approximately as counted in one pass
+func (d *Dup) Merge(other *Dup) {
+	if d.total == 0 && d.hll == nil && other.hll != nil {
+		d.hll, _ = stat.NewHLL(other.hll.Precision())
+	}
+	if d.hll != nil {
+		if other.hll == nil || d.hll.Merge(other.hll) != nil {
+			d.hll = nil // part of reads not estimated
+		}
+	}
+
+	if len(d.counts) < DUP_LIMIT {
+		d.limit += other.limit
+	}
+	d.total += other.total
+	for seq, count := range other.counts {
+		if _, ok := d.counts[seq]; ok {
+			d.counts[seq] += count
+		} else if len(d.counts) < DUP_LIMIT {
+			d.counts[seq] = count
+		} else {
+			continue
+		}
+		d.tracked += count
+	}
+}
@@ -6,38 +6,49 @@ import (
 )
 
 type FileInfo struct {
-	Md5  string
-	Size string
-	Name string
+	Md5  string `json:"md5"`
+	Size string `json:"size"`
+	Name string `json:"name"`
 }
 
-func Stat(filename string) *FileInfo {
+// Stat return size and md5 of file, both are empty of stdin "-" which can't be read twice
+func Stat(filename string) (*FileInfo, error) {
 	name := filepath.Base(filename)
+	if filename == "-" {
+		return &FileInfo{Name: name}, nil
+	}
 	sizech := make(chan string)
-	md5ch := make(chan string)
+	md5ch := make(chan error)
+	var md5sum string
 	go func(ch chan string, filename string) {
 		ch <- lib.FileSize(filename)
 	}(sizech, filename)
-	go func(ch chan string, filename string) {
-		ch <- lib.Md5File(filename)
+	go func(ch chan error, filename string) {
+		var err error
+		md5sum, err = lib.Md5File(filename)
+		ch <- err
 	}(md5ch, filename)
 
-	var fsize, md5sum string
+	var fsize string
+	var err error
 	for {
 		select {
 		case fsize = <-sizech:
 			sizech = nil
-		case md5sum = <-md5ch:
+		case err = <-md5ch:
 			md5ch = nil
 		}
 		if sizech == nil && md5ch == nil {
 			break
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	return &FileInfo{
 		Name: name,
 		Size: fsize,
 		Md5:  md5sum,
-	}
+	}, nil
 }
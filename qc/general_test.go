@@ -0,0 +1,26 @@
+package qc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStat(t *testing.T) {
+	fi, err := Stat("-")
+	if err != nil || fi.Name != "-" || fi.Md5 != "" || fi.Size != "" {
+		t.Errorf("Stat stdin expect: %v get: %v %v", "- with empty md5 and size", fi, err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "test.fq")
+	if err := os.WriteFile(filename, []byte("@r\nACGT\n+\nIIII\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fi, err = Stat(filename)
+	if err != nil || fi.Name != "test.fq" || fi.Md5 != "6f952306024120c74b2d750637241e24" || fi.Size != "15.00B" {
+		t.Errorf("Stat expect: %v get: %v %v", "test.fq 15.00B", fi, err)
+	}
+	if _, err := Stat(filepath.Join(t.TempDir(), "none.fq")); err == nil {
+		t.Errorf("Stat not exist file expect: %v get: %v", "error", err)
+	}
+}
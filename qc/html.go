@@ -0,0 +1,331 @@
+// self-contained html report: inline svg charts and css, no javascript,
+// a single file can be sent by email
+
+package qc
+
+import (
+	"fmt"
+	"gongs/xopen"
+	"html/template"
+	"math"
+)
+
+const (
+	chartWidth  = 800
+	chartHeight = 320
+)
+
+var baseColors = map[byte]string{'A': "#2ca02c", 'C': "#1f77b4", 'G': "#333333", 'T': "#d62728", 'N': "#999999"}
+
+// qualBoxplot per cycle boxplot of quality: whiskers 10%-90%, box 25%-75%, red median and blue mean
+func (r *Report) qualBoxplot() template.HTML {
+	n := len(r.Tile.qualByCycle)
+	offset := float64(r.Tile.Offset())
+	ymax := math.Max(40, float64(r.Tile.MaxQual())-offset+2)
+	p := newPlot(chartWidth, chartHeight, 0.5, float64(n)+0.5, 0, ymax)
+
+	// background of good, reasonable and poor quality
+	p.rect(0.5, 28, float64(n)+0.5, ymax, "fill:#e6f5e6")
+	p.rect(0.5, 20, float64(n)+0.5, 28, "fill:#f8f0dc")
+	p.rect(0.5, 0, float64(n)+0.5, 20, "fill:#f8e0e0")
+
+	xs := make([]float64, n)
+	means := make([]float64, n)
+	for i := 0; i < n; i++ {
+		s := r.Tile.qualByCycle[i].summary()
+		x := float64(i + 1)
+		p.line(x, s.P10-offset, x, s.P90-offset, "stroke:#333;stroke-width:1")
+		p.rect(x-0.3, s.Q1-offset, x+0.3, s.Q3-offset, "fill:#f0e442;stroke:#333;stroke-width:0.5")
+		p.line(x-0.3, s.Median-offset, x+0.3, s.Median-offset, "stroke:#d62728;stroke-width:1.5")
+		xs[i], means[i] = x, s.Mean-offset
+	}
+	p.polyline(xs, means, "#1f77b4")
+	p.axes("Position in read (bp)", "Quality")
+	return p.svg()
+}
+
+// tileHeatmap median quality of each tile by cycle, red is low and green is high
+func (r *Report) tileHeatmap() template.HTML {
+	rows := r.Tile.tileRows()
+	if len(rows) == 0 {
+		return ""
+	}
+	length := 0
+	qmin, qmax := math.MaxFloat64, 0.0
+	for _, row := range rows {
+		if len(row.medians) > length {
+			length = len(row.medians)
+		}
+		for _, m := range row.medians {
+			if m > 0 {
+				qmin, qmax = math.Min(qmin, m), math.Max(qmax, m)
+			}
+		}
+	}
+
+	cell := math.Max(4, math.Min(14, 600/float64(len(rows))))
+	p := newPlot(chartWidth, float64(len(rows))*cell+65, 0.5, float64(length)+0.5, 0, float64(len(rows)))
+	p.left = 110
+	labelEvery := int(math.Ceil(12 / cell)) // keep tile labels from overlapping
+	for i, row := range rows {
+		y := float64(len(rows) - i)
+		for j, m := range row.medians {
+			color := "#cccccc"
+			if m > 0 {
+				hue := 120.0
+				if qmax > qmin {
+					hue = 120 * (m - qmin) / (qmax - qmin)
+				}
+				color = fmt.Sprintf("hsl(%.0f,70%%,50%%)", hue)
+			}
+			p.rect(float64(j)+0.5, y-1, float64(j)+1.5, y, "fill:"+color)
+		}
+		if i%labelEvery == 0 {
+			p.text(p.left-6, p.y(y-0.5)+4, "end", fmt.Sprintf("%s:%d:%d", row.flowid, row.laneid, row.tileid))
+		}
+	}
+	p.axes("Position in read (bp)", "")
+	p.text(p.width-p.right, p.height-8, "end", fmt.Sprintf("median quality %.0f (red) - %.0f (green)",
+		qmin-float64(r.Tile.Offset()), qmax-float64(r.Tile.Offset())))
+	return p.svg()
+}
+
+// seqQualLine distribution of read mean quality
+func (r *Report) seqQualLine() template.HTML {
+	m := r.Seq.Quals()
+	offset := float64(r.Tile.Offset())
+	keys := m.Keys()
+	xs, ys := []float64{}, []float64{}
+	ymax := 0.0
+	for _, q := range keys {
+		xs = append(xs, float64(q)-offset)
+		ys = append(ys, float64(m.Data[q]))
+		ymax = math.Max(ymax, float64(m.Data[q]))
+	}
+	xmin, xmax := 0.0, 40.0
+	if len(keys) > 0 {
+		xmin, xmax = float64(keys[0])-offset-1, float64(keys[len(keys)-1])-offset+1
+	}
+	p := newPlot(chartWidth, chartHeight, xmin, xmax, 0, ymax*1.05)
+	p.polyline(xs, ys, "#d62728")
+	p.axes("Mean sequence quality", "Reads")
+	return p.svg()
+}
+
+// baseContentLines percent of each base by cycle
+func (r *Report) baseContentLines() template.HTML {
+	n := r.Base.Len()
+	p := newPlot(chartWidth, chartHeight, 1, float64(n), 0, 100)
+	xs := make([]float64, n)
+	for i := range xs {
+		xs[i] = float64(i + 1)
+	}
+	names, colors := []string{}, []string{}
+	for _, nt := range []byte("ACGT") {
+		ys := make([]float64, n)
+		for i := range ys {
+			ys[i] = r.Base.Percent(i, nt)
+		}
+		p.polyline(xs, ys, baseColors[nt])
+		names, colors = append(names, string(nt)), append(colors, baseColors[nt])
+	}
+	p.axes("Position in read (bp)", "Percent")
+	p.legend(names, colors)
+	return p.svg()
+}
+
+// nContentLine percent of N by cycle
+func (r *Report) nContentLine() template.HTML {
+	n := r.Base.Len()
+	p := newPlot(chartWidth, chartHeight, 1, float64(n), 0, 100)
+	xs, ys := make([]float64, n), make([]float64, n)
+	for i := range xs {
+		xs[i], ys[i] = float64(i+1), r.Base.Percent(i, 'N')
+	}
+	p.polyline(xs, ys, "#d62728")
+	p.axes("Position in read (bp)", "Percent")
+	return p.svg()
+}
+
+// gcHistogram read GC percent histogram with the theoretical normal distribution
+func (r *Report) gcHistogram() template.HTML {
+	theory, _ := r.Seq.GCNormal()
+	ymax := 0.0
+	for gc, t := range theory {
+		ymax = math.Max(ymax, math.Max(t, float64(r.Seq.gc.counts[gc])))
+	}
+	p := newPlot(chartWidth, chartHeight, -0.5, 100.5, 0, ymax*1.05)
+	xs := make([]float64, len(theory))
+	for gc := range theory {
+		xs[gc] = float64(gc)
+		if count := r.Seq.gc.counts[gc]; count > 0 {
+			p.rect(float64(gc)-0.4, 0, float64(gc)+0.4, float64(count), "fill:#d62728")
+		}
+	}
+	p.polyline(xs, theory, "#1f77b4")
+	p.axes("Mean GC content (%)", "Reads")
+	p.legend([]string{"GC count", "Theoretical"}, []string{"#d62728", "#1f77b4"})
+	return p.svg()
+}
+
+// lengthLine read length distribution
+func (r *Report) lengthLine() template.HTML {
+	m := r.Seq.Lengths()
+	keys := m.Keys()
+	xs, ys := []float64{}, []float64{}
+	ymax := 0.0
+	for _, l := range keys {
+		xs = append(xs, float64(l))
+		ys = append(ys, float64(m.Data[l]))
+		ymax = math.Max(ymax, float64(m.Data[l]))
+	}
+	xmin, xmax := 0.0, 1.0
+	if len(keys) > 0 {
+		xmin, xmax = float64(keys[0])-1, float64(keys[len(keys)-1])+1
+	}
+	if len(keys) == 1 { // single length, show as a peak
+		xs = []float64{xmin, xs[0], xmax}
+		ys = []float64{0, ys[0], 0}
+	}
+	p := newPlot(chartWidth, chartHeight, xmin, xmax, 0, ymax*1.05)
+	p.polyline(xs, ys, "#d62728")
+	p.axes("Sequence length (bp)", "Reads")
+	return p.svg()
+}
+
+// dupLines percent of deduplicated and total reads by duplication level
+func (r *Report) dupLines() template.HTML {
+	seqs, reads := r.Dup.Levels()
+	xs := make([]float64, len(DupLevels))
+	for i := range xs {
+		xs[i] = float64(i + 1)
+	}
+	p := newPlot(chartWidth, chartHeight, 0.5, float64(len(DupLevels))+0.5, 0, 100)
+	for _, level := range DupLevels {
+		p.xnames = append(p.xnames, levelName(level))
+	}
+	p.polyline(xs, seqs, "#d62728")
+	p.polyline(xs, reads, "#1f77b4")
+	p.axes(fmt.Sprintf("Sequence duplication level (%.2f%% remaining if deduplicated)", r.Dup.Remaining()), "Percent")
+	p.legend([]string{"Deduplicated", "Total"}, []string{"#d62728", "#1f77b4"})
+	return p.svg()
+}
+
+type htmlChart struct {
+	Name    string
+	Verdict string
+	SVG     template.HTML
+}
+
+type htmlData struct {
+	Files          []*FileInfo
+	Basic          [][2]string
+	Modules        []Module
+	Charts         []htmlChart
+	Overrep        []*Overseq
+	OverrepVerdict string
+	Kmers          []*KmerHit
+	KmerVerdict    string
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>QC Report</title>
+<style>
+body { font-family: sans-serif; margin: 20px 40px; color: #222; }
+h2 { border-bottom: 1px solid #ccc; padding-bottom: 4px; }
+table { border-collapse: collapse; margin: 10px 0; }
+td, th { border: 1px solid #ddd; padding: 3px 10px; text-align: left; }
+th { background: #f0f0f0; }
+.PASS { color: #fff; background: #2ca02c; }
+.WARN { color: #000; background: #f0c000; }
+.FAIL { color: #fff; background: #d62728; }
+.verdict { padding: 1px 6px; border-radius: 3px; font-size: 80%; }
+.seq { font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>QC Report</h1>
+<h2>Summary</h2>
+<table>
+{{range .Modules}}<tr><td><span class="verdict {{.Verdict}}">{{.Verdict}}</span></td><td><a href="#{{.Name}}">{{.Name}}</a></td></tr>
+{{end}}</table>
+<h2 id="Basic Statistics">Basic Statistics</h2>
+<table>
+<tr><th>File</th><th>Size</th><th>Md5</th></tr>
+{{range .Files}}<tr><td>{{.Name}}</td><td>{{.Size}}</td><td class="seq">{{.Md5}}</td></tr>
+{{end}}</table>
+<table>
+{{range .Basic}}<tr><th>{{index . 0}}</th><td>{{index . 1}}</td></tr>
+{{end}}</table>
+{{range .Charts}}<h2 id="{{.Name}}">{{if .Verdict}}<span class="verdict {{.Verdict}}">{{.Verdict}}</span> {{end}}{{.Name}}</h2>
+{{.SVG}}
+{{end}}<h2 id="Overrepresented sequences"><span class="verdict {{.OverrepVerdict}}">{{.OverrepVerdict}}</span> Overrepresented sequences</h2>
+{{if .Overrep}}<table>
+<tr><th>Sequence</th><th>Count</th><th>Percentage</th><th>Possible Source</th></tr>
+{{range .Overrep}}<tr><td class="seq">{{.Seq}}</td><td>{{.Count}}</td><td>{{printf "%.4f" .Percent}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+{{else}}<p>No overrepresented sequences</p>
+{{end}}<h2 id="Kmer Content"><span class="verdict {{.KmerVerdict}}">{{.KmerVerdict}}</span> Kmer Content</h2>
+{{if .Kmers}}<table>
+<tr><th>Sequence</th><th>Count</th><th>PValue</th><th>Obs/Exp Max</th><th>Max Obs/Exp Position</th><th>Possible Source</th></tr>
+{{range .Kmers}}<tr><td class="seq">{{.Seq}}</td><td>{{.Count}}</td><td>{{printf "%.3g" .Pvalue}}</td><td>{{printf "%.2f" .Ratio}}</td><td>{{.Position}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+{{else}}<p>No enriched k-mers</p>
+{{end}}</body>
+</html>
+`))
+
+// SaveHTML save self-contained html report
+func (r *Report) SaveHTML(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".html", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	modules := r.Summary()
+	verdicts := make(map[string]string)
+	for _, m := range modules {
+		verdicts[m.Name] = m.Verdict.String()
+	}
+	lengths := r.Seq.Lengths()
+	data := &htmlData{
+		Files: r.Files,
+		Basic: [][2]string{
+			{"Encoding", r.Tile.GuessEncoding()},
+			{"Total Sequences", fmt.Sprintf("%d", r.Reads())},
+			{"Total Bases", fmt.Sprintf("%d", r.Base.TotalAll())},
+			{"Sequence length", fmt.Sprintf("%.0f-%.0f", lengths.Percentile(0), lengths.Percentile(1))},
+			{"%GC", fmt.Sprintf("%.2f", r.Base.GC())},
+			{"Q20 (%)", fmt.Sprintf("%.2f", r.Tile.Q20())},
+			{"Q30 (%)", fmt.Sprintf("%.2f", r.Tile.Q30())},
+		},
+		Modules: modules,
+		Overrep: r.Overseqs(),
+		Kmers:   r.Kmers(),
+	}
+	data.OverrepVerdict = verdicts["Overrepresented sequences"]
+	data.KmerVerdict = verdicts["Kmer Content"]
+	charts := []struct {
+		name string
+		draw func() template.HTML
+	}{
+		{"Per base sequence quality", r.qualBoxplot},
+		{"Per tile sequence quality", r.tileHeatmap},
+		{"Per sequence quality scores", r.seqQualLine},
+		{"Per base sequence content", r.baseContentLines},
+		{"Per sequence GC content", r.gcHistogram},
+		{"Per base N content", r.nContentLine},
+		{"Sequence Length Distribution", r.lengthLine},
+		{"Sequence Duplication Levels", r.dupLines},
+	}
+	for _, c := range charts {
+		if svg := c.draw(); svg != "" {
+			data.Charts = append(data.Charts, htmlChart{Name: c.name, Verdict: verdicts[c.name], SVG: svg})
+		}
+	}
+	return htmlTemplate.Execute(f, data)
+}
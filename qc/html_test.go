@@ -0,0 +1,72 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveHTML(t *testing.T) {
+	r := NewReport()
+	r.Files = []*FileInfo{{Name: `<script>alert("x")</script>&.fq`, Size: "1.00KB", Md5: "abc"}}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		seq := make([]byte, 50)
+		qual := make([]byte, 50)
+		for j := range seq {
+			seq[j] = "ACGT"[rng.Intn(4)]
+			qual[j] = byte(33 + 20 + rng.Intn(20))
+		}
+		name := fmt.Sprintf("M1:1:FC1:1:%d:%d:%d 1:N:0:ACGT", 1101+i%2, i, i)
+		r.Count(&fastq.Fastq{Name: name, Seq: seq, Qual: qual})
+	}
+
+	prefix := filepath.Join(t.TempDir(), "test")
+	if err := r.SaveHTML(prefix); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(prefix + ".html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(data)
+
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Errorf("SaveHTML file name expect: %v get: %v", "escaped", "<script> in html")
+	}
+	for _, m := range r.Summary() {
+		row := fmt.Sprintf(`<span class="verdict %s">%s</span></td><td><a href=`, m.Verdict, m.Verdict)
+		if !strings.Contains(html, row) || !strings.Contains(html, fmt.Sprintf(`<h2 id="%s">`, m.Name)) {
+			t.Errorf("SaveHTML module expect: %v get: %v", m, "no summary row or section")
+		}
+		if m.Name == "Basic Statistics" {
+			continue
+		}
+		heading := fmt.Sprintf(`<span class="verdict %s">%s</span> %s</h2>`, m.Verdict, m.Verdict, m.Name)
+		if !strings.Contains(html, heading) {
+			t.Errorf("SaveHTML section expect: %v get: %v", heading, "missing")
+		}
+	}
+	if n := strings.Count(html, "<svg "); n != 8 {
+		t.Errorf("SaveHTML charts expect: %v get: %v", 8, n)
+	}
+}
+
+func TestPlotText(t *testing.T) {
+	p := newPlot(100, 100, 0, 1, 0, 1)
+	p.text(10, 10, "start", `a<b & "c"`)
+	p.axes("x<1", "y&2")
+	svg := string(p.svg())
+	for _, s := range []string{"a&lt;b &amp; &#34;c&#34;", "x&lt;1", "y&amp;2"} {
+		if !strings.Contains(svg, s) {
+			t.Errorf("plot text expect: %v get: %v", s, svg)
+		}
+	}
+	if strings.Contains(svg, "a<b") {
+		t.Errorf("plot text expect: %v get: %v", "escaped", svg)
+	}
+}
@@ -0,0 +1,356 @@
+// json serialization of qc collectors, the schema field is increased when the
+// json layout changes, LoadJSON refuse files of unknown schema
+
+package qc
+
+import (
+	"encoding/json"
+	"fmt"
+	"gongs/stat"
+	"gongs/xopen"
+	"io"
+)
+
+const JSON_SCHEMA = 1
+
+// posData convert counts of positions to maps, json object keys must be text
+func posData(cs []counts) []map[int]int {
+	data := make([]map[int]int, len(cs))
+	for i := range cs {
+		data[i] = cs[i].toMap()
+	}
+	return data
+}
+
+func dataPos(data []map[int]int) []counts {
+	cs := make([]counts, len(data))
+	for i, m := range data {
+		cs[i].fromMap(m)
+	}
+	return cs
+}
+
+// ****************************** Verdict *************************************
+
+func (v Verdict) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *Verdict) UnmarshalText(text []byte) error {
+	for _, vv := range []Verdict{PASS, WARN, FAIL} {
+		if vv.String() == string(text) {
+			*v = vv
+			return nil
+		}
+	}
+	return fmt.Errorf("Unkown verdict: %s", text)
+}
+
+// ****************************** Base ****************************************
+
+type baseJSON struct {
+	Stat map[int]int   `json:"stat"`
+	Pos  []map[int]int `json:"pos"`
+}
+
+func (b *Base) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&baseJSON{Stat: b.stat().toMap(), Pos: posData(b.pos)})
+}
+
+func (b *Base) UnmarshalJSON(data []byte) error {
+	bj := &baseJSON{}
+	if err := json.Unmarshal(data, bj); err != nil {
+		return err
+	}
+	b.pos = dataPos(bj.Pos)
+	return nil
+}
+
+// ****************************** Qual ****************************************
+
+type qualJSON struct {
+	Stat map[int]int   `json:"stat"`
+	Pos  []map[int]int `json:"pos"`
+	Min  int           `json:"min"`
+	Max  int           `json:"max"`
+}
+
+func (q *Qual) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&qualJSON{Stat: sumCounts(q.pos).toMap(), Pos: posData(q.pos), Min: q.Min(), Max: q.Max()})
+}
+
+func (q *Qual) UnmarshalJSON(data []byte) error {
+	qj := &qualJSON{}
+	if err := json.Unmarshal(data, qj); err != nil {
+		return err
+	}
+	q.pos = dataPos(qj.Pos)
+	return nil
+}
+
+// ****************************** Tilestat ************************************
+
+type tileJSON struct {
+	Id     int           `json:"id"`
+	Cycles []map[int]int `json:"cycles"`
+}
+
+type laneJSON struct {
+	Id    int         `json:"id"`
+	Tiles []*tileJSON `json:"tiles"`
+}
+
+type flowcellJSON struct {
+	Id     string      `json:"id"`
+	Length int         `json:"length"`
+	Lanes  []*laneJSON `json:"lanes"`
+}
+
+type tilestatJSON struct {
+	Quals     map[int]int     `json:"quals"`
+	Cycles    []map[int]int   `json:"cycles"`
+	Flowcells []*flowcellJSON `json:"flowcells"`
+	Min       int             `json:"min"`
+	Max       int             `json:"max"`
+}
+
+// cyclesData return quality count of tile cycles in position order
+func cyclesData(cycles [][]uint32) []map[int]int {
+	data := make([]map[int]int, len(cycles))
+	for i, c := range cycles {
+		data[i] = make(map[int]int)
+		for q, n := range c {
+			if n > 0 {
+				data[i][q] = int(n)
+			}
+		}
+	}
+	return data
+}
+
+func dataCycles(data []map[int]int) [][]uint32 {
+	cycles := make([][]uint32, len(data))
+	for i, m := range data {
+		for q, n := range m {
+			if q < 0 || q > 255 {
+				continue
+			}
+			if q >= len(cycles[i]) {
+				cycles[i] = append(cycles[i], make([]uint32, q+1-len(cycles[i]))...)
+			}
+			cycles[i][q] += uint32(n)
+		}
+	}
+	return cycles
+}
+
+func (t *Tilestat) MarshalJSON() ([]byte, error) {
+	byCycle := make([]map[int]int, len(t.qualByCycle))
+	for i := range t.qualByCycle {
+		byCycle[i] = t.qualByCycle[i].toMap()
+	}
+	tj := &tilestatJSON{Quals: t.quals().toMap(), Cycles: byCycle, Min: t.MinQual(), Max: t.MaxQual()}
+	var fj *flowcellJSON
+	var lj *laneJSON
+	for _, row := range t.tileRows() { // rows are sorted by flowcell, lane and tile
+		if fj == nil || fj.Id != row.flowid {
+			fj = &flowcellJSON{Id: row.flowid, Length: t.flowcells[row.flowid].length}
+			tj.Flowcells = append(tj.Flowcells, fj)
+			lj = nil
+		}
+		if lj == nil || lj.Id != row.laneid {
+			lj = &laneJSON{Id: row.laneid}
+			fj.Lanes = append(fj.Lanes, lj)
+		}
+		mtile := t.flowcells[row.flowid].lanes[row.laneid].tiles[row.tileid]
+		lj.Tiles = append(lj.Tiles, &tileJSON{Id: row.tileid, Cycles: cyclesData(mtile.cycles)})
+	}
+	return json.Marshal(tj)
+}
+
+func (t *Tilestat) UnmarshalJSON(data []byte) error {
+	tj := &tilestatJSON{}
+	if err := json.Unmarshal(data, tj); err != nil {
+		return err
+	}
+	*t = *NewTile()
+	t.qualByCycle = make([]cycle, len(tj.Cycles))
+	for i, m := range tj.Cycles {
+		t.qualByCycle[i].fromMap(m)
+	}
+	for _, fj := range tj.Flowcells {
+		mflowcell := &flowcell{id: fj.Id, length: fj.Length, lanes: make(map[int]*lane)}
+		for _, lj := range fj.Lanes {
+			mlane := &lane{id: lj.Id, tiles: make(map[int]*tile)}
+			for _, tj := range lj.Tiles {
+				mlane.tiles[tj.Id] = &tile{id: tj.Id, cycles: dataCycles(tj.Cycles)}
+			}
+			mflowcell.lanes[lj.Id] = mlane
+		}
+		t.flowcells[fj.Id] = mflowcell
+	}
+	return nil
+}
+
+// ****************************** Seqstat *************************************
+
+type seqstatJSON struct {
+	Reads   int         `json:"reads"`
+	Lengths map[int]int `json:"lengths"`
+	GC      map[int]int `json:"gc"`
+	Quals   map[int]int `json:"quals"`
+}
+
+func (s *Seqstat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&seqstatJSON{Reads: s.reads, Lengths: s.lengths.counts, GC: s.gc.counts, Quals: s.quals})
+}
+
+func (s *Seqstat) UnmarshalJSON(data []byte) error {
+	sj := &seqstatJSON{}
+	if err := json.Unmarshal(data, sj); err != nil {
+		return err
+	}
+	*s = *NewSeqstat()
+	s.reads = sj.Reads
+	for _, m := range []struct{ dst, src map[int]int }{{s.lengths.counts, sj.Lengths}, {s.gc.counts, sj.GC}, {s.quals, sj.Quals}} {
+		for key, val := range m.src {
+			m.dst[key] = val
+		}
+	}
+	return nil
+}
+
+// ****************************** Dup *****************************************
+
+type dupJSON struct {
+	Total   int            `json:"total"`
+	Tracked int            `json:"tracked"`
+	Limit   int            `json:"limit"`
+	HLL     *stat.HLL      `json:"hll,omitempty"`
+	Counts  map[string]int `json:"counts"`
+}
+
+func (d *Dup) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&dupJSON{Total: d.total, Tracked: d.tracked, Limit: d.limit, HLL: d.hll, Counts: d.counts})
+}
+
+func (d *Dup) UnmarshalJSON(data []byte) error {
+	dj := &dupJSON{}
+	if err := json.Unmarshal(data, dj); err != nil {
+		return err
+	}
+	*d = *NewDup()
+	d.total, d.tracked, d.limit, d.hll = dj.Total, dj.Tracked, dj.Limit, dj.HLL
+	for seq, count := range dj.Counts {
+		d.counts[seq] = count
+	}
+	return nil
+}
+
+// ****************************** Kmer ****************************************
+
+type kmerJSON struct {
+	Reads int              `json:"reads"`
+	Pos   []map[int]uint32 `json:"pos"` // not zero counts of kmers by position
+}
+
+func (k *Kmer) MarshalJSON() ([]byte, error) {
+	kj := &kmerJSON{Reads: k.reads, Pos: make([]map[int]uint32, len(k.pos))}
+	for i, counts := range k.pos {
+		kj.Pos[i] = make(map[int]uint32)
+		for kmer, count := range counts {
+			if count > 0 {
+				kj.Pos[i][kmer] = count
+			}
+		}
+	}
+	return json.Marshal(kj)
+}
+
+func (k *Kmer) UnmarshalJSON(data []byte) error {
+	kj := &kmerJSON{}
+	if err := json.Unmarshal(data, kj); err != nil {
+		return err
+	}
+	k.reads = kj.Reads
+	k.pos = make([][]uint32, len(kj.Pos))
+	for i, m := range kj.Pos {
+		k.pos[i] = make([]uint32, 1<<(2*KMER_SIZE))
+		for kmer, count := range m {
+			if kmer < 0 || kmer >= len(k.pos[i]) {
+				return fmt.Errorf("Unkown kmer code: %d", kmer)
+			}
+			k.pos[i][kmer] = count
+		}
+	}
+	return nil
+}
+
+// ****************************** Report **************************************
+
+type reportJSON struct {
+	Schema  int         `json:"schema"`
+	Files   []*FileInfo `json:"files"`
+	Untiled int         `json:"untiled"`
+	Summary []Module    `json:"summary"` // not loaded, verdicts are judged again from collectors
+	Base    *Base       `json:"base"`
+	Qual    *Qual       `json:"qual"`
+	Tile    *Tilestat   `json:"tile"`
+	Seq     *Seqstat    `json:"seq"`
+	Dup     *Dup        `json:"dup"`
+	PairDup *Dup        `json:"pair_dup,omitempty"`
+	Kmer    *Kmer       `json:"kmer"`
+}
+
+// WriteJSON write report as json
+func (r *Report) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(&reportJSON{
+		Schema:  JSON_SCHEMA,
+		Files:   r.Files,
+		Untiled: r.Untiled,
+		Summary: r.Summary(),
+		Base:    r.Base,
+		Qual:    r.Qual,
+		Tile:    r.Tile,
+		Seq:     r.Seq,
+		Dup:     r.Dup,
+		PairDup: r.PairDup,
+		Kmer:    r.Kmer,
+	})
+}
+
+// SaveJSON save report as json
+func (r *Report) SaveJSON(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".json", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.WriteJSON(f)
+}
+
+// ReadJSON read report written by WriteJSON
+func ReadJSON(rd io.Reader) (*Report, error) {
+	r := NewReport()
+	rj := &reportJSON{Base: r.Base, Qual: r.Qual, Tile: r.Tile, Seq: r.Seq, Dup: r.Dup, Kmer: r.Kmer}
+	if err := json.NewDecoder(rd).Decode(rj); err != nil {
+		return nil, err
+	}
+	if rj.Schema < 1 || rj.Schema > JSON_SCHEMA {
+		return nil, fmt.Errorf("Unkown qc json schema: %d, support schema <= %d", rj.Schema, JSON_SCHEMA)
+	}
+	r.Files = rj.Files
+	r.Untiled = rj.Untiled
+	r.PairDup = rj.PairDup
+	return r, nil
+}
+
+// LoadJSON load report saved by SaveJSON, gzipped file is supported
+func LoadJSON(filename string) (*Report, error) {
+	f, err := xopen.Xopen(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadJSON(f)
+}
@@ -0,0 +1,72 @@
+package qc
+
+import (
+	"bytes"
+	"fmt"
+	"gongs/biofile/fastq"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// randomReads return n reads of random sequences from 2 tiles, one of every 5 reads
+// is a duplicate of the former read
+func randomReads(n int, seed int64) []*fastq.Fastq {
+	rng := rand.New(rand.NewSource(seed))
+	fqs := make([]*fastq.Fastq, n)
+	for i := range fqs {
+		seq := make([]byte, 60)
+		qual := make([]byte, 60)
+		for j := range seq {
+			seq[j] = "ACGTN"[rng.Intn(5)]
+			qual[j] = byte(33 + 2 + rng.Intn(39))
+		}
+		if i%5 == 4 {
+			copy(seq, fqs[i-1].Seq)
+		}
+		name := fmt.Sprintf("M1:1:FC1:1:%d:%d:%d 1:N:0:ACGT", 1101+i%2, seed, i)
+		fqs[i] = &fastq.Fastq{Name: name, Seq: seq, Qual: qual}
+	}
+	return fqs
+}
+
+func TestJSON(t *testing.T) {
+	r := NewReport()
+	r.EnableHLL()
+	r.Files = []*FileInfo{{Name: "test_1.fq", Size: "1.00KB", Md5: "abc"}}
+	fqs := randomReads(2000, 1)
+	for i := 0; i < len(fqs); i += 2 {
+		r.CountPair(fqs[i], fqs[i+1])
+	}
+	r.Count(&fastq.Fastq{Name: "untiled", Seq: []byte("ACGT"), Qual: []byte("IIII")})
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	saved := buf.String()
+	rr, err := ReadJSON(strings.NewReader(saved))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := rr.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != saved {
+		t.Errorf("JSON round trip expect: %v get: %v", saved, buf.String())
+	}
+	if rr.PairDup == nil || !rr.Dup.HLL() || rr.Untiled != 1 || len(rr.Kmer.pos) == 0 {
+		t.Errorf("JSON fields expect: %v get: %v %v %v %v", "all loaded", rr.PairDup, rr.Dup.HLL(), rr.Untiled, len(rr.Kmer.pos))
+	}
+	if fmt.Sprint(rr.Summary()) != fmt.Sprint(r.Summary()) {
+		t.Errorf("JSON summary expect: %v get: %v", r.Summary(), rr.Summary())
+	}
+
+	for _, schema := range []int{0, JSON_SCHEMA + 1} {
+		bad := strings.Replace(saved, fmt.Sprintf(`"schema":%d`, JSON_SCHEMA), fmt.Sprintf(`"schema":%d`, schema), 1)
+		if _, err := ReadJSON(strings.NewReader(bad)); err == nil {
+			t.Errorf("JSON schema %d expect: %v get: %v", schema, "error", err)
+		}
+	}
+}
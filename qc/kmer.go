@@ -0,0 +1,231 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/xopen"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	KMER_SIZE   = 7
+	KMER_SAMPLE = 50   // count k-mers of 1/KMER_SAMPLE reads, chosen by read name
+	KMER_RATIO  = 5    // min observed / expected at the enriched position
+	KMER_PVALUE = 0.01 // max binomial p-value after bonferroni correction
+	KMER_TOP    = 20   // max enriched k-mers reported
+)
+
+// Kmer count k-mers of each start position bin to find positionally enriched k-mers as FastQC
+type Kmer struct {
+	pos   [][]uint32 // pos[i][kmer] count of 2-bit encoded kmer start in position bin i
+	reads int        // reads sampled
+}
+
+func NewKmer() *Kmer {
+	return &Kmer{}
+}
+
+// kmerSampled return if read of name is sampled, the same read is always sampled whatever the order
+func kmerSampled(name string) bool {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return h.Sum32()%KMER_SAMPLE == 0
+}
+
+var kmerCode = func() [256]int8 {
+	var code [256]int8
+	for i := range code {
+		code[i] = -1
+	}
+	for i, nt := range []byte("ACGT") {
+		code[nt], code[nt+'a'-'A'] = int8(i), int8(i)
+	}
+	return code
+}()
+
+// kmerNextBin return start of the next position bin of the bin start at 0-based position start,
+// the first 9 positions are not binned, then bins widen from 5 by 10% as FastQC groups long reads,
+// so memory of a position bin, 4^KMER_SIZE counts, grows with log of read length
+func kmerNextBin(start int) int {
+	if start < 9 {
+		return start + 1
+	} else if start < 50 {
+		return start + 5
+	}
+	return start + start/10
+}
+
+// kmerBinRange return 1-based first and last position of bin
+func kmerBinRange(bin int) (int, int) {
+	start := 0
+	for i := 0; i < bin; i++ {
+		start = kmerNextBin(start)
+	}
+	return start + 1, kmerNextBin(start)
+}
+
+// Count count k-mers of seq if read name is sampled, k-mers with non ACGT skipped
+func (k *Kmer) Count(name string, seq []byte) {
+	if !kmerSampled(name) {
+		return
+	}
+	k.reads++
+	mask := uint32(1<<(2*KMER_SIZE) - 1)
+	kmer, valid := uint32(0), 0
+	bin, next := 0, kmerNextBin(0)
+	for i, nt := range seq {
+		c := kmerCode[nt]
+		if c < 0 {
+			valid = 0
+			continue
+		}
+		kmer = (kmer<<2 | uint32(c)) & mask
+		if valid++; valid < KMER_SIZE {
+			continue
+		}
+		for i-KMER_SIZE+1 >= next {
+			bin, next = bin+1, kmerNextBin(next)
+		}
+		for bin >= len(k.pos) {
+			k.pos = append(k.pos, make([]uint32, 1<<(2*KMER_SIZE)))
+		}
+		k.pos[bin][kmer]++
+	}
+}
+
+// kmerString decode 2-bit encoded kmer
+func kmerString(kmer uint32) string {
+	s := make([]byte, KMER_SIZE)
+	for i := KMER_SIZE - 1; i >= 0; i-- {
+		s[i] = "ACGT"[kmer&3]
+		kmer >>= 2
+	}
+	return string(s)
+}
+
+// binomialTail return P(X >= k) of X ~ Binomial(n, p), summed in log space from k
+// until terms negligible, so only fast when k is beyond the mean
+func binomialTail(n int, p float64, k int) float64 {
+	if k <= 0 {
+		return 1
+	} else if k > n || p <= 0 {
+		return 0
+	} else if p >= 1 {
+		return 1
+	}
+	lgn, _ := math.Lgamma(float64(n + 1))
+	lp, lq := math.Log(p), math.Log1p(-p)
+	sum := 0.0
+	for i := k; i <= n; i++ {
+		lgi, _ := math.Lgamma(float64(i + 1))
+		lgni, _ := math.Lgamma(float64(n - i + 1))
+		term := math.Exp(lgn - lgi - lgni + float64(i)*lp + float64(n-i)*lq)
+		sum += term
+		if term < sum*1e-12 && float64(i) > float64(n)*p {
+			break
+		}
+	}
+	return math.Min(sum, 1)
+}
+
+// KmerHit positionally enriched k-mer
+type KmerHit struct {
+	Seq    string
+	Count  int     // count of all positions in sampled reads
+	Pvalue float64 // bonferroni corrected binomial p-value at the enriched position
+	Ratio  float64 // observed / expected at the enriched position
+	Pos    int     // first position of the enriched position bin, start from 1
+	End    int     // last position of the enriched position bin
+	Source string  // contaminant contains the k-mer
+}
+
+// Position return the enriched position, or position range of bin, eg. 3, 10-14
+func (h *KmerHit) Position() string {
+	if h.End > h.Pos {
+		return fmt.Sprintf("%d-%d", h.Pos, h.End)
+	}
+	return fmt.Sprintf("%d", h.Pos)
+}
+
+// Enriched return k-mers enriched at a position: observed / expected >= KMER_RATIO and
+// corrected p-value < KMER_PVALUE, at most KMER_TOP k-mers of the smallest p-value
+func (k *Kmer) Enriched() []*KmerHit {
+	nkmer := 1 << (2 * KMER_SIZE)
+	totals := make([]uint64, nkmer) // count of each kmer
+	posTotals := make([]uint64, len(k.pos))
+	var total uint64
+	for i, counts := range k.pos {
+		for kmer, count := range counts {
+			totals[kmer] += uint64(count)
+			posTotals[i] += uint64(count)
+		}
+		total += posTotals[i]
+	}
+
+	hits := []*KmerHit{}
+	for kmer, t := range totals {
+		if t == 0 {
+			continue
+		}
+		p := float64(t) / float64(total)
+		var best *KmerHit
+		for i, counts := range k.pos {
+			obs := counts[kmer]
+			ratio := float64(obs) / (p * float64(posTotals[i]))
+			if obs < 2 || ratio < KMER_RATIO {
+				continue
+			}
+			pvalue := math.Min(binomialTail(int(posTotals[i]), p, int(obs))*float64(nkmer), 1)
+			if best == nil || pvalue < best.Pvalue || (pvalue == best.Pvalue && ratio > best.Ratio) {
+				best = &KmerHit{Seq: kmerString(uint32(kmer)), Count: int(t), Pvalue: pvalue, Ratio: ratio, Pos: i, Source: NO_HIT}
+			}
+		}
+		if best != nil && best.Pvalue < KMER_PVALUE {
+			best.Pos, best.End = kmerBinRange(best.Pos)
+			hits = append(hits, best)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Pvalue != hits[j].Pvalue {
+			return hits[i].Pvalue < hits[j].Pvalue
+		}
+		if hits[i].Ratio != hits[j].Ratio {
+			return hits[i].Ratio > hits[j].Ratio
+		}
+		return hits[i].Seq < hits[j].Seq
+	})
+	if len(hits) > KMER_TOP {
+		hits = hits[:KMER_TOP]
+	}
+	return hits
+}
+
+// SaveKmer save enriched k-mers
+func SaveKmer(prefix string, hits []*KmerHit) error {
+	f, err := xopen.Xcreate(prefix+".kmer", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "##", strings.Join([]string{"sequence", "count", "pvalue", "ratio", "position", "source"}, "\t"))
+	for _, h := range hits {
+		fmt.Fprintf(f, "%s\t%d\t%.3g\t%.2f\t%s\t%s\n", h.Seq, h.Count, h.Pvalue, h.Ratio, h.Position(), h.Source)
+	}
+	return nil
+}
+
+// Merge add counts of other into k
+func (k *Kmer) Merge(other *Kmer) {
+	k.reads += other.reads
+	for i, counts := range other.pos {
+		if i >= len(k.pos) {
+			k.pos = append(k.pos, make([]uint32, len(counts)))
+		}
+		for kmer, count := range counts {
+			k.pos[i][kmer] += count
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package qc
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestBinomialTail(t *testing.T) {
+	for _, c := range []struct {
+		n      int
+		p      float64
+		k      int
+		expect float64
+	}{
+		{10, 0.5, 0, 1},
+		{10, 0.5, 11, 0},
+		{10, 0, 1, 0},
+		{10, 1, 10, 1},
+		{10, 0.5, 8, 0.0546875},
+		{100, 0.01, 5, 0.003432321587754513},
+		{1000, 0.001, 20, 1.3357486992362542e-19},
+	} {
+		if get := binomialTail(c.n, c.p, c.k); math.Abs(get-c.expect) > c.expect*1e-9 {
+			t.Errorf("binomialTail(%d, %v, %d) expect: %v get: %v", c.n, c.p, c.k, c.expect, get)
+		}
+	}
+}
+
+func TestKmerBin(t *testing.T) {
+	for _, c := range []struct {
+		bin        int
+		start, end int
+	}{
+		{0, 1, 1},
+		{8, 9, 9},
+		{9, 10, 14},
+		{17, 50, 54},
+		{18, 55, 59},
+	} {
+		if start, end := kmerBinRange(c.bin); start != c.start || end != c.end {
+			t.Errorf("kmerBinRange(%d) expect: %d-%d get: %d-%d", c.bin, c.start, c.end, start, end)
+		}
+	}
+
+	// memory grows with log of read length
+	k := NewKmer()
+	read := sampledReads(1, 10000)[0]
+	k.Count(read[0], []byte(read[1]))
+	total := 0
+	for _, counts := range k.pos {
+		for _, count := range counts {
+			total += int(count)
+		}
+	}
+	if len(k.pos) > 80 || total != 10000-KMER_SIZE+1 {
+		t.Errorf("Kmer Count expect: %v get: %v bins %v kmers", "< 80 bins", len(k.pos), total)
+	}
+}
+
+func TestEnriched(t *testing.T) {
+	k := NewKmer()
+	for i, read := range sampledReads(400, 150) {
+		seq := []byte(read[1])
+		if i%2 == 0 { // half reads have the k-mer at position 101
+			copy(seq[100:], "ACGGTCA")
+		}
+		k.Count(read[0], seq)
+		k.Count("not sampled", seq)
+	}
+	if k.reads != 400 {
+		t.Errorf("Kmer reads expect: %v get: %v", 400, k.reads)
+	}
+	// k-mers overlapping the inserted are also enriched, but less significant
+	hits := k.Enriched()
+	if len(hits) == 0 {
+		t.Fatalf("Enriched expect: %v get: %v", "hits", len(hits))
+	}
+	h := hits[0]
+	if h.Seq != "ACGGTCA" || h.Position() != "93-101" || h.Count < 200 || h.Ratio < KMER_RATIO || h.Pvalue > 1e-10 {
+		t.Errorf("Enriched expect: %v get: %+v", "ACGGTCA at 93-101", h)
+	}
+
+	// random reads are not enriched
+	k = NewKmer()
+	for _, read := range sampledReads(400, 150) {
+		k.Count(read[0], []byte(read[1]))
+	}
+	if hits := k.Enriched(); len(hits) != 0 {
+		t.Errorf("Enriched expect: %v get: %v", 0, hits[0])
+	}
+}
+
+// sampledReads return name and sequence of n random reads of length l sampled by Kmer
+func sampledReads(n, l int) [][2]string {
+	rng := rand.New(rand.NewSource(1))
+	reads := [][2]string{}
+	for i := 0; len(reads) < n; i++ {
+		name := fmt.Sprintf("r%d", i)
+		if !kmerSampled(name) {
+			continue
+		}
+		seq := make([]byte, l)
+		for j := range seq {
+			seq[j] = "ACGT"[rng.Intn(4)]
+		}
+		reads = append(reads, [2]string{name, string(seq)})
+	}
+	return reads
+}
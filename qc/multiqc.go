@@ -0,0 +1,153 @@
+// MultiQC custom content output, each section is saved to a prefix.xxx_mqc.json
+// file, section ids are fixed so MultiQC merge sections of all samples
+// see https://multiqc.info/docs/custom_content/
+
+package qc
+
+import (
+	"encoding/json"
+	"gongs/xopen"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// mqcSection MultiQC custom content section
+type mqcSection struct {
+	Id          string                            `json:"id"`
+	SectionName string                            `json:"section_name,omitempty"`
+	Description string                            `json:"description,omitempty"`
+	PlotType    string                            `json:"plot_type"`
+	Pconfig     map[string]interface{}            `json:"pconfig,omitempty"`
+	Headers     map[string]map[string]interface{} `json:"headers,omitempty"`
+	Data        map[string]interface{}            `json:"data"`
+}
+
+// lineData convert counts to MultiQC line graph data, x in text
+func lineData(xs []int, ys []float64) map[string]float64 {
+	data := make(map[string]float64, len(xs))
+	for i, x := range xs {
+		data[strconv.Itoa(x)] = ys[i]
+	}
+	return data
+}
+
+func countsData(counts map[int]int, offset int) map[string]float64 {
+	data := make(map[string]float64, len(counts))
+	for key, val := range counts {
+		data[strconv.Itoa(key-offset)] = float64(val)
+	}
+	return data
+}
+
+func lineSection(id, name, desc, xlab, ylab string, sample string, data interface{}) *mqcSection {
+	return &mqcSection{
+		Id:          id,
+		SectionName: name,
+		Description: desc,
+		PlotType:    "linegraph",
+		Pconfig:     map[string]interface{}{"id": id + "_plot", "title": name, "xlab": xlab, "ylab": ylab},
+		Data:        map[string]interface{}{sample: data},
+	}
+}
+
+// mqcSections return MultiQC sections of report, keyed by file suffix
+func (r *Report) mqcSections(sample string) map[string]*mqcSection {
+	offset := r.Tile.Offset()
+	sections := make(map[string]*mqcSection)
+
+	general := map[string]interface{}{
+		"reads":     r.Reads(),
+		"gc":        r.Base.GC(),
+		"q20":       r.Tile.Q20(),
+		"q30":       r.Tile.Q30(),
+		"remaining": r.Dup.Remaining(),
+	}
+	sections["general"] = &mqcSection{
+		Id:       "gongs_general",
+		PlotType: "generalstats",
+		Headers: map[string]map[string]interface{}{
+			"reads":     {"title": "Reads", "description": "Total reads", "format": "{:,.0f}"},
+			"gc":        {"title": "GC", "description": "GC percent", "suffix": "%", "max": 100, "min": 0},
+			"q20":       {"title": "Q20", "description": "Percent of bases >= Q20", "suffix": "%", "max": 100, "min": 0},
+			"q30":       {"title": "Q30", "description": "Percent of bases >= Q30", "suffix": "%", "max": 100, "min": 0},
+			"remaining": {"title": "Unique", "description": "Percent of reads remaining if deduplicated", "suffix": "%", "max": 100, "min": 0},
+		},
+		Data: map[string]interface{}{sample: general},
+	}
+
+	verdicts := make(map[string]string)
+	for _, m := range r.Summary() {
+		verdicts[m.Name] = m.Verdict.String()
+	}
+	sections["summary"] = &mqcSection{
+		Id:          "gongs_summary",
+		SectionName: "gongs QC summary",
+		Description: "PASS/WARN/FAIL verdict of each QC module",
+		PlotType:    "table",
+		Pconfig:     map[string]interface{}{"id": "gongs_summary_table", "title": "gongs QC summary"},
+		Data:        map[string]interface{}{sample: verdicts},
+	}
+
+	cycles, means := []int{}, []float64{}
+	for i, l := 0, len(r.Tile.qualByCycle); i < l; i++ {
+		cycles = append(cycles, i+1)
+		means = append(means, r.Tile.qualByCycle[i].meanQ()-float64(offset))
+	}
+	sections["quality"] = lineSection("gongs_quality", "Per base sequence quality", "Mean quality by position",
+		"Position (bp)", "Quality", sample, lineData(cycles, means))
+
+	sections["seqqual"] = lineSection("gongs_seqqual", "Per sequence quality scores", "Distribution of read mean quality",
+		"Mean quality", "Reads", sample, countsData(r.Seq.quals, offset))
+
+	gcs := make([]int, 101)
+	pcts := make([]float64, 101)
+	reads := float64(r.Seq.GCs().Items())
+	for gc := range gcs {
+		gcs[gc] = gc
+		if reads > 0 {
+			pcts[gc] = float64(r.Seq.gc.counts[gc]) * 100 / reads
+		}
+	}
+	sections["gc"] = lineSection("gongs_gc", "Per sequence GC content", "Percent of reads by GC content",
+		"GC (%)", "Percent of reads", sample, lineData(gcs, pcts))
+
+	positions, ns := []int{}, []float64{}
+	for i, l := 0, r.Base.Len(); i < l; i++ {
+		positions = append(positions, i+1)
+		ns = append(ns, r.Base.Percent(i, 'N'))
+	}
+	sections["ncontent"] = lineSection("gongs_ncontent", "Per base N content", "Percent of N by position",
+		"Position (bp)", "Percent", sample, lineData(positions, ns))
+
+	sections["length"] = lineSection("gongs_length", "Sequence Length Distribution", "Distribution of read length",
+		"Length (bp)", "Reads", sample, countsData(r.Seq.lengths.counts, 0))
+
+	seqs, _ := r.Dup.Levels()
+	dup := lineSection("gongs_dup", "Sequence Duplication Levels",
+		"Percent of deduplicated sequences by duplication level, level x include sequences occur [x, next x) times",
+		"Duplication level", "Percent", sample, lineData(DupLevels, seqs))
+	dup.Pconfig["xlog"] = true
+	sections["dup"] = dup
+	return sections
+}
+
+// SaveMultiQC save MultiQC custom content files prefix.xxx_mqc.json, sample name is the base name of prefix
+func (r *Report) SaveMultiQC(prefix string) error {
+	sample := strings.TrimSuffix(filepath.Base(prefix), ".")
+	for suffix, section := range r.mqcSections(sample) {
+		f, err := xopen.Xcreate(prefix+"."+suffix+"_mqc.json", "w")
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		err = enc.Encode(section)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,42 +1,79 @@
 package qc
 
+import "gongs/stat"
+
 type Qual struct {
-	stat map[byte]int
-	pos  map[int]map[byte]int
-	max  byte
-	min  byte
+	pos []counts // qualities count by position
 }
 
 func NewQual() *Qual {
-	return &Qual{
-		stat: make(map[byte]int),
-		pos:  make(map[int]map[byte]int),
-		min:  127,
-	}
+	return &Qual{}
 }
 
 func (q *Qual) Count(qual string) {
-	for i, qu := range []byte(qual) {
-		if q.max < qu {
-			q.max = qu
-		}
-		if q.min > qu {
-			q.min = qu
-		}
-		q.stat[qu]++
-		mm, ok := q.pos[i]
-		if !ok {
-			mm = make(map[byte]int)
-			q.pos[i] = mm
-		}
-		mm[qu]++
+	q.pos = grow(q.pos, len(qual))
+	for i := 0; i < len(qual); i++ {
+		q.pos[i][qual[i]]++
+	}
+}
+
+// CountBytes same as Count, avoid converting []byte to string
+func (q *Qual) CountBytes(qual []byte) {
+	q.pos = grow(q.pos, len(qual))
+	for i, qu := range qual {
+		q.pos[i][qu]++
 	}
 }
 
+// Max return the max quality, 0 if nothing counted
 func (q *Qual) Max() int {
-	return int(q.max)
+	if max := sumCounts(q.pos).max(); max >= 0 {
+		return max
+	}
+	return 0
 }
 
+// Min return the min quality, 127 if nothing counted
 func (q *Qual) Min() int {
-	return int(q.min)
+	if min := sumCounts(q.pos).min(); min >= 0 {
+		return min
+	}
+	return 127
+}
+
+// Len return the number of positions counted, the max read length
+func (q *Qual) Len() int {
+	return len(q.pos)
+}
+
+// At return quality distribution at position pos
+func (q *Qual) At(pos int) *stat.IntMap {
+	if pos < 0 || pos >= len(q.pos) {
+		return stat.NewIntMap(make(map[int]int))
+	}
+	return q.pos[pos].intMap()
+}
+
+// Percentile return quality percentile at position pos
+func (q *Qual) Percentile(pos int, p float64) float64 {
+	if pos < 0 || pos >= len(q.pos) {
+		return 0
+	}
+	return q.pos[pos].percentile(p)
+}
+
+// Mean return mean quality at position pos
+func (q *Qual) Mean(pos int) float64 {
+	if pos < 0 || pos >= len(q.pos) {
+		return 0
+	}
+	return q.pos[pos].mean()
+}
+
+// Merge add counts of other into q
+func (q *Qual) Merge(other *Qual) {
+	q.pos = grow(q.pos, len(other.pos))
+	for i := range other.pos {
+		q.pos[i].add(&other.pos[i])
+	}
 }
@@ -0,0 +1,346 @@
+// Report drive all qc collectors over fastq reads, judge each module
+// PASS/WARN/FAIL using the FastQC default thresholds
+
+package qc
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"gongs/xopen"
+	"math"
+	"strings"
+)
+
+type Verdict int
+
+const (
+	PASS Verdict = iota
+	WARN
+	FAIL
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case PASS:
+		return "PASS"
+	case WARN:
+		return "WARN"
+	case FAIL:
+		return "FAIL"
+	}
+	return "Unkown"
+}
+
+// judge return FAIL if value beyond fail, WARN if beyond warn, else PASS,
+// larger value is worse if fail > warn
+func judge(value, warn, fail float64) Verdict {
+	if fail > warn {
+		value, warn, fail = -value, -warn, -fail
+	}
+	if value < fail {
+		return FAIL
+	} else if value < warn {
+		return WARN
+	}
+	return PASS
+}
+
+// Module qc module verdict
+type Module struct {
+	Name    string
+	Verdict Verdict
+}
+
+func (m Module) String() string {
+	return fmt.Sprintf("%s\t%s", m.Verdict, m.Name)
+}
+
+const OVERREP_RATE = 0.1 // percent of reads a sequence treated as overrepresented
+
+type Report struct {
+	Files   []*FileInfo
+	Base    *Base
+	Qual    *Qual
+	Tile    *Tilestat
+	Seq     *Seqstat
+	Dup     *Dup
+	PairDup *Dup // pair duplication by prefixes of both mates, nil if reads not counted in pair
+	Kmer    *Kmer
+	Untiled int // reads with name can't split into flowcell, lane and tile
+
+	Annotator *Annotator     // annotate overrepresented sequences and k-mers, built-in Contaminants by default
+	TileTh    *TileThreshold // threshold to flag bad tiles
+}
+
+func NewReport() *Report {
+	return &Report{
+		Base: NewBase(),
+		Qual: NewQual(),
+		Tile: NewTile(),
+		Seq:  NewSeqstat(),
+		Dup:  NewDup(),
+		Kmer: NewKmer(),
+
+		Annotator: builtinAnnotator,
+		TileTh:    DefaultTileThreshold(),
+	}
+}
+
+var builtinAnnotator, _ = NewAnnotator(Contaminants)
+
+// Count count a read by all collectors
+func (r *Report) Count(fq *fastq.Fastq) {
+	r.Base.CountBytes(fq.Seq)
+	r.Qual.CountBytes(fq.Qual)
+	if r.Tile.Count(fq) != nil {
+		r.Untiled++
+	}
+	r.Seq.Count(fq.Seq, fq.Qual)
+	r.Dup.Count(fq.Seq)
+	r.Kmer.Count(fq.Name, fq.Seq)
+}
+
+// CountPair count both mates by all collectors, and the pair duplication
+func (r *Report) CountPair(fq1, fq2 *fastq.Fastq) {
+	r.Count(fq1)
+	r.Count(fq2)
+	if r.PairDup == nil {
+		r.PairDup = r.Dup.empty()
+	}
+	r.PairDup.CountPair(fq1.Seq, fq2.Seq)
+}
+
+// EnableHLL estimate percent unique of all reads by HyperLogLog, must be called before counting
+func (r *Report) EnableHLL() {
+	r.Dup = NewDupHLL()
+	if r.PairDup != nil {
+		r.PairDup = NewDupHLL()
+	}
+}
+
+// Merge add counts of other into r, eg. reports of workers or lanes
+func (r *Report) Merge(other *Report) {
+	r.Files = append(r.Files, other.Files...)
+	r.Untiled += other.Untiled
+	r.Base.Merge(other.Base)
+	r.Qual.Merge(other.Qual)
+	r.Tile.Merge(other.Tile)
+	r.Seq.Merge(other.Seq)
+	r.Dup.Merge(other.Dup)
+	r.Kmer.Merge(other.Kmer)
+	if other.PairDup != nil {
+		if r.PairDup == nil {
+			r.PairDup = other.PairDup.empty()
+		}
+		r.PairDup.Merge(other.PairDup)
+	}
+}
+
+// Reads return reads number counted
+func (r *Report) Reads() int {
+	return r.Seq.Reads()
+}
+
+// BaseQuality judge per base quality: lower quartile < 10 or median < 25 WARN,
+// lower quartile < 5 or median < 20 FAIL
+func (r *Report) BaseQuality() Verdict {
+	offset := float64(r.Tile.Offset())
+	v := PASS
+	for i, l := 0, r.Qual.Len(); i < l; i++ {
+		lower := r.Qual.Percentile(i, 0.25) - offset
+		median := r.Qual.Percentile(i, 0.5) - offset
+		if vv := judge(lower, 10, 5); vv > v {
+			v = vv
+		}
+		if vv := judge(median, 25, 20); vv > v {
+			v = vv
+		}
+	}
+	return v
+}
+
+// TileQuality judge per tile sequence quality: mean quality of any tile cycle lower
+// than its lane by > 5 WARN, > 10 FAIL
+func (r *Report) TileQuality() Verdict {
+	return judge(r.Tile.MaxDrop(), 5, 10)
+}
+
+// SeqQuality judge per sequence quality: most frequent mean quality < 27 WARN, < 20 FAIL
+func (r *Report) SeqQuality() Verdict {
+	if r.Reads() == 0 {
+		return PASS
+	}
+	return judge(float64(r.Seq.ModeQual()-r.Tile.Offset()), 27, 20)
+}
+
+// BaseContent judge per base sequence content: difference between A and T, or G and C
+// at any position > 10% WARN, > 20% FAIL
+func (r *Report) BaseContent() Verdict {
+	diff := 0.0
+	for i, l := 0, r.Base.Len(); i < l; i++ {
+		diff = math.Max(diff, math.Abs(r.Base.Percent(i, 'A')-r.Base.Percent(i, 'T')))
+		diff = math.Max(diff, math.Abs(r.Base.Percent(i, 'G')-r.Base.Percent(i, 'C')))
+	}
+	return judge(diff, 10, 20)
+}
+
+// GCContent judge per sequence GC content: reads deviate from normal distribution > 15% WARN, > 30% FAIL
+func (r *Report) GCContent() Verdict {
+	_, deviation := r.Seq.GCNormal()
+	return judge(deviation, 15, 30)
+}
+
+// NContent judge per base N content: N percent at any position > 5% WARN, > 20% FAIL
+func (r *Report) NContent() Verdict {
+	return judge(r.Base.NContent().Max(), 5, 20)
+}
+
+// LengthDist judge sequence length distribution: reads not in the same length WARN, any read of 0 length FAIL
+func (r *Report) LengthDist() Verdict {
+	lengths := r.Seq.lengths.counts
+	if lengths[0] > 0 {
+		return FAIL
+	} else if len(lengths) > 1 {
+		return WARN
+	}
+	return PASS
+}
+
+// Duplication judge sequence duplication: reads remaining if deduplicated < 80% WARN, < 50% FAIL,
+// pair duplication is judged if counted in pair
+func (r *Report) Duplication() Verdict {
+	if r.PairDup != nil {
+		return judge(r.PairDup.Remaining(), 80, 50)
+	}
+	return judge(r.Dup.Remaining(), 80, 50)
+}
+
+// Overrepresented judge overrepresented sequences: any sequence > 0.1% of reads WARN, > 1% FAIL
+func (r *Report) Overrepresented() Verdict {
+	max := 0.0
+	for _, s := range r.Dup.Overrepresented(OVERREP_RATE) {
+		max = math.Max(max, s.Percent)
+	}
+	return judge(max, OVERREP_RATE, 1)
+}
+
+// Overseqs return overrepresented sequences annotated by contaminants
+func (r *Report) Overseqs() []*Overseq {
+	seqs := r.Dup.Overrepresented(OVERREP_RATE)
+	for _, s := range seqs {
+		s.Source = r.Annotator.Annotate(s.Seq)
+	}
+	return seqs
+}
+
+// Kmers return positionally enriched k-mers annotated by contaminants
+func (r *Report) Kmers() []*KmerHit {
+	hits := r.Kmer.Enriched()
+	for _, h := range hits {
+		h.Source = r.Annotator.Contains(h.Seq)
+	}
+	return hits
+}
+
+// KmerContent judge k-mer content: any k-mer enriched with p-value < 0.01 WARN, < 1e-5 FAIL
+func (r *Report) KmerContent() Verdict {
+	pvalue := 1.0
+	for _, h := range r.Kmer.Enriched() {
+		pvalue = math.Min(pvalue, h.Pvalue)
+	}
+	return judge(-math.Log10(pvalue), 2, 5)
+}
+
+// Summary return verdict of each module
+func (r *Report) Summary() []Module {
+	return []Module{
+		{"Basic Statistics", PASS},
+		{"Per base sequence quality", r.BaseQuality()},
+		{"Per tile sequence quality", r.TileQuality()},
+		{"Per sequence quality scores", r.SeqQuality()},
+		{"Per base sequence content", r.BaseContent()},
+		{"Per sequence GC content", r.GCContent()},
+		{"Per base N content", r.NContent()},
+		{"Sequence Length Distribution", r.LengthDist()},
+		{"Sequence Duplication Levels", r.Duplication()},
+		{"Overrepresented sequences", r.Overrepresented()},
+		{"Kmer Content", r.KmerContent()},
+	}
+}
+
+// SaveSummary save file info, basic statistics and module verdicts
+func (r *Report) SaveSummary(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".summary", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	for _, fi := range r.Files {
+		fmt.Fprintf(f, "#: file: %s\t%s\t%s\n", fi.Name, fi.Size, fi.Md5)
+	}
+	lengths := r.Seq.Lengths()
+	fmt.Fprintln(f, "#: encoding:", r.Tile.GuessEncoding())
+	fmt.Fprintln(f, "#: reads:", r.Reads())
+	fmt.Fprintln(f, "#: bases:", r.Base.TotalAll())
+	fmt.Fprintf(f, "#: length: %.0f-%.0f\n", lengths.Percentile(0), lengths.Percentile(1))
+	fmt.Fprintf(f, "#: gc: %.2f\n", r.Base.GC())
+	fmt.Fprintf(f, "#: q20: %.2f\n", r.Tile.Q20())
+	fmt.Fprintf(f, "#: q30: %.2f\n", r.Tile.Q30())
+	fmt.Fprintln(f, "#: untiled:", r.Untiled)
+	fmt.Fprintf(f, "#: unique: %.2f\n", r.Dup.Remaining())
+	if r.PairDup != nil {
+		fmt.Fprintln(f, "#: pairs:", r.PairDup.Reads())
+		fmt.Fprintf(f, "#: pair unique: %.2f\n", r.PairDup.Remaining())
+	}
+	fmt.Fprintln(f, "##", strings.Join([]string{"verdict", "module"}, "\t"))
+	for _, m := range r.Summary() {
+		fmt.Fprintln(f, m)
+	}
+	return nil
+}
+
+// SaveOverrep save annotated overrepresented sequences occur more than OVERREP_RATE percent of all reads
+func (r *Report) SaveOverrep(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".overrep", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "##", strings.Join([]string{"sequence", "count", "percent", "source"}, "\t"))
+	for _, s := range r.Overseqs() {
+		fmt.Fprintf(f, "%s\t%d\t%.4f\t%s\n", s.Seq, s.Count, s.Percent, s.Source)
+	}
+	return nil
+}
+
+// Save save all qc result files with prefix
+func (r *Report) Save(prefix string) error {
+	savers := []func(string) error{
+		r.Tile.SaveQualDist,
+		r.Tile.SaveCycleStat,
+		r.Tile.SaveTileStat,
+		func(prefix string) error { return r.Tile.SaveTileDev(prefix, r.TileTh) },
+		r.Base.SaveBaseStat,
+		r.Seq.SaveLenDist,
+		r.Seq.SaveGCDist,
+		func(prefix string) error { return r.Seq.SaveQualHist(prefix, r.Tile.Offset()) },
+		r.Dup.SaveDupStat,
+		r.SaveOverrep,
+		func(prefix string) error { return SaveKmer(prefix, r.Kmers()) },
+		r.SaveSummary,
+		r.SaveHTML,
+		r.SaveJSON,
+		r.SaveMultiQC,
+	}
+	if r.PairDup != nil {
+		savers = append(savers, r.PairDup.SavePairDup)
+	}
+	for _, save := range savers {
+		if err := save(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
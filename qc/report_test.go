@@ -0,0 +1,148 @@
+package qc
+
+import (
+	"bytes"
+	"fmt"
+	"gongs/biofile/fastq"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestJudge(t *testing.T) {
+	for _, c := range []struct {
+		value, warn, fail float64
+		expect            Verdict
+	}{
+		// lower is worse, eg. base quality lower quartile < 10 WARN, < 5 FAIL
+		{10, 10, 5, PASS},
+		{9.99, 10, 5, WARN},
+		{5, 10, 5, WARN},
+		{4.99, 10, 5, FAIL},
+		// higher is worse, eg. base content difference > 10 WARN, > 20 FAIL
+		{10, 10, 20, PASS},
+		{10.01, 10, 20, WARN},
+		{20, 10, 20, WARN},
+		{20.01, 10, 20, FAIL},
+	} {
+		if v := judge(c.value, c.warn, c.fail); v != c.expect {
+			t.Errorf("judge(%v, %v, %v) expect: %v get: %v", c.value, c.warn, c.fail, c.expect, v)
+		}
+	}
+}
+
+// qualReport return report of reads all bases of phred quality q, n of reads has N at
+// the first base of 100 reads
+func qualReport(q, n int) *Report {
+	r := NewReport()
+	for i := 0; i < 100; i++ {
+		seq := []byte("ACGTACGTAC")
+		if i < n {
+			seq[0] = 'N'
+		}
+		r.Count(&fastq.Fastq{Name: "r", Seq: seq, Qual: []byte(strings.Repeat(string(rune(33+q)), len(seq)))})
+	}
+	return r
+}
+
+func TestVerdicts(t *testing.T) {
+	for _, c := range []struct {
+		module string
+		q, n   int
+		expect Verdict
+	}{
+		// median < 25 WARN, < 20 FAIL
+		{"BaseQuality", 25, 0, PASS},
+		{"BaseQuality", 24, 0, WARN},
+		{"BaseQuality", 20, 0, WARN},
+		{"BaseQuality", 19, 0, FAIL},
+		// most frequent mean quality < 27 WARN, < 20 FAIL
+		{"SeqQuality", 27, 0, PASS},
+		{"SeqQuality", 26, 0, WARN},
+		{"SeqQuality", 20, 0, WARN},
+		{"SeqQuality", 19, 0, FAIL},
+		// N percent of any position > 5 WARN, > 20 FAIL
+		{"NContent", 30, 5, PASS},
+		{"NContent", 30, 6, WARN},
+		{"NContent", 30, 20, WARN},
+		{"NContent", 30, 21, FAIL},
+	} {
+		r := qualReport(c.q, c.n)
+		verdicts := map[string]func() Verdict{
+			"BaseQuality": r.BaseQuality,
+			"SeqQuality":  r.SeqQuality,
+			"NContent":    r.NContent,
+		}
+		if v := verdicts[c.module](); v != c.expect {
+			t.Errorf("%s q: %d n: %d expect: %v get: %v", c.module, c.q, c.n, c.expect, v)
+		}
+	}
+
+	r := qualReport(30, 0)
+	if v := r.LengthDist(); v != PASS {
+		t.Errorf("LengthDist expect: %v get: %v", PASS, v)
+	}
+	r.Count(&fastq.Fastq{Name: "r", Seq: []byte("ACGT"), Qual: []byte("????")})
+	if v := r.LengthDist(); v != WARN {
+		t.Errorf("LengthDist expect: %v get: %v", WARN, v)
+	}
+	r.Count(&fastq.Fastq{Name: "r"})
+	if v := r.LengthDist(); v != FAIL {
+		t.Errorf("LengthDist expect: %v get: %v", FAIL, v)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	fqs := randomReads(3000, 2)
+	for _, hll := range []bool{false, true} {
+		single := NewReport()
+		shards := []*Report{NewReport(), NewReport(), NewReport()}
+		if hll {
+			single.EnableHLL()
+			for _, r := range shards {
+				r.EnableHLL()
+			}
+		}
+		for i := 0; i < len(fqs); i += 2 {
+			single.CountPair(fqs[i], fqs[i+1])
+			shards[i/2%len(shards)].CountPair(fqs[i], fqs[i+1])
+		}
+		merged := NewReport()
+		for _, r := range shards {
+			merged.Merge(r)
+		}
+
+		var expect, get bytes.Buffer
+		single.WriteJSON(&expect)
+		merged.WriteJSON(&get)
+		if expect.String() != get.String() {
+			t.Errorf("Merge hll: %v expect: %v get: %v", hll, expect.String(), get.String())
+		}
+	}
+
+	// more unique sequences than DUP_LIMIT, merged Dup only approximates the single pass
+	rng := rand.New(rand.NewSource(3))
+	single := NewDup()
+	shards := []*Dup{NewDup(), NewDup(), NewDup()}
+	n := DUP_LIMIT * 2
+	for i := 0; i < n; i++ {
+		seq := []byte(fmt.Sprintf("ACGT%08d", rng.Intn(DUP_LIMIT*3/2)))
+		if i%10 == 0 {
+			seq = []byte("ACGTACGTACGT")
+		}
+		single.Count(seq)
+		shards[i*len(shards)/n].Count(seq)
+	}
+	merged := NewDup()
+	for _, d := range shards {
+		merged.Merge(d)
+	}
+	if merged.Reads() != single.Reads() || math.Abs(merged.Remaining()-single.Remaining()) > 0.5 {
+		t.Errorf("Merge dup expect: %d %.2f get: %d %.2f", single.Reads(), single.Remaining(), merged.Reads(), merged.Remaining())
+	}
+	expect, get := single.Overrepresented(OVERREP_RATE), merged.Overrepresented(OVERREP_RATE)
+	if len(expect) != 1 || len(get) != 1 || *expect[0] != *get[0] {
+		t.Errorf("Merge overrepresented expect: %v get: %v", expect, get)
+	}
+}
@@ -0,0 +1,105 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/stat"
+	"gongs/xopen"
+	"strings"
+)
+
+// Seqstat count per read stat: length, GC percent and mean quality
+type Seqstat struct {
+	reads   int
+	lengths *LenDist    // read length distribution
+	gc      *GCDist     // read GC percent distribution, reads without ACGT skipped
+	quals   map[int]int // read mean quality distribution, encoding offset not removed
+}
+
+func NewSeqstat() *Seqstat {
+	return &Seqstat{
+		lengths: NewLenDist(),
+		gc:      NewGCDist(),
+		quals:   make(map[int]int),
+	}
+}
+
+func (s *Seqstat) Count(seq, qual []byte) {
+	s.reads++
+	s.lengths.CountBytes(seq)
+	s.gc.CountBytes(seq)
+
+	if len(qual) > 0 {
+		sum := 0
+		for _, q := range qual {
+			sum += int(q)
+		}
+		s.quals[sum/len(qual)]++
+	}
+}
+
+// Reads return reads number counted
+func (s *Seqstat) Reads() int {
+	return s.reads
+}
+
+// Lengths return read length distribution
+func (s *Seqstat) Lengths() *stat.IntMap {
+	return s.lengths.Dist()
+}
+
+// GCs return read GC percent distribution
+func (s *Seqstat) GCs() *stat.IntMap {
+	return s.gc.Dist()
+}
+
+// Quals return read mean quality distribution, encoding offset not removed
+func (s *Seqstat) Quals() *stat.IntMap {
+	return stat.NewIntMap(s.quals)
+}
+
+// ModeQual return the most frequent read mean quality, encoding offset not removed
+func (s *Seqstat) ModeQual() int {
+	return stat.Counts[int](s.quals).Mode()
+}
+
+// GCNormal return theoretical normal distribution of GC percent (0-100) fitted
+// by mean and sd of observed distribution, and percent of reads deviate from it
+func (s *Seqstat) GCNormal() ([]float64, float64) {
+	return s.gc.Normal()
+}
+
+// SaveLenDist save read length distribution
+func (s *Seqstat) SaveLenDist(prefix string) error {
+	return s.lengths.Save(prefix)
+}
+
+// SaveGCDist save read GC percent distribution with the theoretical normal distribution
+func (s *Seqstat) SaveGCDist(prefix string) error {
+	return s.gc.Save(prefix)
+}
+
+// SaveQualHist save read mean quality distribution, offset is the quality encoding offset
+func (s *Seqstat) SaveQualHist(prefix string, offset int) error {
+	f, err := xopen.Xcreate(prefix+".seqqual", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintln(f, "##", strings.Join([]string{"quality", "count"}, "\t"))
+	m := s.Quals()
+	for _, q := range m.Keys() {
+		fmt.Fprintf(f, "%d\t%d\n", q-offset, m.Data[q])
+	}
+	return nil
+}
+
+// Merge add counts of other into s
+func (s *Seqstat) Merge(other *Seqstat) {
+	s.reads += other.reads
+	s.lengths.Merge(other.lengths)
+	s.gc.Merge(other.gc)
+	for q, count := range other.quals {
+		s.quals[q] += count
+	}
+}
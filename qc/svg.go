@@ -0,0 +1,130 @@
+package qc
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+)
+
+// plot draw simple inline svg chart, data coordinates are mapped into the
+// area inside margins
+type plot struct {
+	buf    bytes.Buffer
+	width  float64
+	height float64
+	left   float64 // margins
+	right  float64
+	top    float64
+	bottom float64
+	xmin   float64 // data range
+	xmax   float64
+	ymin   float64
+	ymax   float64
+	xnames []string // names of x ticks at 1, 2, 3 ..., numeric ticks if nil
+}
+
+func newPlot(width, height, xmin, xmax, ymin, ymax float64) *plot {
+	if xmax <= xmin {
+		xmax = xmin + 1
+	}
+	if ymax <= ymin {
+		ymax = ymin + 1
+	}
+	return &plot{width: width, height: height, left: 60, right: 20, top: 20, bottom: 45,
+		xmin: xmin, xmax: xmax, ymin: ymin, ymax: ymax}
+}
+
+func (p *plot) x(v float64) float64 {
+	return p.left + (v-p.xmin)/(p.xmax-p.xmin)*(p.width-p.left-p.right)
+}
+
+func (p *plot) y(v float64) float64 {
+	return p.height - p.bottom - (v-p.ymin)/(p.ymax-p.ymin)*(p.height-p.top-p.bottom)
+}
+
+// rect draw rectangle between data points (x0, y0) and (x1, y1)
+func (p *plot) rect(x0, y0, x1, y1 float64, style string) {
+	px0, px1 := math.Min(p.x(x0), p.x(x1)), math.Max(p.x(x0), p.x(x1))
+	py0, py1 := math.Min(p.y(y0), p.y(y1)), math.Max(p.y(y0), p.y(y1))
+	fmt.Fprintf(&p.buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" style="%s"/>`+"\n",
+		px0, py0, px1-px0, py1-py0, style)
+}
+
+// line draw line between data points (x0, y0) and (x1, y1)
+func (p *plot) line(x0, y0, x1, y1 float64, style string) {
+	fmt.Fprintf(&p.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" style="%s"/>`+"\n",
+		p.x(x0), p.y(y0), p.x(x1), p.y(y1), style)
+}
+
+// polyline draw lines through data points
+func (p *plot) polyline(xs, ys []float64, stroke string) {
+	p.buf.WriteString(`<polyline fill="none" stroke-width="1.5" stroke="` + stroke + `" points="`)
+	for i := range xs {
+		fmt.Fprintf(&p.buf, "%.1f,%.1f ", p.x(xs[i]), p.y(ys[i]))
+	}
+	p.buf.WriteString("\"/>\n")
+}
+
+// text draw text at pixel position
+func (p *plot) text(x, y float64, anchor, s string) {
+	fmt.Fprintf(&p.buf, `<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`+"\n",
+		x, y, anchor, template.HTMLEscapeString(s))
+}
+
+// legend draw color legend at the top right corner
+func (p *plot) legend(names, colors []string) {
+	for i, name := range names {
+		x := p.width - p.right - 60
+		y := p.top + 5 + float64(i)*15
+		fmt.Fprintf(&p.buf, `<rect x="%.1f" y="%.1f" width="10" height="10" fill="%s"/>`+"\n", x, y, colors[i])
+		p.text(x+15, y+9, "start", name)
+	}
+}
+
+// step return a nice tick step to split span into about n parts
+func step(span float64, n int) float64 {
+	raw := span / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if m*mag >= raw {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// axes draw x and y axis with ticks and labels
+func (p *plot) axes(xlabel, ylabel string) {
+	x0, x1 := p.x(p.xmin), p.x(p.xmax)
+	y0, y1 := p.y(p.ymin), p.y(p.ymax)
+	style := "stroke:#333;stroke-width:1"
+	fmt.Fprintf(&p.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" style="%s"/>`+"\n", x0, y0, x1, y0, style)
+	fmt.Fprintf(&p.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" style="%s"/>`+"\n", x0, y0, x0, y1, style)
+
+	if p.xnames != nil {
+		for i, name := range p.xnames {
+			p.text(p.x(float64(i+1)), y0+16, "middle", name)
+		}
+	} else {
+		xstep := step(p.xmax-p.xmin, 10)
+		for v := math.Ceil(p.xmin/xstep) * xstep; v <= p.xmax; v += xstep {
+			fmt.Fprintf(&p.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" style="%s"/>`+"\n", p.x(v), y0, p.x(v), y0+4, style)
+			p.text(p.x(v), y0+16, "middle", fmt.Sprintf("%g", v))
+		}
+	}
+	ystep := step(p.ymax-p.ymin, 5)
+	for v := math.Ceil(p.ymin/ystep) * ystep; v <= p.ymax; v += ystep {
+		fmt.Fprintf(&p.buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" style="%s"/>`+"\n", x0-4, p.y(v), x0, p.y(v), style)
+		p.text(x0-6, p.y(v)+4, "end", fmt.Sprintf("%g", v))
+	}
+	p.text((x0+x1)/2, p.height-8, "middle", xlabel)
+	fmt.Fprintf(&p.buf, `<text x="14" y="%.1f" text-anchor="middle" transform="rotate(-90 14 %.1f)">%s</text>`+"\n",
+		(y0+y1)/2, (y0+y1)/2, template.HTMLEscapeString(ylabel))
+}
+
+// svg return the svg element
+func (p *plot) svg() template.HTML {
+	return template.HTML(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" font-size="11" font-family="sans-serif">`+"\n%s</svg>",
+		p.width, p.height, p.buf.String()))
+}
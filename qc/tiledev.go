@@ -0,0 +1,178 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"gongs/xopen"
+	"strconv"
+	"strings"
+)
+
+// TileThreshold thresholds to flag bad tiles, eg. bubbles, focus loss
+type TileThreshold struct {
+	Deviation float64 // a cycle of tile is bad if mean quality lower than the lane mean by more than Deviation
+	Cycles    float64 // a tile is flagged if bad cycles more than Cycles fraction of its cycles
+}
+
+// DefaultTileThreshold return threshold flag tiles of more than 10% cycles 5 lower than the lane mean,
+// deviation as FastQC warn, a single bad cycle is not enough to drop a whole tile
+func DefaultTileThreshold() *TileThreshold {
+	return &TileThreshold{Deviation: 5, Cycles: 0.1}
+}
+
+// TileDev quality deviation of a tile from its lane
+type TileDev struct {
+	Flowcell   string
+	Lane       int
+	Tile       int
+	Reads      int       // reads number of tile
+	Deviations []float64 // mean quality of tile - mean quality of lane by cycle, 0 if cycle not sequenced
+	BadCycles  int       // cycles deviate more than the threshold
+	Flagged    bool
+}
+
+// tileSums return quality sum and quality count of cycle i
+func tileSums(t *tile, i int) (float64, float64) {
+	sum, n := 0.0, 0.0
+	if i < len(t.cycles) {
+		for q, count := range t.cycles[i] {
+			sum += float64(q) * float64(count)
+			n += float64(count)
+		}
+	}
+	return sum, n
+}
+
+// laneMeans return mean quality of all tiles of lane by cycle
+func laneMeans(l *lane, length int) []float64 {
+	sums, ns := make([]float64, length), make([]float64, length)
+	for _, mtile := range l.tiles {
+		for i := range sums {
+			s, n := tileSums(mtile, i)
+			sums[i], ns[i] = sums[i]+s, ns[i]+n
+		}
+	}
+	for i := range sums {
+		if ns[i] > 0 {
+			sums[i] /= ns[i]
+		}
+	}
+	return sums
+}
+
+// TileDevs return quality deviation of each tile from its lane by cycle, sorted by flowcell, lane and tile
+func (t *Tilestat) TileDevs(th *TileThreshold) []*TileDev {
+	devs := []*TileDev{}
+	var means []float64 // lane means of the current lane
+	for i, row := range t.tileRows() {
+		mflow := t.flowcells[row.flowid]
+		mlane := mflow.lanes[row.laneid]
+		mtile := mlane.tiles[row.tileid]
+		if i == 0 || devs[i-1].Flowcell != row.flowid || devs[i-1].Lane != row.laneid {
+			means = laneMeans(mlane, mflow.length)
+		}
+
+		dev := &TileDev{Flowcell: row.flowid, Lane: row.laneid, Tile: row.tileid, Deviations: make([]float64, mflow.length)}
+		_, reads := tileSums(mtile, 0)
+		dev.Reads = int(reads)
+		cycles := 0
+		for j := range dev.Deviations {
+			sum, n := tileSums(mtile, j)
+			if n == 0 {
+				continue
+			}
+			cycles++
+			dev.Deviations[j] = sum/n - means[j]
+			if -dev.Deviations[j] > th.Deviation {
+				dev.BadCycles++
+			}
+		}
+		dev.Flagged = dev.BadCycles > 0 && float64(dev.BadCycles) > th.Cycles*float64(cycles)
+		devs = append(devs, dev)
+	}
+	return devs
+}
+
+// MaxDrop return the max quality drop of any tile cycle below its lane mean, 0 if no tile
+func (t *Tilestat) MaxDrop() float64 {
+	drop := 0.0
+	for _, dev := range t.TileDevs(DefaultTileThreshold()) {
+		for _, d := range dev.Deviations {
+			if -d > drop {
+				drop = -d
+			}
+		}
+	}
+	return drop
+}
+
+// SaveTileDev save quality deviation of each tile from its lane by cycle, flagged tiles by th
+func (t *Tilestat) SaveTileDev(prefix string, th *TileThreshold) error {
+	f, err := xopen.Xcreate(prefix+".tiledev", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "#: deviation: %g\n", th.Deviation)
+	fmt.Fprintf(f, "#: cycles: %g\n", th.Cycles)
+	flowid := ""
+	for _, dev := range t.TileDevs(th) {
+		if dev.Flowcell != flowid { // print flowcell comment and header line
+			flowid = dev.Flowcell
+			fmt.Fprintln(f, "#!", strings.Repeat("=", 20), flowid, strings.Repeat("=", 20))
+			header := []string{"flowid", "laneid", "tileid", "reads", "flagged", "bad_cycles"}
+			for i := range dev.Deviations {
+				header = append(header, strconv.Itoa(i+1))
+			}
+			fmt.Fprintln(f, "##", strings.Join(header, "\t"))
+		}
+		result := []string{flowid, strconv.Itoa(dev.Lane), strconv.Itoa(dev.Tile), strconv.Itoa(dev.Reads),
+			strconv.FormatBool(dev.Flagged), strconv.Itoa(dev.BadCycles)}
+		for _, d := range dev.Deviations {
+			result = append(result, fmt.Sprintf("%.2f", d))
+		}
+		fmt.Fprintln(f, strings.Join(result, "\t"))
+	}
+	return nil
+}
+
+// TileFilter drop reads of flagged tiles
+type TileFilter struct {
+	flagged map[string]bool // flowcell:lane:tile
+	lastKey string          // read name prefix of the last read, reads of a tile are usually in order
+	lastHit bool
+}
+
+// NewTileFilter return filter drop reads of tiles flagged in devs
+func NewTileFilter(devs []*TileDev) *TileFilter {
+	f := &TileFilter{flagged: make(map[string]bool)}
+	for _, dev := range devs {
+		if dev.Flagged {
+			f.flagged[fmt.Sprintf("%s:%d:%d", dev.Flowcell, dev.Lane, dev.Tile)] = true
+		}
+	}
+	return f
+}
+
+// Len return flagged tiles number
+func (f *TileFilter) Len() int {
+	return len(f.flagged)
+}
+
+// Keep return false if read is from a flagged tile, reads with name can't split into
+// flowcell, lane and tile are kept
+func (f *TileFilter) Keep(fq *fastq.Fastq) bool {
+	key, ok := tileKey(fq.Name)
+	if !ok {
+		return true
+	}
+	if key != f.lastKey {
+		ids := strings.Split(key, ":")
+		laneid, err1 := strconv.Atoi(ids[3])
+		tileid, err2 := strconv.Atoi(ids[4])
+		f.lastKey = key
+		f.lastHit = err1 == nil && err2 == nil && f.flagged[fmt.Sprintf("%s:%d:%d", ids[2], laneid, tileid)]
+	}
+	return !f.lastHit
+}
@@ -0,0 +1,82 @@
+package qc
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"math"
+	"strings"
+	"testing"
+)
+
+// tileRead return read of 10 cycles from lane 1 tile, cycles in bad are of quality 20, others 40
+func tileRead(tile int, bad ...int) *fastq.Fastq {
+	qual := []byte(strings.Repeat("I", 10))
+	for _, i := range bad {
+		qual[i] = '5'
+	}
+	return &fastq.Fastq{Name: fmt.Sprintf("M1:1:FC1:1:%d:1:1 1:N:0:ACGT", tile), Seq: []byte("ACGTACGTAC"), Qual: qual}
+}
+
+func TestTileDevs(t *testing.T) {
+	ts := NewTile()
+	for i := 0; i < 10; i++ {
+		ts.Count(tileRead(1101))
+		ts.Count(tileRead(1102, 3))       // a single bad cycle
+		ts.Count(tileRead(1103, 3, 4, 5)) // 3 of 10 cycles bad
+	}
+
+	devs := ts.TileDevs(DefaultTileThreshold())
+	if len(devs) != 3 {
+		t.Fatalf("TileDevs expect: %v get: %v", 3, len(devs))
+	}
+	for i, c := range []struct {
+		tile      int
+		badCycles int
+		flagged   bool
+		dev3      float64 // deviation of cycle 3 from lane mean (40 + 20 + 20) / 3
+	}{
+		{1101, 0, false, 40 - 80.0/3},
+		{1102, 1, false, 20 - 80.0/3},
+		{1103, 3, true, 20 - 80.0/3},
+	} {
+		dev := devs[i]
+		if dev.Tile != c.tile || dev.Reads != 10 || dev.BadCycles != c.badCycles || dev.Flagged != c.flagged || math.Abs(dev.Deviations[3]-c.dev3) > 1e-9 {
+			t.Errorf("TileDevs expect: %+v get: %+v", c, dev)
+		}
+	}
+
+	// the old default flag tiles of any bad cycle
+	if devs := ts.TileDevs(&TileThreshold{Deviation: 5, Cycles: 0}); !devs[1].Flagged {
+		t.Errorf("TileDevs Cycles 0 expect: %v get: %v", true, devs[1].Flagged)
+	}
+	if devs := ts.TileDevs(&TileThreshold{Deviation: 15, Cycles: 0.1}); devs[2].Flagged {
+		t.Errorf("TileDevs Deviation 15 expect: %v get: %v", false, devs[2].Flagged)
+	}
+}
+
+func TestTileFilter(t *testing.T) {
+	devs := []*TileDev{
+		{Flowcell: "FC1", Lane: 1, Tile: 1101},
+		{Flowcell: "FC1", Lane: 1, Tile: 1102, Flagged: true},
+	}
+	f := NewTileFilter(devs)
+	if f.Len() != 1 {
+		t.Errorf("TileFilter Len expect: %v get: %v", 1, f.Len())
+	}
+	for _, c := range []struct {
+		name string
+		keep bool
+	}{
+		{"M1:1:FC1:1:1101:1:1 1:N:0:ACGT", true},
+		{"M1:1:FC1:1:1102:1:1 1:N:0:ACGT", false},
+		{"M1:1:FC1:1:1102:2:2", false}, // the same tile as the last read
+		{"M1:1:FC1:2:1102:1:1", true},  // another lane
+		{"M1:1:FC2:1:1102:1:1", true},  // another flowcell
+		{"FC1:1:1102:1:1", true},       // can't split into flowcell, lane and tile
+		{"M1:1:FC1:01:1102:1:1", false},
+	} {
+		if keep := f.Keep(&fastq.Fastq{Name: c.name}); keep != c.keep {
+			t.Errorf("TileFilter Keep(%s) expect: %v get: %v", c.name, c.keep, keep)
+		}
+	}
+}
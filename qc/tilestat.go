@@ -1,15 +1,19 @@
 package qc
 
 import (
+	"errors"
 	"fmt"
 	"gongs/biofile/fastq"
-	"gongs/stat"
 	"gongs/xopen"
 	"sort"
 	"strconv"
 	"strings"
 )
 
+var (
+	ErrTileHeader = errors.New("Read name can't split into flowcell, lane and tile")
+)
+
 type flowcell struct {
 	id     string
 	length int
@@ -21,143 +25,239 @@ type lane struct {
 	tiles map[int]*tile
 }
 
+// tile count qualities of each cycle, cycles[i][q] is count of quality q at cycle i,
+// slice grows with the max quality, much smaller than cycle for thousands of tiles
 type tile struct {
 	id     int
-	cycles map[int]*cycle
+	cycles [][]uint32
+}
+
+func (t *tile) count(qual []byte) {
+	if len(qual) > len(t.cycles) {
+		t.cycles = append(t.cycles, make([][]uint32, len(qual)-len(t.cycles))...)
+	}
+	for i, q := range qual {
+		c := t.cycles[i]
+		if int(q) >= len(c) {
+			c = append(c, make([]uint32, int(q)+1-len(c))...)
+			t.cycles[i] = c
+		}
+		c[q]++
+	}
+}
+
+// cycle return quality counts at cycle i
+func (t *tile) cycle(i int) *cycle {
+	c := &cycle{}
+	if i < len(t.cycles) {
+		for q, n := range t.cycles[i] {
+			c.counts[q] = uint64(n)
+		}
+	}
+	return c
+}
+
+func (t *tile) merge(other *tile) {
+	if len(other.cycles) > len(t.cycles) {
+		t.cycles = append(t.cycles, make([][]uint32, len(other.cycles)-len(t.cycles))...)
+	}
+	for i, oc := range other.cycles {
+		c := t.cycles[i]
+		if len(oc) > len(c) {
+			c = append(c, make([]uint32, len(oc)-len(c))...)
+			t.cycles[i] = c
+		}
+		for q, n := range oc {
+			c[q] += n
+		}
+	}
 }
 
+// cycle quality counts of a cycle
 type cycle struct {
-	pos int
-	m   *stat.IntMap
+	counts
 }
 
 func (c *cycle) meanQ() float64 {
-	return c.m.Mean()
+	return c.mean()
 }
 
 func (c *cycle) medianQ() float64 {
-	return c.m.Median()
+	return c.percentile(0.5)
 }
 
 func (c *cycle) maxQ() int {
-	maxq := 0
-	for q := range c.m.Data {
-		if maxq < q {
-			maxq = q
-		}
+	if max := c.max(); max >= 0 {
+		return max
 	}
-	return maxq
+	return 0
 }
 
 func (c *cycle) minQ() int {
-	minq := 127
-	for q := range c.m.Data {
-		if minq > q {
-			minq = q
-		}
+	if min := c.min(); min >= 0 {
+		return min
 	}
-	return minq
+	return 127
 }
 
 func (c *cycle) percentile10() float64 {
-	return c.m.Percentile(0.10)
+	return c.percentile(0.10)
 }
 
 func (c *cycle) percentile25() float64 {
-	return c.m.Percentile(0.25)
+	return c.percentile(0.25)
 }
 
 func (c *cycle) percentile75() float64 {
-	return c.m.Percentile(0.75)
+	return c.percentile(0.75)
 }
 
 func (c *cycle) percentile90() float64 {
-	return c.m.Percentile(0.90)
+	return c.percentile(0.90)
 }
 
 type Tilestat struct {
 	flowcells   map[string]*flowcell // record quality by flowcell,lane,tile
-	quals       map[int]int          // record full quality count distribution
-	qualByCycle map[int]*cycle       // record quality by position
-	max         byte                 // max quality score
-	min         byte                 // min quality score
+	qualByCycle []cycle              // record quality by position
+	lastKey     string               // read name prefix of the last tile, flowcell,lane,tile of a run are usually in order
+	lastTile    *tile
+	lastFlow    *flowcell
 }
 
 func NewTile() *Tilestat {
 	return &Tilestat{
-		flowcells:   make(map[string]*flowcell),
-		quals:       make(map[int]int),
-		qualByCycle: make(map[int]*cycle),
-		max:         0,
-		min:         127,
+		flowcells: make(map[string]*flowcell),
+	}
+}
+
+// tileKey return read name prefix ends at the tile field: instrument:run:flowcell:lane:tile
+func tileKey(name string) (string, bool) {
+	n := 0
+	for i := 0; i < len(name); i++ {
+		if name[i] != ':' {
+			continue
+		}
+		if n++; n == 5 {
+			return name[:i], true
+		}
+	}
+	if n == 4 {
+		return name, true
 	}
+	return "", false
 }
 
 // Count count quality by postion and flowcell,lane,tile
+// quality by postion is always counted, ErrTileHeader returned if read name
+// can't split into flowcell, lane and tile
 func (t *Tilestat) Count(fq *fastq.Fastq) error {
-	ids := strings.SplitN(fq.Name, ":", 6)
-	// fmt.Println(ids)
-	flowid := ids[2]
-	laneid, err := strconv.Atoi(ids[3])
-	if err != nil {
-		return err
+	if len(fq.Qual) > len(t.qualByCycle) {
+		t.qualByCycle = append(t.qualByCycle, make([]cycle, len(fq.Qual)-len(t.qualByCycle))...)
 	}
-	tileid, err := strconv.Atoi(ids[4])
-	if err != nil {
-		return err
+	for i, q := range fq.Qual { // record each quality
+		t.qualByCycle[i].counts[q]++
 	}
 
-	mflowcell, ok := t.flowcells[flowid]
+	key, ok := tileKey(fq.Name)
 	if !ok {
-		mflowcell = &flowcell{id: flowid, lanes: make(map[int]*lane)}
-		t.flowcells[flowid] = mflowcell
+		return ErrTileHeader
 	}
-	if mflowcell.length < len(fq.Seq) {
-		mflowcell.length = len(fq.Seq)
+	if key != t.lastKey || t.lastTile == nil {
+		ids := strings.Split(key, ":")
+		flowid := ids[2]
+		laneid, err := strconv.Atoi(ids[3])
+		if err != nil {
+			return ErrTileHeader
+		}
+		tileid, err := strconv.Atoi(ids[4])
+		if err != nil {
+			return ErrTileHeader
+		}
+
+		mflowcell, ok := t.flowcells[flowid]
+		if !ok {
+			mflowcell = &flowcell{id: flowid, lanes: make(map[int]*lane)}
+			t.flowcells[flowid] = mflowcell
+		}
+		mlane, ok := mflowcell.lanes[laneid]
+		if !ok {
+			mlane = &lane{id: laneid, tiles: make(map[int]*tile)}
+			mflowcell.lanes[laneid] = mlane
+		}
+		mtile, ok := mlane.tiles[tileid]
+		if !ok {
+			mtile = &tile{id: tileid}
+			mlane.tiles[tileid] = mtile
+		}
+		t.lastKey, t.lastTile, t.lastFlow = key, mtile, mflowcell
 	}
 
-	mlane, ok := mflowcell.lanes[laneid]
-	if !ok {
-		mlane = &lane{id: laneid, tiles: make(map[int]*tile)}
-		mflowcell.lanes[laneid] = mlane
+	if t.lastFlow.length < len(fq.Seq) {
+		t.lastFlow.length = len(fq.Seq)
 	}
-	mtile, ok := mlane.tiles[tileid]
-	if !ok {
-		mtile = &tile{id: tileid, cycles: make(map[int]*cycle)}
-		mlane.tiles[tileid] = mtile
+	t.lastTile.count(fq.Qual)
+	return nil
+}
+
+// quals return full quality count distribution
+func (t *Tilestat) quals() *counts {
+	c := &counts{}
+	for i := range t.qualByCycle {
+		c.add(&t.qualByCycle[i].counts)
 	}
+	return c
+}
 
-	for i, q := range fq.Qual { // record each quality
-		t.quals[int(q)]++
-		ncycle, ok := t.qualByCycle[i]
-		if !ok {
-			ncycle = &cycle{pos: i, m: stat.NewIntMap(make(map[int]int))}
-			t.qualByCycle[i] = ncycle
-		}
-		ncycle.m.Data[int(q)]++
+// Merge add counts of other into t
+func (t *Tilestat) Merge(other *Tilestat) {
+	if len(other.qualByCycle) > len(t.qualByCycle) {
+		t.qualByCycle = append(t.qualByCycle, make([]cycle, len(other.qualByCycle)-len(t.qualByCycle))...)
+	}
+	for i := range other.qualByCycle {
+		t.qualByCycle[i].add(&other.qualByCycle[i].counts)
+	}
 
-		if t.min > q {
-			t.min = q
+	for flowid, oflowcell := range other.flowcells {
+		mflowcell, ok := t.flowcells[flowid]
+		if !ok {
+			mflowcell = &flowcell{id: flowid, lanes: make(map[int]*lane)}
+			t.flowcells[flowid] = mflowcell
 		}
-		if t.max < q {
-			t.max = q
+		if mflowcell.length < oflowcell.length {
+			mflowcell.length = oflowcell.length
 		}
-		mcycle, ok := mtile.cycles[i]
-		if !ok {
-			mcycle = &cycle{pos: i, m: stat.NewIntMap(map[int]int{})}
-			mtile.cycles[i] = mcycle
+		for laneid, olane := range oflowcell.lanes {
+			mlane, ok := mflowcell.lanes[laneid]
+			if !ok {
+				mlane = &lane{id: laneid, tiles: make(map[int]*tile)}
+				mflowcell.lanes[laneid] = mlane
+			}
+			for tileid, otile := range olane.tiles {
+				mtile, ok := mlane.tiles[tileid]
+				if !ok {
+					mtile = &tile{id: tileid}
+					mlane.tiles[tileid] = mtile
+				}
+				mtile.merge(otile)
+			}
 		}
-		mcycle.m.Data[int(q)]++
 	}
-	return nil
 }
 
+// MinQual return min quality, 127 if nothing counted
 func (t *Tilestat) MinQual() int {
-	return int(t.min)
+	if min := t.quals().min(); min >= 0 {
+		return min
+	}
+	return 127
 }
 
+// MaxQual return max quality, 0 if nothing counted
 func (t *Tilestat) MaxQual() int {
-	return int(t.max)
+	if max := t.quals().max(); max >= 0 {
+		return max
+	}
+	return 0
 }
 
 // GuessEncoding Guess quality Encoding version
@@ -167,23 +267,33 @@ func (t *Tilestat) MaxQual() int {
 // J - Illumina 1.5+ Phred+64,  raw reads typically (3, 40), using ASCII 66 to 104
 // L - Illumina 1.8+ Phred+33,  raw reads typically (0, 41), using ASCII 33 to 74
 func (t *Tilestat) GuessEncoding() string {
-	if t.min < 33 {
+	min, max := t.MinQual(), t.MaxQual()
+	if min < 33 {
 		return "Unkown"
-	} else if t.max < 74 {
+	} else if max < 74 {
 		return "SANGER"
-	} else if t.max < 75 {
+	} else if max < 75 {
 		return "Illumina1.8+"
-	} else if t.min > 58 && t.min < 64 {
+	} else if min > 58 && min < 64 {
 		return "Solexa"
-	} else if t.min > 63 && t.min < 66 {
+	} else if min > 63 && min < 66 {
 		return "Illumina1.3+"
-	} else if t.min > 65 {
+	} else if min > 65 {
 		return "Illumina11.5+"
 	}
 	// minqual < 58 and maxqual > 74
 	return "Mix"
 }
 
+// Offset return quality offset of guessed encoding, 64 for Solexa and Illumina1.3+ else 33
+func (t *Tilestat) Offset() int {
+	switch t.GuessEncoding() {
+	case "Solexa", "Illumina1.3+", "Illumina11.5+":
+		return 64
+	}
+	return 33
+}
+
 func (t *Tilestat) Q20() float64 {
 	return t.Q(20)
 }
@@ -192,23 +302,33 @@ func (t *Tilestat) Q30() float64 {
 	return t.Q(30)
 }
 
-// Q return >=qual percent
+// Q return >=qual percent, qual is phred score without encoding offset
 func (t *Tilestat) Q(q byte) float64 {
+	c, tot := t.qCount(q)
+	if tot == 0 {
+		return 0
+	}
+	return float64(c*100) / float64(tot)
+}
+
+// qCount return >=qual bases count and all bases count
+func (t *Tilestat) qCount(q byte) (int, int) {
 	c := 0
 	tot := 0
-	for qu, count := range t.quals {
-		tot += count
-		if qu < int(q) {
+	offset := t.Offset()
+	for qu, count := range t.quals() {
+		tot += int(count)
+		if qu-offset < int(q) {
 			continue
 		}
-		c += count
+		c += int(count)
 	}
-	return float64(c*100) / float64(tot)
+	return c, tot
 }
 
 // Qat return qual count
 func (t *Tilestat) Qat(q byte) int {
-	return t.quals[int(q)]
+	return int(t.quals()[q])
 }
 
 // Save file block
@@ -226,16 +346,18 @@ func (t *Tilestat) SaveQualDist(prefix string) error {
 	defer f.Close()
 
 	// print header line
+	min, max := t.MinQual(), t.MaxQual()
 	header := []string{"MinQ", " MaxQ"}
-	for q := t.min; q < t.max+1; q++ {
+	for q := min; q < max+1; q++ {
 		header = append(header, fmt.Sprintf("Q%d", q))
 	}
 	fmt.Fprintln(f, "##", strings.Join(header, "\t"))
 
 	// print data line
-	fmt.Fprint(f, strconv.Itoa(int(t.min)), "\t", strconv.Itoa(int(t.max)))
-	for i, max := int(t.min), int(t.max); i < max+1; i++ {
-		fmt.Fprint(f, "\t", strconv.Itoa(t.quals[i]))
+	quals := t.quals()
+	fmt.Fprint(f, strconv.Itoa(min), "\t", strconv.Itoa(max))
+	for i := min; i < max+1; i++ {
+		fmt.Fprint(f, "\t", strconv.FormatUint(quals[i], 10))
 	}
 	return nil
 }
@@ -253,31 +375,34 @@ func (t *Tilestat) SaveCycleStat(prefix string) error {
 
 	// print data line
 	for i, l := 0, len(t.qualByCycle); i < l; i++ {
-		c := t.qualByCycle[i]
+		c := &t.qualByCycle[i]
 		fmt.Fprintln(f, i, "\t", c.minQ(), "\t", c.maxQ(), "\t", c.meanQ(), "\t", c.medianQ(), "\t",
 			c.percentile10(), "\t", c.percentile25(), "\t", c.percentile75(), "\t", c.percentile90())
 	}
 	return nil
 }
 
-// SaveTileStat save Tile qualtity stat
-func (t *Tilestat) SaveTileStat(prefix string) error {
-	f, err := xopen.Xcreate(prefix+".tilestat", "w")
-	if err != nil {
-		return err
-	}
-	defer f.Close()
+// tileRow median quality of each cycle of a tile, 0 if cycle not sequenced
+type tileRow struct {
+	flowid  string
+	laneid  int
+	tileid  int
+	medians []float64
+}
 
+// tileRows return median quality by cycle of each tile, sorted by flowcell, lane and tile
+func (t *Tilestat) tileRows() []*tileRow {
+	rows := []*tileRow{}
+
+	// get sorted flowids
+	flowids := []string{}
 	for flowid := range t.flowcells {
-		mflow := t.flowcells[flowid]
-		fmt.Fprintln(f, "#!", strings.Repeat("=", 20), flowid, strings.Repeat("=", 20))
+		flowids = append(flowids, flowid)
+	}
+	sort.Strings(flowids)
 
-		// print header line
-		header := []string{"flowid", "laneid", "tileid", "length"}
-		for i := 0; i < mflow.length; i++ {
-			header = append(header, fmt.Sprintf("%d", i+1))
-		}
-		fmt.Fprintln(f, "##", strings.Join(header, "\t"))
+	for _, flowid := range flowids {
+		mflow := t.flowcells[flowid]
 
 		// get sorted  laneids
 		laneids := []int{}
@@ -298,19 +423,48 @@ func (t *Tilestat) SaveTileStat(prefix string) error {
 
 			for _, tileid := range tileids { // iterate each tile
 				mtile := mlane.tiles[tileid]
-				result := []string{flowid, strconv.Itoa(laneid), strconv.Itoa(tileid), strconv.Itoa(mflow.length)}
-				for i := 0; i < mflow.length; i++ {
-					cycle, ok := mtile.cycles[i]
-					if !ok {
-						result = append(result, "0")
-						continue
-					}
-					result = append(result, fmt.Sprintf("%.1f", cycle.medianQ()))
+				row := &tileRow{flowid: flowid, laneid: laneid, tileid: tileid, medians: make([]float64, mflow.length)}
+				for i := 0; i < mflow.length && i < len(mtile.cycles); i++ {
+					row.medians[i] = mtile.cycle(i).medianQ()
 				}
-				// print tile lane
-				fmt.Fprintln(f, strings.Join(result, "\t"))
+				rows = append(rows, row)
+			}
+		}
+	}
+	return rows
+}
+
+// SaveTileStat save Tile qualtity stat
+func (t *Tilestat) SaveTileStat(prefix string) error {
+	f, err := xopen.Xcreate(prefix+".tilestat", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	flowid := ""
+	for _, row := range t.tileRows() {
+		length := len(row.medians)
+		if row.flowid != flowid { // print flowcell comment and header line
+			flowid = row.flowid
+			fmt.Fprintln(f, "#!", strings.Repeat("=", 20), flowid, strings.Repeat("=", 20))
+			header := []string{"flowid", "laneid", "tileid", "length"}
+			for i := 0; i < length; i++ {
+				header = append(header, fmt.Sprintf("%d", i+1))
+			}
+			fmt.Fprintln(f, "##", strings.Join(header, "\t"))
+		}
+
+		result := []string{flowid, strconv.Itoa(row.laneid), strconv.Itoa(row.tileid), strconv.Itoa(length)}
+		for _, median := range row.medians {
+			if median == 0 {
+				result = append(result, "0")
+				continue
 			}
+			result = append(result, fmt.Sprintf("%.1f", median))
 		}
+		// print tile lane
+		fmt.Fprintln(f, strings.Join(result, "\t"))
 	}
 	return nil
 }
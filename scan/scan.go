@@ -99,11 +99,11 @@ func (s *Scanner) Scan() bool {
 			s.start = 0
 		}
 
+		// data read with an error (eg. io.EOF of gzip) is still scanned
 		n, err := s.r.Read(s.buf[s.end:len(s.buf)])
 		s.end += n
 		if err != nil {
 			s.setErr(err)
-			return false
 		}
 	}
 }
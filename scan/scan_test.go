@@ -4,7 +4,9 @@ import (
 	"fmt"
 	"gongs/xopen"
 	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 const (
@@ -120,3 +122,19 @@ func TestScannerGz(t *testing.T) {
 		t.Error("TestScanner Error:", err)
 	}
 }
+
+func TestScannerDataWithEOF(t *testing.T) {
+	// reader returns the last data with io.EOF, as gzip reader
+	text := "line1\nline2\nline3"
+	s := New(iotest.DataErrReader(strings.NewReader(text)))
+	lines := []string{}
+	for s.Scan() {
+		lines = append(lines, s.Line())
+	}
+	if got := strings.Join(lines, ","); got != "line1,line2,line3" {
+		t.Errorf("Scan expect: %v get: %v", "line1,line2,line3", got)
+	}
+	if err := s.Err(); err != nil {
+		t.Errorf("Scan Err expect: %v get: %v", nil, err)
+	}
+}
@@ -0,0 +1,139 @@
+// split package split fastq files into parts by reads, bytes or number of parts,
+// mates of pairs are always in the same part
+
+package split
+
+import (
+	"errors"
+	"gongs/biofile/fastq"
+)
+
+var (
+	ErrChunkSize = errors.New("Chunk Size Must Be Greater Than 0")
+	ErrStdin     = errors.New("Can't Count Reads Of Stdin")
+)
+
+// Chunker decide part of each record, parts are contiguous, or records are dealt
+// to parts one by one if RoundRobin
+type Chunker struct {
+	RoundRobin bool
+	reads      int   // max reads of a part
+	bytes      int64 // max bytes of a part
+	parts      int   // parts of round robin
+	extra      int   // the first extra parts have one more read
+
+	part      int
+	partReads int
+	partBytes int64
+	records   int
+}
+
+// ByReads return chunker of n records per part
+func ByReads(n int) (*Chunker, error) {
+	if n < 1 {
+		return nil, ErrChunkSize
+	}
+	return &Chunker{reads: n}, nil
+}
+
+// ByBytes return chunker of about n bytes uncompressed per part, a part has at least one record
+func ByBytes(n int64) (*Chunker, error) {
+	if n < 1 {
+		return nil, ErrChunkSize
+	}
+	return &Chunker{bytes: n}, nil
+}
+
+// ByParts return chunker of n contiguous parts of total records, parts have records
+// of the same number, but the last ones may have one less
+func ByParts(total, n int) (*Chunker, error) {
+	if n < 1 {
+		return nil, ErrChunkSize
+	}
+	if total < n {
+		n = total
+	}
+	if n == 0 {
+		return ByReads(1)
+	}
+	// the first total % n parts have one more record
+	return &Chunker{reads: total / n, extra: total % n}, nil
+}
+
+// RoundRobin return chunker deal records to n parts one by one
+func RoundRobin(n int) (*Chunker, error) {
+	if n < 1 {
+		return nil, ErrChunkSize
+	}
+	return &Chunker{RoundRobin: true, parts: n}, nil
+}
+
+// Part return part of the next record of size bytes
+func (c *Chunker) Part(size int) int {
+	defer func() { c.records++ }()
+	if c.RoundRobin {
+		return c.records % c.parts
+	}
+	if c.partReads > 0 {
+		full := false
+		if c.reads > 0 {
+			max := c.reads
+			if c.part < c.extra {
+				max++
+			}
+			full = c.partReads >= max
+		} else {
+			full = c.partBytes+int64(size) > c.bytes
+		}
+		if full {
+			c.part++
+			c.partReads, c.partBytes = 0, 0
+		}
+	}
+	c.partReads++
+	c.partBytes += int64(size)
+	return c.part
+}
+
+// Size return bytes of records in fastq format
+func Size(fqs ...*fastq.Fastq) int {
+	n := 0
+	for _, fq := range fqs {
+		// @name\nseq\n+\nqual\n
+		n += len(fq.Name) + len(fq.Seq) + len(fq.Qual) + 5
+	}
+	return n
+}
+
+// Count return records of single or paired files, read1 files are counted only of pairs
+func Count(pair bool, filenames ...string) (int, error) {
+	if pair {
+		if len(filenames)%2 != 0 {
+			return 0, fastq.ErrUnPairInputFile
+		}
+		files := make([]string, 0, len(filenames)/2)
+		for i := 0; i < len(filenames); i += 2 {
+			files = append(files, filenames[i])
+		}
+		filenames = files
+	}
+	n := 0
+	for _, filename := range filenames {
+		if filename == "-" {
+			return 0, ErrStdin
+		}
+		ff, err := fastq.Open(filename)
+		if err != nil {
+			return 0, err
+		}
+		for ff.Next() {
+			n++
+		}
+		err = ff.Err()
+		ff.Close()
+		if err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,127 @@
+package split
+
+import (
+	"fmt"
+	"gongs/biofile/fastq"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parts(c *Chunker, sizes ...int) []int {
+	ps := make([]int, len(sizes))
+	for i, size := range sizes {
+		ps[i] = c.Part(size)
+	}
+	return ps
+}
+
+func TestChunker(t *testing.T) {
+	c, _ := ByReads(2)
+	if ps := parts(c, 1, 1, 1, 1, 1); fmt.Sprint(ps) != "[0 0 1 1 2]" {
+		t.Errorf("ByReads expect: %v get: %v", "[0 0 1 1 2]", ps)
+	}
+	c, _ = ByBytes(10)
+	if ps := parts(c, 4, 4, 4, 20, 1, 9); fmt.Sprint(ps) != "[0 0 1 2 3 3]" {
+		t.Errorf("ByBytes expect: %v get: %v", "[0 0 1 2 3 3]", ps)
+	}
+	c, _ = ByParts(7, 3)
+	if ps := parts(c, 1, 1, 1, 1, 1, 1, 1); fmt.Sprint(ps) != "[0 0 0 1 1 2 2]" {
+		t.Errorf("ByParts expect: %v get: %v", "[0 0 0 1 1 2 2]", ps)
+	}
+	c, _ = ByParts(2, 3)
+	if ps := parts(c, 1, 1); fmt.Sprint(ps) != "[0 1]" {
+		t.Errorf("ByParts expect: %v get: %v", "[0 1]", ps)
+	}
+	c, _ = RoundRobin(3)
+	if ps := parts(c, 1, 1, 1, 1, 1); fmt.Sprint(ps) != "[0 1 2 0 1]" {
+		t.Errorf("RoundRobin expect: %v get: %v", "[0 1 2 0 1]", ps)
+	}
+	for _, f := range []func() (*Chunker, error){
+		func() (*Chunker, error) { return ByReads(0) },
+		func() (*Chunker, error) { return ByBytes(0) },
+		func() (*Chunker, error) { return ByParts(10, 0) },
+		func() (*Chunker, error) { return RoundRobin(0) },
+	} {
+		if _, err := f(); err != ErrChunkSize {
+			t.Errorf("Chunker expect: %v get: %v", ErrChunkSize, err)
+		}
+	}
+}
+
+func writeFastq(t *testing.T, filename string, mate, n int) {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "@r%d %d:N:0\nACGT\n+\nIIII\n", i, mate)
+	}
+	if err := os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readIds(t *testing.T, filename string) []string {
+	ff, err := fastq.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ff.Close()
+	ids := []string{}
+	for ff.Next() {
+		ids = append(ids, ff.Fq().Id())
+	}
+	if err := ff.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return ids
+}
+
+func TestSplit(t *testing.T) {
+	dir := t.TempDir()
+	r1, r2 := filepath.Join(dir, "r1.fq"), filepath.Join(dir, "r2.fq")
+	writeFastq(t, r1, 1, 5)
+	writeFastq(t, r2, 2, 5)
+
+	if n, err := Count(true, r1, r2); err != nil || n != 5 {
+		t.Errorf("Count expect: %v get: %v %v", 5, n, err)
+	}
+
+	c, _ := ByParts(5, 2)
+	w := NewWriter(filepath.Join(dir, "pair"), 2, true)
+	if err := Split(c, w, r1, r2); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	indexes, reads, _ := w.Parts()
+	if fmt.Sprint(indexes, reads) != "[0 1] [3 2]" {
+		t.Errorf("Split Parts expect: %v get: %v %v", "[0 1] [3 2]", indexes, reads)
+	}
+	if name := w.Name(1, 1); !strings.HasSuffix(name, "pair.002.r2.fastq.gz") {
+		t.Errorf("Split Name expect: %v get: %v", "pair.002.r2.fastq.gz", name)
+	}
+	for mate := 0; mate < 2; mate++ {
+		if ids := readIds(t, w.Name(1, mate)); fmt.Sprint(ids) != "[r3 r4]" {
+			t.Errorf("Split mate %d expect: %v get: %v", mate+1, "[r3 r4]", ids)
+		}
+	}
+
+	c, _ = RoundRobin(2)
+	w = NewWriter(filepath.Join(dir, "single"), 1, false)
+	if err := Split(c, w, r1); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if ids := readIds(t, w.Name(1, 0)); fmt.Sprint(ids) != "[r1 r3]" {
+		t.Errorf("Split RoundRobin expect: %v get: %v", "[r1 r3]", ids)
+	}
+
+	writeFastq(t, r2, 2, 4)
+	c, _ = ByReads(2)
+	w = NewWriter(filepath.Join(dir, "sync"), 2, false)
+	if err := Split(c, w, r1, r2); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
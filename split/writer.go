@@ -0,0 +1,198 @@
+package split
+
+import (
+	"bufio"
+	"fmt"
+	"gongs/biofile/fastq"
+	"gongs/xopen"
+	"io"
+	"sort"
+)
+
+// part writers of a part, one for each mate
+type part struct {
+	closers []io.Closer
+	writers []*bufio.Writer
+	reads   int
+	bytes   int64
+}
+
+// Writer write records to parts of files named by prefix, eg. prefix.001.fastq.gz,
+// or prefix.001.r1.fastq.gz prefix.001.r2.fastq.gz of pairs, part files are created
+// when the first record written
+type Writer struct {
+	Prefix string
+	Mates  int  // 1 or 2
+	Gzip   bool // gzip output
+	parts  map[int]*part
+	done   map[int]*part // closed parts
+}
+
+func NewWriter(prefix string, mates int, gzip bool) *Writer {
+	return &Writer{Prefix: prefix, Mates: mates, Gzip: gzip, parts: make(map[int]*part), done: make(map[int]*part)}
+}
+
+// Name return file name of mate (0 or 1) of part i
+func (w *Writer) Name(i, mate int) string {
+	name := fmt.Sprintf("%s.%03d", w.Prefix, i+1)
+	if w.Mates > 1 {
+		name += fmt.Sprintf(".r%d", mate+1)
+	}
+	name += ".fastq"
+	if w.Gzip {
+		name += ".gz"
+	}
+	return name
+}
+
+// Write write a record, a read or mates of a pair, to part i
+func (w *Writer) Write(i int, fqs ...*fastq.Fastq) error {
+	if len(fqs) != w.Mates {
+		return fmt.Errorf("Write %d Reads To Writer Of %d Mates", len(fqs), w.Mates)
+	}
+	p, ok := w.parts[i]
+	if !ok {
+		if _, ok := w.done[i]; ok {
+			return fmt.Errorf("Write To Closed Part: %s", w.Name(i, 0))
+		}
+		p = &part{}
+		for mate := 0; mate < w.Mates; mate++ {
+			out, err := xopen.Xcreate(w.Name(i, mate), "w")
+			if err != nil {
+				p.close()
+				return err
+			}
+			p.closers = append(p.closers, out)
+			p.writers = append(p.writers, bufio.NewWriter(out))
+		}
+		w.parts[i] = p
+	}
+	for mate, fq := range fqs {
+		if _, err := fmt.Fprintln(p.writers[mate], fq); err != nil {
+			return err
+		}
+	}
+	p.reads++
+	p.bytes += int64(Size(fqs...))
+	return nil
+}
+
+func (p *part) close() error {
+	var err error
+	for i, c := range p.closers {
+		if e := p.writers[i].Flush(); err == nil {
+			err = e
+		}
+		if e := c.Close(); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// ClosePart flush and close files of part i, no more records can be written to it
+func (w *Writer) ClosePart(i int) error {
+	p, ok := w.parts[i]
+	if !ok {
+		return nil
+	}
+	delete(w.parts, i)
+	w.done[i] = p
+	return p.close()
+}
+
+// Close close all parts, return the first error
+func (w *Writer) Close() error {
+	var err error
+	for i := range w.parts {
+		if e := w.ClosePart(i); err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Parts return parts written, reads and uncompressed bytes of each part
+func (w *Writer) Parts() ([]int, []int, []int64) {
+	indexes := make([]int, 0, len(w.parts)+len(w.done))
+	for _, m := range []map[int]*part{w.parts, w.done} {
+		for i := range m {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Ints(indexes)
+	reads, bytes := make([]int, len(indexes)), make([]int64, len(indexes))
+	for n, i := range indexes {
+		p, ok := w.parts[i]
+		if !ok {
+			p = w.done[i]
+		}
+		reads[n], bytes[n] = p.reads, p.bytes
+	}
+	return indexes, reads, bytes
+}
+
+// Split write records of single files, or pairs of files (read1 read2 ...) in sync
+// by FastqPairFile, to parts decided by c, parts are closed once full unless round robin
+func Split(c *Chunker, w *Writer, filenames ...string) error {
+	if len(filenames) == 0 {
+		return fastq.ErrEmptyInputFile
+	}
+	last := -1
+	write := func(fqs ...*fastq.Fastq) error {
+		i := c.Part(Size(fqs...))
+		if !c.RoundRobin && i != last && last >= 0 {
+			if err := w.ClosePart(last); err != nil {
+				return err
+			}
+		}
+		last = i
+		return w.Write(i, fqs...)
+	}
+
+	if w.Mates == 1 {
+		for _, filename := range filenames {
+			ff, err := fastq.Open(filename)
+			if err != nil {
+				return err
+			}
+			for err == nil && ff.Next() {
+				err = write(ff.Fq())
+			}
+			if err == nil {
+				err = ff.Err()
+			}
+			ff.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(filenames)%2 != 0 {
+		return fastq.ErrUnPairInputFile
+	}
+	for i := 0; i < len(filenames); i += 2 {
+		pf, err := fastq.OpenPair(filenames[i], filenames[i+1])
+		if err != nil {
+			return err
+		}
+		for err == nil && pf.Next() {
+			p := pf.Pair()
+			if p.Read1.MateId() != p.Read2.MateId() {
+				err = fmt.Errorf("Reads Out Of Sync: %s %s", p.Read1.Id(), p.Read2.Id())
+				break
+			}
+			err = write(p.Read1, p.Read2)
+		}
+		if err == nil {
+			err = pf.Err()
+		}
+		pf.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
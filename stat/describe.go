@@ -0,0 +1,277 @@
+// descriptive statistics of int and float values, as a slice of values or count of each value
+
+package stat
+
+import (
+	"math"
+	"sort"
+)
+
+// Number int and float types
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
+
+// Summary five-number summary with moments of values, P10 and P90 are the boxplot whiskers
+type Summary struct {
+	N        int
+	Min      float64
+	P10      float64
+	Q1       float64
+	Median   float64
+	Q3       float64
+	P90      float64
+	Max      float64
+	Mean     float64
+	SD       float64
+	Skewness float64
+}
+
+// IQR return interquartile range
+func (s *Summary) IQR() float64 {
+	return s.Q3 - s.Q1
+}
+
+// ****************************** Counts **************************************
+
+// Counts count of each value, a histogram, statistics are weighted by count,
+// same as statistics of values repeated count times
+type Counts[T Number] map[T]int
+
+// keys return sorted values of count > 0
+func (c Counts[T]) keys() []T {
+	keys := make([]T, 0, len(c))
+	for v, n := range c {
+		if n > 0 {
+			keys = append(keys, v)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+// Items return count of all values
+func (c Counts[T]) Items() int {
+	n := 0
+	for _, count := range c {
+		if count > 0 {
+			n += count
+		}
+	}
+	return n
+}
+
+func (c Counts[T]) Sum() float64 {
+	sum := 0.0
+	for _, v := range c.keys() { // sorted, float sum not depend on map order
+		sum += float64(v) * float64(c[v])
+	}
+	return sum
+}
+
+func (c Counts[T]) Mean() float64 {
+	n := c.Items()
+	if n == 0 {
+		return 0
+	}
+	return c.Sum() / float64(n)
+}
+
+// moment return the kth central moment
+func (c Counts[T]) moment(mean float64, k int) float64 {
+	n := c.Items()
+	if n == 0 {
+		return 0
+	}
+	m := 0.0
+	for _, v := range c.keys() {
+		m += math.Pow(float64(v)-mean, float64(k)) * float64(c[v])
+	}
+	return m / float64(n)
+}
+
+// Variance return sample variance, divided by n-1, 0 if less than 2 values
+func (c Counts[T]) Variance() float64 {
+	n := c.Items()
+	if n < 2 {
+		return 0
+	}
+	return c.moment(c.Mean(), 2) * float64(n) / float64(n-1)
+}
+
+// SD return sample standard deviation
+func (c Counts[T]) SD() float64 {
+	return math.Sqrt(c.Variance())
+}
+
+// Skewness return population skewness m3/m2^1.5, 0 if all values are the same
+func (c Counts[T]) Skewness() float64 {
+	mean := c.Mean()
+	m2 := c.moment(mean, 2)
+	if m2 == 0 {
+		return 0
+	}
+	return c.moment(mean, 3) / math.Pow(m2, 1.5)
+}
+
+// Min return the min value, 0 if empty
+func (c Counts[T]) Min() T {
+	var min T
+	first := true
+	for v, n := range c {
+		if n > 0 && (first || v < min) {
+			min, first = v, false
+		}
+	}
+	return min
+}
+
+// Max return the max value, 0 if empty
+func (c Counts[T]) Max() T {
+	var max T
+	first := true
+	for v, n := range c {
+		if n > 0 && (first || v > max) {
+			max, first = v, false
+		}
+	}
+	return max
+}
+
+// Mode return the most frequent value, the smallest one if tie, 0 if empty
+func (c Counts[T]) Mode() T {
+	var mode T
+	best := 0
+	for _, v := range c.keys() {
+		if c[v] > best {
+			mode, best = v, c[v]
+		}
+	}
+	return mode
+}
+
+// percentile return percentile of sorted keys, interpolate between the closest ranks
+func (c Counts[T]) percentile(keys []T, n int, p float64) float64 {
+	if n == 0 {
+		return 0
+	}
+	at := func(rank int) float64 {
+		count := -1
+		for _, v := range keys {
+			count += c[v]
+			if count >= rank {
+				return float64(v)
+			}
+		}
+		return float64(keys[len(keys)-1])
+	}
+	k := float64(n-1) * p
+	f := math.Floor(k)
+	ce := math.Ceil(k)
+	if f == ce {
+		return at(int(k))
+	}
+	return at(int(f))*(ce-k) + at(int(ce))*(k-f)
+}
+
+// Percentile return percentile p (0-1), same as IntMap.Percentile
+func (c Counts[T]) Percentile(p float64) float64 {
+	return c.percentile(c.keys(), c.Items(), p)
+}
+
+func (c Counts[T]) Median() float64 {
+	return c.Percentile(0.5)
+}
+
+// IQR return interquartile range
+func (c Counts[T]) IQR() float64 {
+	keys, n := c.keys(), c.Items()
+	return c.percentile(keys, n, 0.75) - c.percentile(keys, n, 0.25)
+}
+
+// Summary return summary of all values, keys are sorted once
+func (c Counts[T]) Summary() *Summary {
+	keys, n := c.keys(), c.Items()
+	s := &Summary{N: n, Mean: c.Mean(), SD: c.SD(), Skewness: c.Skewness()}
+	if n == 0 {
+		return s
+	}
+	s.Min, s.Max = float64(keys[0]), float64(keys[len(keys)-1])
+	s.P10 = c.percentile(keys, n, 0.10)
+	s.Q1 = c.percentile(keys, n, 0.25)
+	s.Median = c.percentile(keys, n, 0.5)
+	s.Q3 = c.percentile(keys, n, 0.75)
+	s.P90 = c.percentile(keys, n, 0.90)
+	return s
+}
+
+// ****************************** Values **************************************
+
+// Values values not sorted, statistics are computed from count of each value
+type Values[T Number] []T
+
+// Counts return count of each value
+func (s Values[T]) Counts() Counts[T] {
+	c := make(Counts[T])
+	for _, v := range s {
+		c[v]++
+	}
+	return c
+}
+
+func (s Values[T]) Sum() float64 {
+	sum := 0.0
+	for _, v := range s {
+		sum += float64(v)
+	}
+	return sum
+}
+
+func (s Values[T]) Mean() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s.Sum() / float64(len(s))
+}
+
+func (s Values[T]) Variance() float64 {
+	return s.Counts().Variance()
+}
+
+func (s Values[T]) SD() float64 {
+	return s.Counts().SD()
+}
+
+func (s Values[T]) Skewness() float64 {
+	return s.Counts().Skewness()
+}
+
+func (s Values[T]) Min() T {
+	return s.Counts().Min()
+}
+
+func (s Values[T]) Max() T {
+	return s.Counts().Max()
+}
+
+func (s Values[T]) Mode() T {
+	return s.Counts().Mode()
+}
+
+func (s Values[T]) Percentile(p float64) float64 {
+	return s.Counts().Percentile(p)
+}
+
+func (s Values[T]) Median() float64 {
+	return s.Percentile(0.5)
+}
+
+func (s Values[T]) IQR() float64 {
+	return s.Counts().IQR()
+}
+
+func (s Values[T]) Summary() *Summary {
+	return s.Counts().Summary()
+}
@@ -0,0 +1,60 @@
+package stat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	s := Values[float64]{2, 4, 4, 4, 5, 5, 7, 9}
+	if mean := s.Mean(); mean != 5 {
+		t.Errorf("Mean expect: %v get: %v", 5, mean)
+	}
+	if v := s.Variance(); v != 32.0/7 {
+		t.Errorf("Variance expect: %v get: %v", 32.0/7, v)
+	}
+	if sd := s.SD(); sd != math.Sqrt(32.0/7) {
+		t.Errorf("SD expect: %v get: %v", math.Sqrt(32.0/7), sd)
+	}
+	if mode := s.Mode(); mode != 4 {
+		t.Errorf("Mode expect: %v get: %v", 4, mode)
+	}
+	if min, max := s.Min(), s.Max(); min != 2 || max != 9 {
+		t.Errorf("Min Max expect: %v %v get: %v %v", 2, 9, min, max)
+	}
+	if iqr := s.IQR(); iqr != 5.5-4 {
+		t.Errorf("IQR expect: %v get: %v", 1.5, iqr)
+	}
+	if skew := s.Skewness(); math.Abs(skew-0.65625) > 1e-12 {
+		t.Errorf("Skewness expect: %v get: %v", 0.65625, skew)
+	}
+	if skew := (Values[int]{1, 2, 3}).Skewness(); skew != 0 {
+		t.Errorf("Skewness expect: %v get: %v", 0, skew)
+	}
+}
+
+func TestCountsSummary(t *testing.T) {
+	values := Values[int]{43, 54, 56, 61, 62, 66, 68, 69, 69, 70, 71, 72, 77, 78, 79, 85, 87, 88, 89, 93, 95, 96, 98, 99, 99}
+	m := NewIntMap(map[int]int{43: 1, 54: 1, 56: 1, 61: 1, 62: 1, 66: 1, 68: 1, 69: 2, 70: 1, 71: 1, 72: 1, 77: 1, 78: 1, 79: 1, 85: 1, 87: 1, 88: 1, 89: 1, 93: 1, 95: 1, 96: 1, 98: 1, 99: 2})
+
+	s := m.Summary()
+	expect := &Summary{N: 25, Min: 43, P10: m.Percentile(.1), Q1: 68, Median: 77, Q3: m.Percentile(.75), P90: 97.2, Max: 99,
+		Mean: m.Mean(), SD: values.SD(), Skewness: values.Skewness()}
+	if *s != *expect {
+		t.Errorf("Summary expect: %v get: %v", *expect, *s)
+	}
+	if vs := values.Summary(); *vs != *s {
+		t.Errorf("Values Summary expect: %v get: %v", *s, *vs)
+	}
+	if s.IQR() != s.Q3-s.Q1 {
+		t.Errorf("IQR expect: %v get: %v", s.Q3-s.Q1, s.IQR())
+	}
+	if mode := m.Mode(); mode != 69 {
+		t.Errorf("Mode expect: %v get: %v", 69, mode)
+	}
+
+	empty := Counts[uint8]{}
+	if s := empty.Summary(); s.N != 0 || s.Max != 0 || s.SD != 0 {
+		t.Errorf("empty Summary expect: %v get: %v", Summary{}, *s)
+	}
+}
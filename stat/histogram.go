@@ -0,0 +1,163 @@
+package stat
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrHistogramRange = errors.New("Histogram need min < max and bins > 0")
+	ErrHistogramMerge = errors.New("Merge Histogram of different bins")
+)
+
+// Histogram count values in fixed bins of [Min, Max), memory is bounded by bins number,
+// values out of range are counted in underflow and overflow, percentile is interpolated in bins
+type Histogram struct {
+	Min       float64
+	Max       float64
+	counts    []uint64
+	underflow uint64
+	overflow  uint64
+	n         uint64
+	sum       float64
+	min       float64  // min value added
+	max       float64  // max value added
+	cumulates []uint64 // cumulative counts of bins, nil after any update
+}
+
+func NewHistogram(min, max float64, bins int) (*Histogram, error) {
+	if !(min < max) || bins <= 0 {
+		return nil, ErrHistogramRange
+	}
+	return &Histogram{Min: min, Max: max, counts: make([]uint64, bins)}, nil
+}
+
+// Bins return bins number
+func (h *Histogram) Bins() int {
+	return len(h.counts)
+}
+
+func (h *Histogram) width() float64 {
+	return (h.Max - h.Min) / float64(len(h.counts))
+}
+
+// Add add a value
+func (h *Histogram) Add(v float64) {
+	h.AddN(v, 1)
+}
+
+// AddN add value v count times
+func (h *Histogram) AddN(v float64, count uint64) {
+	if count == 0 {
+		return
+	}
+	if h.n == 0 || v < h.min {
+		h.min = v
+	}
+	if h.n == 0 || v > h.max {
+		h.max = v
+	}
+	h.n += count
+	h.sum += v * float64(count)
+	h.cumulates = nil
+
+	switch {
+	case v < h.Min:
+		h.underflow += count
+	case v >= h.Max:
+		h.overflow += count
+	default:
+		i := int((v - h.Min) / h.width())
+		if i >= len(h.counts) { // float rounding of v close to Max
+			i = len(h.counts) - 1
+		}
+		h.counts[i] += count
+	}
+}
+
+// Count return values number added
+func (h *Histogram) Count() uint64 {
+	return h.n
+}
+
+// Underflow return count of values < Min
+func (h *Histogram) Underflow() uint64 {
+	return h.underflow
+}
+
+// Overflow return count of values >= Max
+func (h *Histogram) Overflow() uint64 {
+	return h.overflow
+}
+
+// At return count of bin i
+func (h *Histogram) At(i int) uint64 {
+	return h.counts[i]
+}
+
+func (h *Histogram) Mean() float64 {
+	if h.n == 0 {
+		return 0
+	}
+	return h.sum / float64(h.n)
+}
+
+func (h *Histogram) Median() float64 {
+	return h.Percentile(0.5)
+}
+
+// Percentile return value of rank p * count, interpolated linearly in the bin,
+// underflow and overflow values are taken as the min and max value added
+func (h *Histogram) Percentile(p float64) float64 {
+	if h.n == 0 {
+		return 0
+	}
+	if h.cumulates == nil {
+		h.cumulates = make([]uint64, len(h.counts))
+		c := h.underflow
+		for i, count := range h.counts {
+			c += count
+			h.cumulates[i] = c
+		}
+	}
+
+	rank := p * float64(h.n)
+	if rank <= float64(h.underflow) {
+		return h.min
+	}
+	for i, c := range h.cumulates {
+		if float64(c) < rank || h.counts[i] == 0 {
+			continue
+		}
+		lo := h.Min + float64(i)*h.width()
+		frac := (rank - float64(c-h.counts[i])) / float64(h.counts[i])
+		v := lo + frac*h.width()
+		return math.Max(h.min, math.Min(h.max, v))
+	}
+	return h.max
+}
+
+// Merge add counts of other into h, both must have the same bins
+func (h *Histogram) Merge(other *Histogram) error {
+	if h.Min != other.Min || h.Max != other.Max || len(h.counts) != len(other.counts) {
+		return ErrHistogramMerge
+	}
+	if other.n == 0 {
+		return nil
+	}
+	if h.n == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if h.n == 0 || other.max > h.max {
+		h.max = other.max
+	}
+	for i, count := range other.counts {
+		h.counts[i] += count
+	}
+	h.underflow += other.underflow
+	h.overflow += other.overflow
+	h.n += other.n
+	h.sum += other.sum
+	h.cumulates = nil
+	return nil
+}
@@ -0,0 +1,49 @@
+package stat
+
+import "testing"
+
+func TestHistogram(t *testing.T) {
+	if _, err := NewHistogram(1, 1, 10); err != ErrHistogramRange {
+		t.Errorf("NewHistogram expect: %v get: %v", ErrHistogramRange, err)
+	}
+
+	h, _ := NewHistogram(0, 100, 100)
+	for i := 0; i < 100; i++ {
+		h.Add(float64(i) + 0.5)
+	}
+	if mean := h.Mean(); mean != 50 {
+		t.Errorf("Mean expect: %v get: %v", 50, mean)
+	}
+	if median := h.Median(); median != 50 {
+		t.Errorf("Median expect: %v get: %v", 50, median)
+	}
+
+	// percentile must follow values added after the last query
+	h.AddN(1000, 100)
+	h.Add(-5)
+	if h.Overflow() != 100 || h.Underflow() != 1 || h.Count() != 201 {
+		t.Errorf("Overflow Underflow Count expect: %v get: %v %v %v", "100 1 201", h.Overflow(), h.Underflow(), h.Count())
+	}
+	if median := h.Median(); median != 99.5 {
+		t.Errorf("Median after Add expect: %v get: %v", 99.5, median)
+	}
+	if p := h.Percentile(0); p != -5 {
+		t.Errorf("Percentile(0) expect: %v get: %v", -5, p)
+	}
+	if p := h.Percentile(1); p != 1000 {
+		t.Errorf("Percentile(1) expect: %v get: %v", 1000, p)
+	}
+
+	other, _ := NewHistogram(0, 100, 100)
+	other.AddN(10.5, 200)
+	if err := h.Merge(other); err != nil {
+		t.Errorf("Merge expect: %v get: %v", nil, err)
+	}
+	if median := h.Median(); median < 10 || median > 11 {
+		t.Errorf("Median after Merge expect: %v get: %v", "10-11", median)
+	}
+	bad, _ := NewHistogram(0, 100, 50)
+	if err := h.Merge(bad); err != ErrHistogramMerge {
+		t.Errorf("Merge expect: %v get: %v", ErrHistogramMerge, err)
+	}
+}
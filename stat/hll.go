@@ -0,0 +1,157 @@
+// HyperLogLog cardinality estimation, see Flajolet et al. 2007, registers are
+// estimated by the improved estimator of Ertl 2017, which is nearly unbiased
+// from small to large range without the empirical bias tables of Heule et al. 2013
+
+package stat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/bits"
+)
+
+const (
+	HLL_MIN_PRECISION = 4
+	HLL_MAX_PRECISION = 18
+)
+
+var (
+	ErrHLLPrecision = errors.New("HyperLogLog precision out of [4, 18]")
+	ErrHLLMerge     = errors.New("Merge HyperLogLog of different precision")
+)
+
+// HLL estimate distinct items number in 2^p bytes, standard error is about 1.04/sqrt(2^p)
+type HLL struct {
+	p         uint8
+	registers []uint8
+}
+
+func NewHLL(p int) (*HLL, error) {
+	if p < HLL_MIN_PRECISION || p > HLL_MAX_PRECISION {
+		return nil, ErrHLLPrecision
+	}
+	return &HLL{p: uint8(p), registers: make([]uint8, 1<<uint(p))}, nil
+}
+
+// Precision return the precision p, 2^p registers
+func (h *HLL) Precision() int {
+	return int(h.p)
+}
+
+// hash64 fnv-1a hash with the murmur3 finalizer, fnv alone mixes short keys poorly
+func hash64(data []byte) uint64 {
+	f := fnv.New64a()
+	f.Write(data)
+	x := f.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Add add an item
+func (h *HLL) Add(data []byte) {
+	x := hash64(data)
+	i := x >> (64 - h.p)
+	rank := uint8(bits.LeadingZeros64(x<<h.p|1<<(h.p-1))) + 1
+	if rank > h.registers[i] {
+		h.registers[i] = rank
+	}
+}
+
+// hllSigma sigma(x) = x + sum(x^(2^k) * 2^(k-1)) of Ertl 2017, correction of zero registers
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		last := z
+		z += x * y
+		y += y
+		if z == last {
+			return z
+		}
+	}
+}
+
+// hllTau tau(x) = (1 - x - sum((1 - x^(2^-k))^2 * 2^-k)) / 3 of Ertl 2017, correction of
+// saturated registers
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		last := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == last {
+			return z / 3
+		}
+	}
+}
+
+// Estimate return estimated distinct items number
+func (h *HLL) Estimate() float64 {
+	q := 64 - int(h.p) // max rank is q + 1
+	ranks := make([]int, q+2)
+	for _, r := range h.registers {
+		ranks[r]++
+	}
+	m := float64(len(h.registers))
+	z := m * hllTau(1-float64(ranks[q+1])/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(ranks[k]))
+	}
+	z += m * hllSigma(float64(ranks[0])/m)
+	return m * m / (2 * math.Ln2 * z)
+}
+
+// Merge merge other into h, h then estimate the union
+func (h *HLL) Merge(other *HLL) error {
+	if h.p != other.p {
+		return ErrHLLMerge
+	}
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+type hllJSON struct {
+	P         int    `json:"p"`
+	Registers []byte `json:"registers"`
+}
+
+func (h *HLL) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&hllJSON{P: int(h.p), Registers: h.registers})
+}
+
+func (h *HLL) UnmarshalJSON(data []byte) error {
+	hj := &hllJSON{}
+	if err := json.Unmarshal(data, hj); err != nil {
+		return err
+	}
+	if hj.P < HLL_MIN_PRECISION || hj.P > HLL_MAX_PRECISION || len(hj.Registers) != 1<<uint(hj.P) {
+		return ErrHLLPrecision
+	}
+	for _, r := range hj.Registers {
+		if int(r) > 64-hj.P+1 {
+			return fmt.Errorf("HyperLogLog register out of range: %d", r)
+		}
+	}
+	h.p, h.registers = uint8(hj.P), hj.Registers
+	return nil
+}
@@ -0,0 +1,85 @@
+package stat
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+)
+
+func TestHLL(t *testing.T) {
+	if _, err := NewHLL(3); err != ErrHLLPrecision {
+		t.Errorf("NewHLL(3) expect: %v get: %v", ErrHLLPrecision, err)
+	}
+
+	for _, n := range []int{0, 10, 1000, 100000} {
+		h, _ := NewHLL(14)
+		for i := 0; i < n; i++ {
+			item := []byte("item" + strconv.Itoa(i))
+			h.Add(item)
+			h.Add(item) // duplicates not counted
+		}
+		if e := h.Estimate(); math.Abs(e-float64(n)) > 0.03*float64(n)+1 {
+			t.Errorf("Estimate of %d expect: %v get: %v", n, n, e)
+		}
+	}
+}
+
+func TestHLLBias(t *testing.T) {
+	// between 2.5m and 5m, after linear counting, the raw estimate of Flajolet et al. is biased
+	for _, n := range []int{40000, 45000, 50000, 60000, 70000, 80000} {
+		sum := 0.0
+		runs := 10
+		for s := 0; s < runs; s++ {
+			h, _ := NewHLL(14)
+			for i := 0; i < n; i++ {
+				h.Add([]byte(strconv.Itoa(s) + "item" + strconv.Itoa(i)))
+			}
+			sum += h.Estimate()
+		}
+		if bias := sum/float64(runs)/float64(n) - 1; math.Abs(bias) > 0.005 {
+			t.Errorf("Estimate bias of %d expect: %v get: %v", n, "< 0.005", bias)
+		}
+	}
+}
+
+func TestHLLMerge(t *testing.T) {
+	h1, _ := NewHLL(12)
+	h2, _ := NewHLL(12)
+	for i := 0; i < 20000; i++ {
+		if i < 15000 {
+			h1.Add([]byte(strconv.Itoa(i)))
+		}
+		if i >= 5000 {
+			h2.Add([]byte(strconv.Itoa(i)))
+		}
+	}
+	if err := h1.Merge(h2); err != nil {
+		t.Fatal(err)
+	}
+	if e := h1.Estimate(); math.Abs(e-20000) > 0.05*20000 {
+		t.Errorf("Merge Estimate expect: %v get: %v", 20000, e)
+	}
+
+	h3, _ := NewHLL(10)
+	if err := h1.Merge(h3); err != ErrHLLMerge {
+		t.Errorf("Merge expect: %v get: %v", ErrHLLMerge, err)
+	}
+
+	data, err := json.Marshal(h1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h4 := &HLL{}
+	if err := json.Unmarshal(data, h4); err != nil {
+		t.Fatal(err)
+	}
+	if h4.Estimate() != h1.Estimate() {
+		t.Errorf("json Estimate expect: %v get: %v", h1.Estimate(), h4.Estimate())
+	}
+	h4.registers[0] = 64
+	data, _ = json.Marshal(h4)
+	if err := json.Unmarshal(data, &HLL{}); err == nil {
+		t.Errorf("json register expect: %v get: %v", "error", err)
+	}
+}
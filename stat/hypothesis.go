@@ -0,0 +1,182 @@
+// hypothesis tests to compare count distributions, p-values are asymptotic,
+// fine for the read numbers of sequencing
+
+package stat
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrTestLength = errors.New("Observed and expected of different length")
+	ErrTestEmpty  = errors.New("Test on empty data")
+)
+
+// TestResult statistic and p-value of a test, DF is 0 if test has no degrees of freedom
+type TestResult struct {
+	Statistic float64
+	DF        int
+	PValue    float64
+}
+
+// gammaPQ return the regularized lower and upper incomplete gamma function P(a, x) and
+// Q(a, x) = 1 - P(a, x), the smaller is computed directly, not cancelled by 1 - the other
+func gammaPQ(a, x float64) (float64, float64) {
+	if x <= 0 {
+		return 0, 1
+	}
+	lg, _ := math.Lgamma(a)
+	if x < a+1 { // series
+		sum, term := 1/a, 1/a
+		for n := 1.0; n < 1000; n++ {
+			term *= x / (a + n)
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		p := sum * math.Exp(-x+a*math.Log(x)-lg)
+		return p, 1 - p
+	}
+	// continued fraction of Q(a, x) by modified Lentz
+	tiny := 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1.0; i < 1000; i++ {
+		an := -i * (i - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < 1e-15 {
+			break
+		}
+	}
+	q := math.Exp(-x+a*math.Log(x)-lg) * h
+	return 1 - q, q
+}
+
+// ChiSquareSF return P(X >= x) of X ~ chi-square distribution of df degrees of freedom
+func ChiSquareSF(x float64, df int) float64 {
+	if df <= 0 {
+		return math.NaN()
+	}
+	if math.IsInf(x, 1) {
+		return 0
+	}
+	_, q := gammaPQ(float64(df)/2, x/2)
+	return math.Max(0, q)
+}
+
+// ChiSquareGOF test observed counts fit expected, expected can be counts or proportions,
+// scaled to the observed total, cells expected 0 and observed 0 are skipped
+func ChiSquareGOF(observed, expected []float64) (*TestResult, error) {
+	if len(observed) != len(expected) {
+		return nil, ErrTestLength
+	}
+	n, e := 0.0, 0.0
+	for i := range observed {
+		n, e = n+observed[i], e+expected[i]
+	}
+	if n == 0 || e == 0 {
+		return nil, ErrTestEmpty
+	}
+
+	chi, cells := 0.0, 0
+	for i, o := range observed {
+		exp := expected[i] * n / e
+		if exp == 0 {
+			if o > 0 {
+				chi = math.Inf(1)
+				cells++
+			}
+			continue
+		}
+		chi += (o - exp) * (o - exp) / exp
+		cells++
+	}
+	if cells < 2 {
+		return &TestResult{Statistic: chi, PValue: 1}, nil
+	}
+	return &TestResult{Statistic: chi, DF: cells - 1, PValue: ChiSquareSF(chi, cells-1)}, nil
+}
+
+// kolmogorovSF return P(K > x) of the Kolmogorov distribution
+func kolmogorovSF(x float64) float64 {
+	if x < 0.2 { // series converge slowly, the value is 1 in float64
+		return 1
+	}
+	sum := 0.0
+	for k := 1.0; k < 100; k++ {
+		term := math.Exp(-2 * k * k * x * x)
+		if int(k)%2 == 0 {
+			term = -term
+		}
+		sum += term
+		if term < 1e-16 && term > -1e-16 {
+			break
+		}
+	}
+	return math.Max(0, math.Min(1, 2*sum))
+}
+
+// KSTwoSample Kolmogorov-Smirnov test two count distributions are the same, Statistic
+// is the max distance D of the two cumulative distributions
+func KSTwoSample(a, b *IntMap) (*TestResult, error) {
+	na, nb := a.Items(), b.Items()
+	if na == 0 || nb == 0 {
+		return nil, ErrTestEmpty
+	}
+	union := make(Counts[int])
+	for _, m := range []*IntMap{a, b} {
+		for key, count := range m.Data {
+			if count > 0 {
+				union[key] = 1
+			}
+		}
+	}
+
+	d := 0.0
+	ca, cb := 0, 0
+	for _, key := range union.keys() {
+		if count := a.Data[key]; count > 0 {
+			ca += count
+		}
+		if count := b.Data[key]; count > 0 {
+			cb += count
+		}
+		d = math.Max(d, math.Abs(float64(ca)/float64(na)-float64(cb)/float64(nb)))
+	}
+	en := math.Sqrt(float64(na) * float64(nb) / float64(na+nb))
+	return &TestResult{Statistic: d, PValue: kolmogorovSF((en + 0.12 + 0.11/en) * d)}, nil
+}
+
+// NormalQuantile return x of P(X <= x) = p, X ~ standard normal distribution
+func NormalQuantile(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
+}
+
+// BinomialCI return Wilson score interval of rate k/n at confidence level, eg. 0.95,
+// return 0, 1 if n is 0
+func BinomialCI(k, n int, confidence float64) (float64, float64) {
+	if n <= 0 {
+		return 0, 1
+	}
+	z := NormalQuantile(1 - (1-confidence)/2)
+	p := float64(k) / float64(n)
+	nf := float64(n)
+	center := (p + z*z/(2*nf)) / (1 + z*z/nf)
+	half := z / (1 + z*z/nf) * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf))
+	return math.Max(0, center-half), math.Min(1, center+half)
+}
@@ -0,0 +1,75 @@
+package stat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestChiSquareGOF(t *testing.T) {
+	// a die rolled 60 times
+	r, err := ChiSquareGOF([]float64{5, 8, 9, 8, 10, 20}, []float64{1, 1, 1, 1, 1, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Statistic != 13.4 || r.DF != 5 || math.Abs(r.PValue-0.019905) > 1e-5 {
+		t.Errorf("ChiSquareGOF expect: %v get: %v", TestResult{13.4, 5, 0.019905}, *r)
+	}
+	if p := ChiSquareSF(3.841459, 1); math.Abs(p-0.05) > 1e-6 {
+		t.Errorf("ChiSquareSF expect: %v get: %v", 0.05, p)
+	}
+	if p := ChiSquareSF(200, 3); p > 1e-40 {
+		t.Errorf("ChiSquareSF expect: %v get: %v", "< 1e-40", p)
+	}
+	// P(X >= x) = exp(-x/2) of 2 degrees, exp(-x/2) * (1 + x/2) of 4 degrees
+	for _, c := range []struct {
+		x      float64
+		df     int
+		expect float64
+	}{
+		{1, 2, math.Exp(-0.5)},
+		{90, 2, math.Exp(-45)},
+		{90, 4, math.Exp(-45) * 46},
+		{1000, 2, math.Exp(-500)},
+	} {
+		if p := ChiSquareSF(c.x, c.df); math.Abs(p-c.expect) > c.expect*1e-10 {
+			t.Errorf("ChiSquareSF(%v, %d) expect: %v get: %v", c.x, c.df, c.expect, p)
+		}
+	}
+	if _, err := ChiSquareGOF([]float64{1, 2}, []float64{1}); err != ErrTestLength {
+		t.Errorf("ChiSquareGOF expect: %v get: %v", ErrTestLength, err)
+	}
+	if r, _ := ChiSquareGOF([]float64{1, 2, 0}, []float64{1, 2, 0}); r.PValue != 1 || r.DF != 1 {
+		t.Errorf("ChiSquareGOF expect: %v get: %v", TestResult{0, 1, 1}, *r)
+	}
+}
+
+func TestKSTwoSample(t *testing.T) {
+	a := NewIntMap(map[int]int{30: 100, 35: 100, 40: 100})
+	if r, _ := KSTwoSample(a, a); r.Statistic != 0 || r.PValue != 1 {
+		t.Errorf("KSTwoSample same expect: %v get: %v", TestResult{0, 0, 1}, *r)
+	}
+	b := NewIntMap(map[int]int{20: 100, 30: 100, 35: 100})
+	r, _ := KSTwoSample(a, b)
+	if math.Abs(r.Statistic-1.0/3) > 1e-12 || r.PValue > 1e-10 {
+		t.Errorf("KSTwoSample expect: %v get: %v", "D 1/3, p < 1e-10", *r)
+	}
+	if p := kolmogorovSF(1.36); math.Abs(p-0.0494) > 1e-3 {
+		t.Errorf("kolmogorovSF expect: %v get: %v", 0.0494, p)
+	}
+	if _, err := KSTwoSample(a, NewIntMap(map[int]int{})); err != ErrTestEmpty {
+		t.Errorf("KSTwoSample expect: %v get: %v", ErrTestEmpty, err)
+	}
+}
+
+func TestBinomialCI(t *testing.T) {
+	lo, hi := BinomialCI(81, 263, 0.95)
+	if math.Abs(lo-0.255289) > 1e-6 || math.Abs(hi-0.366210) > 1e-6 {
+		t.Errorf("BinomialCI expect: %v %v get: %v %v", 0.255289, 0.366210, lo, hi)
+	}
+	if lo, hi := BinomialCI(0, 10, 0.95); lo != 0 || hi <= 0 {
+		t.Errorf("BinomialCI expect: %v get: %v %v", "0 > 0", lo, hi)
+	}
+	if z := NormalQuantile(0.975); math.Abs(z-1.959964) > 1e-6 {
+		t.Errorf("NormalQuantile expect: %v get: %v", 1.959964, z)
+	}
+}
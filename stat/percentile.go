@@ -56,39 +56,49 @@ func (s *IntSlice) Median() float64 {
 	return s.Percentile(0.5)
 }
 
+// Summary return descriptive statistics of data, data need not be sorted
+func (s *IntSlice) Summary() *Summary {
+	return Values[int](s.Data).Summary()
+}
+
+// Distribution summary of values, implemented by IntSlice, IntMap, Histogram and TDigest
+type Distribution interface {
+	Percentile(p float64) float64
+	Mean() float64
+	Median() float64
+}
+
+// IntMap count of each int value, Data can be changed any time, nothing is cached
 type IntMap struct {
 	Data map[int]int
-	keys []int
-	vals []int
 }
 
 func NewIntMap(data map[int]int) *IntMap {
 	return &IntMap{Data: data}
 }
 
+// Add add count of key
+func (m *IntMap) Add(key, count int) {
+	m.Data[key] += count
+}
+
 // Keys return sorted keys
 func (m *IntMap) Keys() []int {
-	if m.keys != nil {
-		return m.keys
-	}
-	keys := []int{}
+	keys := make([]int, 0, len(m.Data))
 	for key := range m.Data {
 		keys = append(keys, key)
 	}
 	sort.Ints(keys)
-	m.keys = keys
 	return keys
 }
 
+// Vals return counts in order of sorted keys
 func (m *IntMap) Vals() []int {
-	if m.vals != nil {
-		return m.vals
-	}
-	vals := make([]int, len(m.Data))
-	for i, key := range m.Keys() {
+	keys := m.Keys()
+	vals := make([]int, len(keys))
+	for i, key := range keys {
 		vals[i] = m.Data[key]
 	}
-	m.vals = vals
 	return vals
 }
 
@@ -100,9 +110,10 @@ func (m *IntMap) Items() int {
 	return n
 }
 
-func (m *IntMap) percentKey(i int) int {
+// percentKey return key of rank i (start from 0), keys are sorted keys
+func (m *IntMap) percentKey(keys []int, i int) int {
 	count := -1
-	for _, key := range m.Keys() {
+	for _, key := range keys {
 		count += m.Data[key]
 		if count >= i {
 			return key
@@ -115,23 +126,24 @@ func (m *IntMap) Percentile(p float64) float64 {
 	if len(m.Data) == 0 {
 		return 0
 	}
+	keys := m.Keys()
 	n := m.Items()
 	k := float64(n-1) * p
 	f := math.Floor(k)
 	c := math.Ceil(k)
 	if f == c {
-		return float64(m.percentKey(int(k)))
+		return float64(m.percentKey(keys, int(k)))
 	}
 
-	d0 := float64(m.percentKey(int(f))) * (c - k)
-	d1 := float64(m.percentKey(int(c))) * (k - f)
+	d0 := float64(m.percentKey(keys, int(f))) * (c - k)
+	d1 := float64(m.percentKey(keys, int(c))) * (k - f)
 	return d0 + d1
 }
 
 func (m *IntMap) Sum() int {
 	sum := 0
-	for _, key := range m.Keys() {
-		sum += key * m.Data[key]
+	for key, count := range m.Data {
+		sum += key * count
 	}
 	return sum
 }
@@ -146,3 +158,13 @@ func (m *IntMap) Mean() float64 {
 func (m *IntMap) Median() float64 {
 	return m.Percentile(0.5)
 }
+
+// Summary return descriptive statistics weighted by count
+func (m *IntMap) Summary() *Summary {
+	return Counts[int](m.Data).Summary()
+}
+
+// Mode return the most frequent key, the smallest one if tie
+func (m *IntMap) Mode() int {
+	return Counts[int](m.Data).Mode()
+}
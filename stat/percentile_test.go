@@ -161,3 +161,38 @@ func TestIntMap3(t *testing.T) {
 		t.Errorf("percentile95 expect: %v get: %v", 98.799999999999997, p95)
 	}
 }
+
+func TestIntMapUpdate(t *testing.T) {
+	m := NewIntMap(map[int]int{1: 1, 2: 1, 3: 1})
+	if median := m.Median(); median != 2 {
+		t.Errorf("Median expect: %v get: %v", 2, median)
+	}
+	m.Add(10, 4)
+	m.Data[20]++
+	if keys := m.Keys(); len(keys) != 5 || keys[4] != 20 {
+		t.Errorf("Keys expect: %v get: %v", []int{1, 2, 3, 10, 20}, keys)
+	}
+	if median := m.Median(); median != 10 {
+		t.Errorf("Median expect: %v get: %v", 10, median)
+	}
+	if max := m.Percentile(1); max != 20 {
+		t.Errorf("Percentile(1) expect: %v get: %v", 20, max)
+	}
+}
+
+func TestDistribution(t *testing.T) {
+	h, _ := NewHistogram(0, 10, 10)
+	d := NewTDigest(0)
+	for _, v := range []float64{1, 2, 3} {
+		h.Add(v)
+		d.Add(v)
+	}
+	for _, dist := range []Distribution{NewIntSlice([]int{1, 2, 3}), NewIntMap(map[int]int{1: 1, 2: 1, 3: 1}), h, d} {
+		if mean := dist.Mean(); mean != 2 {
+			t.Errorf("%T Mean expect: %v get: %v", dist, 2, mean)
+		}
+		if median := dist.Median(); median < 2 || median > 2.5 {
+			t.Errorf("%T Median expect: %v get: %v", dist, "2-2.5", median)
+		}
+	}
+}
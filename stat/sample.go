@@ -0,0 +1,80 @@
+// random sampling of a fixed number of items from a stream, reservoir sampling of
+// Vitter 1985 (algorithm R) and Floyd's sampling of indexes
+
+package stat
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Reservoir keep n items sampled uniformly from all items added, memory of n items
+type Reservoir[T any] struct {
+	n       int
+	rng     *rand.Rand
+	seen    int
+	items   []T
+	indexes []int // input order of items
+}
+
+func NewReservoir[T any](n int, rng *rand.Rand) *Reservoir[T] {
+	return &Reservoir[T]{n: n, rng: rng}
+}
+
+// Add add an item, it is kept with the probability n / seen
+func (r *Reservoir[T]) Add(item T) {
+	r.seen++
+	if len(r.items) < r.n {
+		r.items = append(r.items, item)
+		r.indexes = append(r.indexes, r.seen-1)
+		return
+	}
+	if j := r.rng.Intn(r.seen); j < r.n {
+		r.items[j] = item
+		r.indexes[j] = r.seen - 1
+	}
+}
+
+// Seen return number of items added
+func (r *Reservoir[T]) Seen() int {
+	return r.seen
+}
+
+// Items return items sampled in the input order
+func (r *Reservoir[T]) Items() []T {
+	order := make([]int, len(r.items))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return r.indexes[order[i]] < r.indexes[order[j]]
+	})
+	items := make([]T, len(order))
+	for i, o := range order {
+		items[i] = r.items[o]
+	}
+	return items
+}
+
+// SampleIndexes return n distinct indexes of [0, total) sampled uniformly in ascending
+// order, all indexes if n >= total, memory of n indexes
+func SampleIndexes(total, n int, rng *rand.Rand) []int {
+	if n >= total {
+		n = total
+	}
+	if n <= 0 {
+		return []int{}
+	}
+	picked := make(map[int]bool, n)
+	indexes := make([]int, 0, n)
+	for j := total - n; j < total; j++ {
+		i := rng.Intn(j + 1)
+		if picked[i] {
+			i = j
+		}
+		picked[i] = true
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,69 @@
+package stat
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestReservoir(t *testing.T) {
+	r := NewReservoir[int](5, rand.New(rand.NewSource(1)))
+	for i := 0; i < 3; i++ {
+		r.Add(i)
+	}
+	if items := r.Items(); len(items) != 3 || items[0] != 0 || items[2] != 2 {
+		t.Errorf("Reservoir Items expect: %v get: %v", []int{0, 1, 2}, items)
+	}
+	for i := 3; i < 1000; i++ {
+		r.Add(i)
+	}
+	items := r.Items()
+	if r.Seen() != 1000 || len(items) != 5 || !sort.IntsAreSorted(items) {
+		t.Errorf("Reservoir expect: %v get: %v %v", "1000 5 sorted", r.Seen(), items)
+	}
+
+	// each item is kept with the probability n / total
+	counts := make([]int, 10)
+	rng := rand.New(rand.NewSource(2))
+	for k := 0; k < 20000; k++ {
+		r := NewReservoir[int](3, rng)
+		for i := 0; i < 10; i++ {
+			r.Add(i)
+		}
+		for _, i := range r.Items() {
+			counts[i]++
+		}
+	}
+	for i, c := range counts {
+		if math.Abs(float64(c)/20000-0.3) > 0.02 {
+			t.Errorf("Reservoir item %d frequency expect: %v get: %v", i, 0.3, float64(c)/20000)
+		}
+	}
+}
+
+func TestSampleIndexes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	if indexes := SampleIndexes(5, 10, rng); len(indexes) != 5 || indexes[4] != 4 {
+		t.Errorf("SampleIndexes expect: %v get: %v", []int{0, 1, 2, 3, 4}, indexes)
+	}
+	if indexes := SampleIndexes(5, 0, rng); len(indexes) != 0 {
+		t.Errorf("SampleIndexes expect: %v get: %v", []int{}, indexes)
+	}
+
+	counts := make([]int, 10)
+	for k := 0; k < 20000; k++ {
+		indexes := SampleIndexes(10, 4, rng)
+		for i, index := range indexes {
+			if i > 0 && index <= indexes[i-1] {
+				t.Fatalf("SampleIndexes expect: %v get: %v", "ascending distinct", indexes)
+			}
+			counts[index]++
+		}
+	}
+	for i, c := range counts {
+		if math.Abs(float64(c)/20000-0.4) > 0.02 {
+			t.Errorf("SampleIndexes index %d frequency expect: %v get: %v", i, 0.4, float64(c)/20000)
+		}
+	}
+}
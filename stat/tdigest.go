@@ -0,0 +1,199 @@
+// t-digest streaming quantile sketch, the merging variant of Dunning & Ertl 2019,
+// accurate at tails, memory is bounded by the compression
+
+package stat
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+)
+
+const TDIGEST_COMPRESSION = 100
+
+// centroid mean of weight values
+type centroid struct {
+	Mean   float64 `json:"m"`
+	Weight float64 `json:"w"`
+}
+
+// TDigest summary values in at most about compression centroids, mergeable
+type TDigest struct {
+	compression float64
+	centroids   []centroid // sorted by mean, merged
+	buffer      []centroid // values not merged yet
+	n           float64
+	sum         float64
+	min         float64
+	max         float64
+}
+
+// NewTDigest return t-digest of compression, TDIGEST_COMPRESSION if compression <= 0,
+// larger compression is more accurate and uses more memory
+func NewTDigest(compression float64) *TDigest {
+	if compression <= 0 {
+		compression = TDIGEST_COMPRESSION
+	}
+	return &TDigest{compression: compression}
+}
+
+// Add add a value
+func (t *TDigest) Add(v float64) {
+	t.AddWeight(v, 1)
+}
+
+// AddWeight add value v of weight w, eg. count of value in a histogram
+func (t *TDigest) AddWeight(v, w float64) {
+	if w <= 0 || math.IsNaN(v) {
+		return
+	}
+	if t.n == 0 || v < t.min {
+		t.min = v
+	}
+	if t.n == 0 || v > t.max {
+		t.max = v
+	}
+	t.n += w
+	t.sum += v * w
+	t.buffer = append(t.buffer, centroid{v, w})
+	if len(t.buffer) >= int(5*t.compression) {
+		t.compress()
+	}
+}
+
+// k scale function, centroids near the tails are smaller
+func (t *TDigest) k(q float64) float64 {
+	return t.compression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func (t *TDigest) kInverse(k float64) float64 {
+	return (math.Sin(2*math.Pi*k/t.compression) + 1) / 2
+}
+
+// compress merge buffer into centroids
+func (t *TDigest) compress() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	all := append(t.centroids, t.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].Mean < all[j].Mean })
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	cur := all[0]
+	wsofar := 0.0
+	limit := t.n * t.kInverse(t.k(0)+1)
+	for _, c := range all[1:] {
+		if wsofar+cur.Weight+c.Weight <= limit {
+			cur.Mean += (c.Mean - cur.Mean) * c.Weight / (cur.Weight + c.Weight)
+			cur.Weight += c.Weight
+			continue
+		}
+		wsofar += cur.Weight
+		merged = append(merged, cur)
+		limit = t.n * t.kInverse(t.k(wsofar/t.n)+1)
+		cur = c
+	}
+	t.centroids = append(merged, cur)
+	t.buffer = t.buffer[:0]
+}
+
+// Count return total weight of values added
+func (t *TDigest) Count() float64 {
+	return t.n
+}
+
+// Centroids return centroids number after merging
+func (t *TDigest) Centroids() int {
+	t.compress()
+	return len(t.centroids)
+}
+
+func (t *TDigest) Mean() float64 {
+	if t.n == 0 {
+		return 0
+	}
+	return t.sum / t.n
+}
+
+func (t *TDigest) Median() float64 {
+	return t.Percentile(0.5)
+}
+
+// Percentile return estimated value of rank p, interpolated between centroid centres,
+// the exact min and max at both ends
+func (t *TDigest) Percentile(p float64) float64 {
+	if t.n == 0 {
+		return 0
+	}
+	t.compress()
+	if p <= 0 {
+		return t.min
+	} else if p >= 1 {
+		return t.max
+	}
+
+	rank := p * t.n
+	first, last := t.centroids[0], t.centroids[len(t.centroids)-1]
+	if rank < first.Weight/2 { // between min and the first centre
+		if first.Weight == 1 {
+			return first.Mean
+		}
+		return t.min + (first.Mean-t.min)*rank/(first.Weight/2)
+	}
+	if rank > t.n-last.Weight/2 { // between the last centre and max
+		if last.Weight == 1 {
+			return last.Mean
+		}
+		return t.max - (t.max-last.Mean)*(t.n-rank)/(last.Weight/2)
+	}
+
+	wsofar := first.Weight / 2 // rank of the current centre
+	for i := 0; i < len(t.centroids)-1; i++ {
+		c, next := t.centroids[i], t.centroids[i+1]
+		gap := (c.Weight + next.Weight) / 2
+		if rank <= wsofar+gap {
+			return c.Mean + (next.Mean-c.Mean)*(rank-wsofar)/gap
+		}
+		wsofar += gap
+	}
+	return last.Mean
+}
+
+// Merge add centroids of other into t
+func (t *TDigest) Merge(other *TDigest) {
+	other.compress()
+	for _, c := range other.centroids {
+		t.AddWeight(c.Mean, c.Weight)
+	}
+	if other.n > 0 { // min max of other are exact, not centroid means
+		t.min, t.max = math.Min(t.min, other.min), math.Max(t.max, other.max)
+	}
+	t.compress()
+}
+
+type tdigestJSON struct {
+	Compression float64    `json:"compression"`
+	Centroids   []centroid `json:"centroids"`
+	Sum         float64    `json:"sum"`
+	Min         float64    `json:"min"`
+	Max         float64    `json:"max"`
+}
+
+func (t *TDigest) MarshalJSON() ([]byte, error) {
+	t.compress()
+	return json.Marshal(&tdigestJSON{Compression: t.compression, Centroids: t.centroids, Sum: t.sum, Min: t.min, Max: t.max})
+}
+
+func (t *TDigest) UnmarshalJSON(data []byte) error {
+	tj := &tdigestJSON{}
+	if err := json.Unmarshal(data, tj); err != nil {
+		return err
+	}
+	*t = *NewTDigest(tj.Compression)
+	t.centroids = tj.Centroids
+	for _, c := range t.centroids {
+		t.n += c.Weight
+	}
+	t.sum, t.min, t.max = tj.Sum, tj.Min, tj.Max
+	return nil
+}
@@ -0,0 +1,59 @@
+package stat
+
+import (
+	"encoding/json"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestTDigest(t *testing.T) {
+	d := NewTDigest(0)
+	if p := d.Percentile(0.5); p != 0 {
+		t.Errorf("empty Percentile expect: %v get: %v", 0, p)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	n := 100000
+	values := make([]float64, n)
+	parts := []*TDigest{NewTDigest(0), NewTDigest(0)}
+	for i := range values {
+		values[i] = r.NormFloat64()*50 + 300 // insert size like
+		d.Add(values[i])
+		parts[i%2].Add(values[i])
+	}
+	sort.Float64s(values)
+
+	if c := d.Centroids(); c > 2*TDIGEST_COMPRESSION {
+		t.Errorf("Centroids expect: <= %v get: %v", 2*TDIGEST_COMPRESSION, c)
+	}
+	if p := d.Percentile(0); p != values[0] {
+		t.Errorf("Percentile(0) expect: %v get: %v", values[0], p)
+	}
+	if p := d.Percentile(1); p != values[n-1] {
+		t.Errorf("Percentile(1) expect: %v get: %v", values[n-1], p)
+	}
+
+	parts[0].Merge(parts[1])
+	data, err := json.Marshal(parts[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := &TDigest{}
+	if err := json.Unmarshal(data, loaded); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []float64{0.001, 0.01, 0.25, 0.5, 0.75, 0.99, 0.999} {
+		for name, digest := range map[string]*TDigest{"": d, "Merge ": parts[0], "JSON ": loaded} {
+			// error in rank is small relative to q(1-q)
+			rank := float64(sort.SearchFloat64s(values, digest.Percentile(q))) / float64(n)
+			if math.Abs(rank-q) > 0.05*q*(1-q)+0.0002 {
+				t.Errorf("%sPercentile(%v) expect rank: %v get: %v", name, q, q, rank)
+			}
+		}
+	}
+	if math.Abs(loaded.Mean()-d.Mean()) > 1e-6 || loaded.Count() != float64(n) {
+		t.Errorf("JSON Mean Count expect: %v %v get: %v %v", d.Mean(), n, loaded.Mean(), loaded.Count())
+	}
+}
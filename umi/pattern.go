@@ -0,0 +1,211 @@
+// umi package extract UMI from read sequence by pattern and tag read name
+// in the format of umi_tools, READID_UMI
+
+package umi
+
+import (
+	"errors"
+	"fmt"
+	"gongs/biofile/fastq"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPattern = errors.New("Wrong UMI Pattern")
+	ErrNoMatch = errors.New("Read Not Match UMI Pattern")
+)
+
+const (
+	UMI     = 'N' // bases moved to UMI
+	KEEP    = 'X' // bases kept in read
+	DISCARD = 'D' // bases dropped
+	ANCHOR  = 'A' // fixed sequence dropped, matched with substitutions
+)
+
+// element a segment of pattern, fixed length if Min == Max
+type element struct {
+	Kind  byte
+	Min   int
+	Max   int
+	Seq   string // anchor sequence
+	Subst int    // substitutions allowed of anchor
+}
+
+// Pattern segments matched from the read start, bases after the last segment
+// are kept in read, syntax:
+//
+//	N X D       a UMI, kept or discarded base, repeat as NNNNNNNN
+//	N{8}        8 UMI bases, same for X and D
+//	N{6,10}     6 to 10 UMI bases, length is decided by the following anchor
+//	(ACGT)      anchor sequence, dropped from read
+//	(ACGT){s<=1} anchor allow 1 substitution
+//
+// eg. NNNNNNNNXXXX, N{8}(GAGTGATTGCTTGTGACGCCTT){s<=2}, N{6,10}(GTAC){s<=1}
+type Pattern struct {
+	text     string
+	elements []*element
+}
+
+// quantifier parse {n} or {n,m} at start of s, return min, max and length parsed
+func quantifier(s string) (int, int, int, error) {
+	if !strings.HasPrefix(s, "{") {
+		return 1, 1, 0, nil
+	}
+	end := strings.IndexByte(s, '}')
+	if end < 0 {
+		return 0, 0, 0, ErrPattern
+	}
+	parts := strings.Split(s[1:end], ",")
+	min, err := strconv.Atoi(parts[0])
+	if err != nil || len(parts) > 2 || min < 0 {
+		return 0, 0, 0, ErrPattern
+	}
+	max := min
+	if len(parts) == 2 {
+		if max, err = strconv.Atoi(parts[1]); err != nil || max < min {
+			return 0, 0, 0, ErrPattern
+		}
+	}
+	return min, max, end + 1, nil
+}
+
+// NewPattern parse pattern text
+func NewPattern(text string) (*Pattern, error) {
+	p := &Pattern{text: text}
+	s := strings.ToUpper(strings.TrimSpace(text))
+	for len(s) > 0 {
+		e := &element{Kind: s[0]}
+		switch s[0] {
+		case UMI, KEEP, DISCARD:
+			min, max, n, err := quantifier(s[1:])
+			if err != nil {
+				return nil, fmt.Errorf("%v: %s", err, text)
+			}
+			e.Min, e.Max = min, max
+			s = s[1+n:]
+		case '(':
+			end := strings.IndexByte(s, ')')
+			if end < 2 || strings.Trim(s[1:end], "ACGTN") != "" {
+				return nil, fmt.Errorf("%v: %s", ErrPattern, text)
+			}
+			e.Kind, e.Seq = ANCHOR, s[1:end]
+			e.Min, e.Max = len(e.Seq), len(e.Seq)
+			s = s[end+1:]
+			if strings.HasPrefix(s, "{S<=") {
+				close := strings.IndexByte(s, '}')
+				if close < 0 {
+					return nil, fmt.Errorf("%v: %s", ErrPattern, text)
+				}
+				subst, err := strconv.Atoi(s[len("{S<="):close])
+				if err != nil || subst < 0 {
+					return nil, fmt.Errorf("%v: %s", ErrPattern, text)
+				}
+				e.Subst = subst
+				s = s[close+1:]
+			}
+		default:
+			return nil, fmt.Errorf("%v: %s", ErrPattern, text)
+		}
+		// merge fixed elements of the same kind, eg. NNNN
+		if n := len(p.elements); n > 0 && e.Kind != ANCHOR && e.Min == e.Max {
+			if last := p.elements[n-1]; last.Kind == e.Kind && last.Min == last.Max {
+				last.Min, last.Max = last.Min+e.Min, last.Max+e.Max
+				continue
+			}
+		}
+		p.elements = append(p.elements, e)
+	}
+	if len(p.elements) == 0 {
+		return nil, fmt.Errorf("%v: %s", ErrPattern, text)
+	}
+	return p, nil
+}
+
+func (p *Pattern) String() string {
+	return p.text
+}
+
+// MinLen return the min read length can match
+func (p *Pattern) MinLen() int {
+	n := 0
+	for _, e := range p.elements {
+		n += e.Min
+	}
+	return n
+}
+
+// substs return substitutions of anchor at seq, N in anchor match any base
+func substs(anchor string, seq []byte) int {
+	n := 0
+	for i := 0; i < len(anchor); i++ {
+		if anchor[i] != 'N' && anchor[i] != seq[i]&^0x20 {
+			n++
+		}
+	}
+	return n
+}
+
+// match return lengths of elements from start of seq with the fewest anchor substitutions,
+// shorter lengths first if tie, false if no match
+func (p *Pattern) match(seq []byte) ([]int, bool) {
+	best, bestSubst := []int(nil), -1
+	lengths := make([]int, len(p.elements))
+	var try func(i, pos, subst int)
+	try = func(i, pos, subst int) {
+		if bestSubst >= 0 && subst >= bestSubst {
+			return
+		}
+		if i == len(p.elements) {
+			best, bestSubst = append([]int{}, lengths...), subst
+			return
+		}
+		e := p.elements[i]
+		for l := e.Min; l <= e.Max && pos+l <= len(seq); l++ {
+			s := subst
+			if e.Kind == ANCHOR {
+				n := substs(e.Seq, seq[pos:pos+l])
+				if n > e.Subst {
+					continue
+				}
+				s += n
+			}
+			lengths[i] = l
+			try(i+1, pos+l, s)
+		}
+	}
+	try(0, 0, 0)
+	return best, bestSubst >= 0
+}
+
+// Extract return UMI bases and the read of kept bases, ErrNoMatch if read not match,
+// the read is a new record, fq is not changed
+func (p *Pattern) Extract(fq *fastq.Fastq) (string, *fastq.Fastq, error) {
+	lengths, ok := p.match(fq.Seq)
+	if !ok {
+		return "", nil, ErrNoMatch
+	}
+	umi := []byte{}
+	kept := &fastq.Fastq{Name: fq.Name}
+	pos := 0
+	for i, e := range p.elements {
+		l := lengths[i]
+		switch e.Kind {
+		case UMI:
+			umi = append(umi, fq.Seq[pos:pos+l]...)
+		case KEEP:
+			kept.Seq = append(kept.Seq, fq.Seq[pos:pos+l]...)
+			kept.Qual = append(kept.Qual, fq.Qual[pos:pos+l]...)
+		}
+		pos += l
+	}
+	kept.Seq = append(kept.Seq, fq.Seq[pos:]...)
+	kept.Qual = append(kept.Qual, fq.Qual[pos:]...)
+	return strings.ToUpper(string(umi)), kept, nil
+}
+
+// Tag append umi to read id as umi_tools, eg. "id 1:N:0" to "id_UMI 1:N:0"
+func Tag(fq *fastq.Fastq, umi string) {
+	id := fq.Id()
+	fq.Name = id + "_" + umi + fq.Name[len(id):]
+}
@@ -0,0 +1,117 @@
+package umi
+
+import (
+	"fmt"
+	"gongs/stat"
+	"gongs/xopen"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Stats count of each UMI, and reads not match pattern
+type Stats struct {
+	counts    map[string]int
+	unmatched int
+}
+
+func NewStats() *Stats {
+	return &Stats{counts: make(map[string]int)}
+}
+
+// Add count an extracted UMI
+func (s *Stats) Add(umi string) {
+	s.counts[umi]++
+}
+
+// Unmatch count a read not match pattern
+func (s *Stats) Unmatch() {
+	s.unmatched++
+}
+
+// Reads return reads of UMI extracted
+func (s *Stats) Reads() int {
+	n := 0
+	for _, c := range s.counts {
+		n += c
+	}
+	return n
+}
+
+// Unmatched return reads not match pattern
+func (s *Stats) Unmatched() int {
+	return s.unmatched
+}
+
+// Distinct return distinct UMIs number
+func (s *Stats) Distinct() int {
+	return len(s.counts)
+}
+
+// Entropy return Shannon entropy of UMI frequencies in bits, log2 of distinct UMIs
+// if all UMIs are even, lower if some UMIs dominate
+func (s *Stats) Entropy() float64 {
+	n := float64(s.Reads())
+	h := 0.0
+	for _, umi := range s.umis() {
+		p := float64(s.counts[umi]) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// umis return UMIs sorted by count desc, then UMI
+func (s *Stats) umis() []string {
+	umis := make([]string, 0, len(s.counts))
+	for umi := range s.counts {
+		umis = append(umis, umi)
+	}
+	sort.Slice(umis, func(i, j int) bool {
+		ci, cj := s.counts[umis[i]], s.counts[umis[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return umis[i] < umis[j]
+	})
+	return umis
+}
+
+// ReadsPerUMI return distribution of reads number per UMI
+func (s *Stats) ReadsPerUMI() *stat.IntMap {
+	m := stat.NewIntMap(make(map[int]int))
+	for _, c := range s.counts {
+		m.Add(c, 1)
+	}
+	return m
+}
+
+// Save save UMI diversity, reads per UMI distribution and the top UMIs
+func (s *Stats) Save(prefix string, top int) error {
+	f, err := xopen.Xcreate(prefix+".umi", "w")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reads := s.ReadsPerUMI()
+	fmt.Fprintln(f, "#: reads:", s.Reads())
+	fmt.Fprintln(f, "#: unmatched:", s.unmatched)
+	fmt.Fprintln(f, "#: distinct:", s.Distinct())
+	fmt.Fprintf(f, "#: entropy: %.3f\n", s.Entropy())
+	fmt.Fprintf(f, "#: reads per umi: mean %.2f median %.0f max %.0f\n", reads.Mean(), reads.Median(), reads.Percentile(1))
+	fmt.Fprintln(f, "#!", strings.Repeat("=", 20), "reads per umi", strings.Repeat("=", 20))
+	fmt.Fprintln(f, "##", strings.Join([]string{"reads", "umis"}, "\t"))
+	for _, n := range reads.Keys() {
+		fmt.Fprintf(f, "%d\t%d\n", n, reads.Data[n])
+	}
+	fmt.Fprintln(f, "#!", strings.Repeat("=", 20), "top umis", strings.Repeat("=", 20))
+	fmt.Fprintln(f, "##", strings.Join([]string{"umi", "reads"}, "\t"))
+	umis := s.umis()
+	if top > 0 && len(umis) > top {
+		umis = umis[:top]
+	}
+	for _, umi := range umis {
+		fmt.Fprintf(f, "%s\t%d\n", umi, s.counts[umi])
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package umi
+
+import (
+	"gongs/biofile/fastq"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewPattern(t *testing.T) {
+	for _, c := range []struct {
+		text   string
+		minLen int
+		ok     bool
+	}{
+		{"NNNNNNNNXXXX", 12, true},
+		{"N{8}D{2}", 10, true},
+		{"N{6,10}(GTAC){s<=1}", 10, true},
+		{"nnnn(acgt)", 8, true},
+		{"", 0, false},
+		{"NNZ", 0, false},
+		{"N{8", 0, false},
+		{"N{8,4}", 0, false},
+		{"N(AC", 0, false},
+		{"N(AC){s<=x}", 0, false},
+	} {
+		p, err := NewPattern(c.text)
+		if (err == nil) != c.ok {
+			t.Errorf("NewPattern %q expect ok: %v get: %v", c.text, c.ok, err)
+			continue
+		}
+		if c.ok && p.MinLen() != c.minLen {
+			t.Errorf("NewPattern %q MinLen expect: %v get: %v", c.text, c.minLen, p.MinLen())
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	for _, c := range []struct {
+		pattern string
+		seq     string
+		umi     string
+		kept    string
+		ok      bool
+	}{
+		{"NNNNXX", "acgtGGCCCC", "ACGT", "GGCCCC", true},
+		{"NNNNDDX", "ACGTGGCCCC", "ACGT", "CCCC", true},
+		{"N{4}(GTAC)", "AAAAGTACTTTT", "AAAA", "TTTT", true},
+		{"N{4}(GTAC)", "AAAAGTTCTTTT", "", "", false},
+		{"N{4}(GTAC){s<=1}", "AAAAGTTCTTTT", "AAAA", "TTTT", true},
+		{"N{2,5}(GTAC)", "AAAGTACTTTT", "AAA", "TTTT", true},
+		{"N{2,5}(GTAC)", "AAAAAGTACTT", "AAAAA", "TT", true},
+		{"N{2,5}(GTAC){s<=1}", "AAGTACGTACTT", "AA", "GTACTT", true},
+		// the fewest substitutions win over the shorter umi
+		{"N{2,4}(GGGG){s<=1}", "TTTGGGGCC", "TTT", "CC", true},
+		{"NNNNNNNN", "ACGT", "", "", false},
+	} {
+		p, err := NewPattern(c.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fq := &fastq.Fastq{Name: "r1 1:N:0", Seq: []byte(c.seq), Qual: []byte(strings.Repeat("I", len(c.seq)))}
+		umi, kept, err := p.Extract(fq)
+		if (err == nil) != c.ok {
+			t.Errorf("Extract %s %s expect ok: %v get: %v", c.pattern, c.seq, c.ok, err)
+			continue
+		}
+		if !c.ok {
+			continue
+		}
+		if umi != c.umi || string(kept.Seq) != c.kept || len(kept.Qual) != len(kept.Seq) {
+			t.Errorf("Extract %s %s expect: %s %s get: %s %s", c.pattern, c.seq, c.umi, c.kept, umi, kept.Seq)
+		}
+		if string(fq.Seq) != c.seq {
+			t.Errorf("Extract %s read changed expect: %s get: %s", c.pattern, c.seq, fq.Seq)
+		}
+	}
+}
+
+func TestTag(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		expect string
+	}{
+		{"r1 1:N:0:ACGT", "r1_AACC 1:N:0:ACGT"},
+		{"r1", "r1_AACC"},
+		{"r1#0/1", "r1_AACC#0/1"},
+	} {
+		fq := &fastq.Fastq{Name: c.name}
+		Tag(fq, "AACC")
+		if fq.Name != c.expect {
+			t.Errorf("Tag %s expect: %s get: %s", c.name, c.expect, fq.Name)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	s := NewStats()
+	for _, umi := range []string{"AA", "AA", "AA", "CC", "GG", "TT"} {
+		s.Add(umi)
+	}
+	s.Unmatch()
+	if s.Reads() != 6 || s.Unmatched() != 1 || s.Distinct() != 4 {
+		t.Errorf("Stats expect: %v get: %v %v %v", "6 1 4", s.Reads(), s.Unmatched(), s.Distinct())
+	}
+	expect := -(0.5*math.Log2(0.5) + 3*math.Log2(1.0/6)/6)
+	if math.Abs(s.Entropy()-expect) > 1e-9 {
+		t.Errorf("Stats Entropy expect: %v get: %v", expect, s.Entropy())
+	}
+	if umis := s.umis(); strings.Join(umis, ",") != "AA,CC,GG,TT" {
+		t.Errorf("Stats umis expect: %v get: %v", "AA,CC,GG,TT", umis)
+	}
+	if m := s.ReadsPerUMI(); m.Data[1] != 3 || m.Data[3] != 1 {
+		t.Errorf("Stats ReadsPerUMI expect: %v get: %v", "1:3 3:1", m.Data)
+	}
+
+	prefix := filepath.Join(t.TempDir(), "test")
+	if err := s.Save(prefix, 2); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(prefix + ".umi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := string(data); !strings.Contains(text, "#: distinct: 4\n") || !strings.HasSuffix(text, "AA\t3\nCC\t1\n") {
+		t.Errorf("Stats Save expect: %v get: %v", "distinct 4 and top 2", text)
+	}
+}
@@ -39,8 +39,21 @@ func Xcreate(args ...string) (io.WriteCloser, error) {
 	}
 
 	if strings.HasSuffix(filename, ".gz") {
-		gfile := gzip.NewWriter(file)
-		return gfile, nil
+		return &gzipWriteCloser{gzip.NewWriter(file), file}, nil
 	}
 	return file, nil
 }
+
+// gzipWriteCloser close the gzip writer and the file under it
+type gzipWriteCloser struct {
+	*gzip.Writer
+	file *os.File
+}
+
+func (gz *gzipWriteCloser) Close() error {
+	err := gz.Writer.Close()
+	if e := gz.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}